@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
@@ -10,9 +11,11 @@ import (
 	"time"
 
 	"github.com/Amir-Sadati/order-packing/internal/config"
-	"github.com/Amir-Sadati/order-packing/internal/constants"
 	"github.com/Amir-Sadati/order-packing/internal/database/redisdb"
+	"github.com/Amir-Sadati/order-packing/internal/event"
 	"github.com/Amir-Sadati/order-packing/internal/handler/api"
+	"github.com/Amir-Sadati/order-packing/internal/model"
+	"github.com/Amir-Sadati/order-packing/internal/repository"
 	"github.com/Amir-Sadati/order-packing/internal/router"
 	"github.com/Amir-Sadati/order-packing/internal/service/pack"
 	"github.com/gin-gonic/gin"
@@ -25,13 +28,15 @@ type App struct {
 	r          *gin.Engine
 	httpServer *http.Server
 	rdb        *redis.Client
+	packSizes  repository.PackSizeStore
+	publisher  event.Publisher
 }
 
 // New creates and returns a new App instance
 func New() *App {
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("error in loading config")
+		log.Fatalf("error in loading config: %v", err)
 	}
 
 	return &App{
@@ -44,25 +49,26 @@ func (a *App) Run() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
-	rdb, err := redisdb.NewClient(ctx, a.config.Redis)
+	err := a.setupStorage(ctx)
 	if err != nil {
 		// Use log.Printf instead of log.Fatalf to avoid exitAfterDefer issue
-		log.Printf("failed to connect to redis: %v", err)
+		log.Printf("failed to set up %s storage: %v", a.config.Storage.Driver, err)
 		return
 	}
 
-	a.rdb = rdb
-
 	err = a.seedDefaultPackSizes(ctx)
 	if err != nil {
 		// Use log.Printf instead of log.Fatalf to avoid exitAfterDefer issue
-		log.Printf("failed to seed to redis: %v", err)
+		log.Printf("failed to seed pack sizes: %v", err)
 		return
 	}
 
-	packService := pack.NewService(rdb)
+	packService := pack.NewService(a.packSizes, a.config.Pack)
 	packHandler := api.NewPackHandler(packService)
 
+	go a.activateSchedules(ctx, packService)
+	go a.expireReservations(ctx, packService)
+
 	r := router.New(packHandler)
 
 	a.r = r
@@ -95,34 +101,118 @@ func (a *App) ServeHTTP() {
 	}
 }
 
-// seedDefaultPackSizes inserts default pack sizes into Redis if none exist.
+// setupStorage creates the pack size repository for the configured storage driver
+func (a *App) setupStorage(ctx context.Context) error {
+	switch a.config.Storage.Driver {
+	case config.StorageDriverMemory:
+		a.packSizes = repository.NewMemoryPackSizeStore()
+		a.publisher = event.NewLogPublisher()
+	case config.StorageDriverRedis:
+		rdb, err := redisdb.NewClient(ctx, a.config.Redis)
+		if err != nil {
+			return err
+		}
+
+		a.rdb = rdb
+		a.packSizes = repository.NewRedisPackSizeStore(rdb)
+		a.publisher = event.NewRedisPublisher(rdb)
+	}
+
+	return nil
+}
+
+// seedDefaultPackSizes inserts default pack sizes into every configured namespace that has none.
 func (a *App) seedDefaultPackSizes(ctx context.Context) error {
 	defaultSizes := []int{250, 500, 1000, 2000, 5000}
 
-	count, err := a.rdb.ZCard(ctx, string(constants.RedisKeyPackSizes)).Result()
-	if err != nil {
-		log.Printf("failed to check Redis: %v", err)
-		return err
-	}
+	for _, namespace := range a.config.Pack.SeedNamespaces {
+		repo, err := a.packSizes.Namespace(namespace)
+		if err != nil {
+			return fmt.Errorf("namespace %q: %w", namespace, err)
+		}
+
+		seeded := false
+
+		_, _, err = repo.Update(ctx, "seed", func(current []int) ([]int, error) {
+			if len(current) > 0 {
+				return current, nil
+			}
 
-	if count == 0 {
-		var zData []redis.Z
-		for _, size := range defaultSizes {
-			zData = append(zData, redis.Z{
-				Score:  float64(size),
-				Member: size,
-			})
+			seeded = true
+
+			return defaultSizes, nil
+		})
+		if err != nil {
+			return fmt.Errorf("namespace %q: %w", namespace, err)
 		}
 
-		if err := a.rdb.ZAdd(ctx, string(constants.RedisKeyPackSizes), zData...).Err(); err != nil {
-			log.Printf("failed to seed pack sizes to Redis: %v", err)
-			return err
+		if seeded {
+			log.Printf("Default pack sizes seeded in namespace %q.", namespace)
+		}
+	}
+
+	return nil
+}
+
+// activateSchedules swaps scheduled pack sets in as they take effect, until ctx is done.
+// Every activation is logged and published as an event.
+func (a *App) activateSchedules(ctx context.Context, packService *pack.Service) {
+	ticker := time.NewTicker(a.config.Pack.ScheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		settled, err := packService.ActivateDueSchedules(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("failed to activate scheduled pack sets: %v", err)
 		}
 
-		log.Println("Default pack sizes seeded into Redis.")
+		for _, schedule := range settled {
+			if schedule.Status != model.ScheduleStatusActivated {
+				log.Printf("Scheduled pack set %d in namespace %q superseded by a later change.", schedule.ID, schedule.Namespace)
+				continue
+			}
 
-		return nil
+			log.Printf("Scheduled pack set %d in namespace %q activated as version %d: %v",
+				schedule.ID, schedule.Namespace, schedule.ActivatedVersion, schedule.Sizes)
+
+			if err := a.publisher.Publish(ctx, event.New(event.TypePackSetActivated, schedule)); err != nil {
+				log.Printf("failed to publish pack set activation: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
+}
 
-	return nil
+// expireReservations returns the packs of expired reservations to stock, until ctx is done.
+// Every expiry is logged and published as an event.
+func (a *App) expireReservations(ctx context.Context, packService *pack.Service) {
+	ticker := time.NewTicker(a.config.Pack.ReservationSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := packService.ExpirePackReservations(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("failed to expire pack reservations: %v", err)
+		}
+
+		for _, reservation := range expired {
+			log.Printf("Pack reservation %s in namespace %q expired, returning %v to stock.",
+				reservation.ID, reservation.Namespace, reservation.Packs)
+
+			if err := a.publisher.Publish(ctx, event.New(event.TypePackReservationExpired, reservation)); err != nil {
+				log.Printf("failed to publish pack reservation expiry: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
@@ -2,17 +2,37 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config represents the main application configuration
 type Config struct {
-	HTTP  *HTTPConfig
-	Redis *RedisConfig
+	HTTP    *HTTPConfig
+	Storage *StorageConfig
+	Redis   *RedisConfig
+	Pack    *PackConfig
+}
+
+// StorageDriver selects where pack sizes are stored
+type StorageDriver string
+
+const (
+	// StorageDriverRedis stores pack sizes in Redis
+	StorageDriverRedis StorageDriver = "redis"
+	// StorageDriverMemory stores pack sizes in memory, for local development and tests
+	StorageDriverMemory StorageDriver = "memory"
+)
+
+// StorageConfig represents storage configuration
+type StorageConfig struct {
+	Driver StorageDriver
 }
 
 // HTTPConfig represents HTTP server configuration
@@ -28,14 +48,50 @@ type RedisConfig struct {
 	DB       int
 }
 
+// PackConfig represents pack calculation configuration
+type PackConfig struct {
+	MaxComputeNodes          int
+	ComputeTimeout           time.Duration
+	MinPackSize              int
+	MaxPackSize              int
+	MaxPackSizes             int
+	KeepLastSize             bool
+	ScheduleInterval         time.Duration
+	SeedNamespaces           []string
+	ReservationTTL           time.Duration
+	ReservationSweepInterval time.Duration
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	_ = godotenv.Load()
 
-	return &Config{
-		HTTP:  loadHTTPConfig(),
-		Redis: loadRedisConfig(),
-	}, nil
+	storage, err := loadStorageConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		HTTP:    loadHTTPConfig(),
+		Storage: storage,
+		Pack:    loadPackConfig(),
+	}
+
+	// Redis settings are only required when pack sizes are stored in Redis
+	if storage.Driver == StorageDriverRedis {
+		cfg.Redis = loadRedisConfig()
+	}
+
+	return cfg, nil
+}
+
+func loadStorageConfig() (*StorageConfig, error) {
+	driver := StorageDriver(getEnvOrDefault("STORAGE_DRIVER", string(StorageDriverRedis)))
+	if driver != StorageDriverRedis && driver != StorageDriverMemory {
+		return nil, fmt.Errorf("invalid STORAGE_DRIVER %q", driver)
+	}
+
+	return &StorageConfig{Driver: driver}, nil
 }
 
 func loadHTTPConfig() *HTTPConfig {
@@ -53,6 +109,21 @@ func loadRedisConfig() *RedisConfig {
 	}
 }
 
+func loadPackConfig() *PackConfig {
+	return &PackConfig{
+		MaxComputeNodes:          getEnvAsIntOrDefault("PACK_MAX_COMPUTE_NODES", 5_000_000),
+		ComputeTimeout:           getEnvAsDurationOrDefault("PACK_COMPUTE_TIMEOUT", 2*time.Second),
+		MinPackSize:              getEnvAsIntOrDefault("PACK_MIN_SIZE", 1),
+		MaxPackSize:              getEnvAsIntOrDefault("PACK_MAX_SIZE", 1_000_000),
+		MaxPackSizes:             getEnvAsIntOrDefault("PACK_MAX_SIZES", 20),
+		KeepLastSize:             getEnvAsBoolOrDefault("PACK_KEEP_LAST_SIZE", true),
+		ScheduleInterval:         getEnvAsDurationOrDefault("PACK_SCHEDULE_INTERVAL", 10*time.Second),
+		SeedNamespaces:           getEnvAsListOrDefault("PACK_SEED_NAMESPACES", []string{"default"}),
+		ReservationTTL:           getEnvAsDurationOrDefault("PACK_RESERVATION_TTL", 15*time.Minute),
+		ReservationSweepInterval: getEnvAsDurationOrDefault("PACK_RESERVATION_SWEEP_INTERVAL", 30*time.Second),
+	}
+}
+
 func getEnv(key string) string {
 	val := os.Getenv(key)
 	if val == "" {
@@ -70,3 +141,63 @@ func getEnvAsInt(key string) int {
 	}
 	return n
 }
+
+func getEnvOrDefault(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+
+	return def
+}
+
+func getEnvAsIntOrDefault(key string, def int) int {
+	if os.Getenv(key) == "" {
+		return def
+	}
+
+	return getEnvAsInt(key)
+}
+
+func getEnvAsBoolOrDefault(key string, def bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Fatalf("Invalid bool for %s: %v", key, err)
+	}
+
+	return b
+}
+
+func getEnvAsDurationOrDefault(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Fatalf("Invalid duration for %s: %v", key, err)
+	}
+
+	return d
+}
+
+func getEnvAsListOrDefault(key string, def []string) []string {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+
+	var list []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
@@ -7,4 +7,34 @@ type RedisKey string
 const (
 	// RedisKeyPackSizes is the Redis key for storing pack sizes
 	RedisKeyPackSizes RedisKey = "pack_sizes"
+	// RedisKeyPackSizeVersions is the Redis key for the list of recorded pack set versions
+	RedisKeyPackSizeVersions RedisKey = "pack_sizes:versions"
+	// RedisKeyPackSizeSchedules is the Redis key for the hash of scheduled pack sets by id
+	RedisKeyPackSizeSchedules RedisKey = "pack_sizes:schedules"
+	// RedisKeyPackSizeScheduleSeq is the Redis key for the counter handing out schedule ids
+	RedisKeyPackSizeScheduleSeq RedisKey = "pack_sizes:schedules:seq"
+	// RedisKeyPackSizeStock is the Redis key for the hash of packs in stock by size
+	RedisKeyPackSizeStock RedisKey = "pack_sizes:stock"
+	// RedisKeyPackSizeCosts is the Redis key for the pack costs stored as JSON
+	RedisKeyPackSizeCosts RedisKey = "pack_sizes:costs"
+	// RedisKeyPackSizeSpecs is the Redis key for the pack specs stored as JSON
+	RedisKeyPackSizeSpecs RedisKey = "pack_sizes:specs"
+	// RedisKeyPackSizeConstraints is the Redis key for the pack count constraints stored as JSON
+	RedisKeyPackSizeConstraints RedisKey = "pack_sizes:constraints"
+	// RedisKeyPackaging is the Redis key for the packaging hierarchy stored as JSON
+	RedisKeyPackaging RedisKey = "pack_sizes:packaging"
+	// RedisKeyPackCustomers is the Redis key for the hash of customer profiles by id
+	RedisKeyPackCustomers RedisKey = "pack_sizes:customers"
+	// RedisKeyPackReservations is the Redis key for the hash of held pack reservations by id
+	RedisKeyPackReservations RedisKey = "pack_sizes:reservations"
+	// RedisKeyPackReservationExpiry is the Redis key for the sorted set of held reservation ids scored by expiry
+	RedisKeyPackReservationExpiry RedisKey = "pack_sizes:reservations:expiry"
+	// RedisKeyNamespaces is the Redis key for the set of namespaces holding a pack set
+	RedisKeyNamespaces RedisKey = "pack_namespaces"
+	// RedisKeyNamespacePrefix prefixes the pack set keys of every namespace but the default one
+	RedisKeyNamespacePrefix RedisKey = "namespaces:"
+	// RedisKeySKUPrefix prefixes the pack set keys of a SKU, after the prefix of its namespace
+	RedisKeySKUPrefix RedisKey = "skus:"
+	// RedisKeyEvents is the Redis channel domain events are published on
+	RedisKeyEvents RedisKey = "order_packing:events"
 )
@@ -0,0 +1,38 @@
+// Package event publishes domain events for other services to react to
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// Type identifies what happened
+type Type string
+
+const (
+	// TypePackSetActivated is emitted when a scheduled pack set becomes the live set
+	TypePackSetActivated Type = "pack_set.activated"
+	// TypePackReservationExpired is emitted when a pack reservation expires and its packs return to stock
+	TypePackReservationExpired Type = "pack_reservation.expired"
+)
+
+// Event represents something that happened in the application
+type Event struct {
+	Type       Type      `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Data       any       `json:"data"`
+}
+
+// New creates and returns a new Event of the given type occurring now
+func New(t Type, data any) Event {
+	return Event{
+		Type:       t,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// Publisher emits events
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
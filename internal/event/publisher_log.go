@@ -0,0 +1,27 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+)
+
+// LogPublisher writes events as JSON to the standard logger, for local development
+type LogPublisher struct{}
+
+// NewLogPublisher creates and returns a new LogPublisher instance
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{}
+}
+
+// Publish logs e
+func (p *LogPublisher) Publish(_ context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("event: %s", data)
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Amir-Sadati/order-packing/internal/constants"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisPublisher publishes events as JSON on a Redis channel
+type RedisPublisher struct {
+	rdb *redis.Client
+}
+
+// NewRedisPublisher creates and returns a new RedisPublisher instance
+func NewRedisPublisher(rdb *redis.Client) *RedisPublisher {
+	return &RedisPublisher{
+		rdb: rdb,
+	}
+}
+
+// Publish publishes e on the events channel
+func (p *RedisPublisher) Publish(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return p.rdb.Publish(ctx, string(constants.RedisKeyEvents), data).Err()
+}
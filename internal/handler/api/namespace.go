@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Amir-Sadati/order-packing/internal/handler/api/response"
+	"github.com/Amir-Sadati/order-packing/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// namespaceHeader names the request header selecting the pack set namespace
+	namespaceHeader = "X-Pack-Namespace"
+	// namespaceParam names the path parameter selecting the pack set namespace
+	namespaceParam = "namespace"
+	// namespaceKey is the gin context key the resolved namespace is stored under
+	namespaceKey = "namespace"
+	// skuParam names the path parameter selecting the pack set of a SKU within the namespace
+	skuParam = "sku"
+)
+
+// ResolveNamespace resolves the pack set namespace of a request from the path, then from
+// the X-Pack-Namespace header, falling back to the default namespace. Requests naming an
+// invalid namespace are rejected before reaching a handler.
+func ResolveNamespace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ns := c.Param(namespaceParam)
+		if ns == "" {
+			ns = c.GetHeader(namespaceHeader)
+		}
+
+		if ns == "" {
+			ns = repository.DefaultNamespace
+		}
+
+		if err := repository.ValidateNamespace(ns); err != nil {
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, err.Error(), "use lowercase letters, digits, '-' and '_'")
+			c.Abort()
+
+			return
+		}
+
+		c.Set(namespaceKey, ns)
+		c.Next()
+	}
+}
+
+// ResolveSKU scopes a request to the pack set of the SKU in the path, within the namespace
+// ResolveNamespace resolved. Requests naming an invalid SKU are rejected before reaching
+// a handler.
+func ResolveSKU() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sku := c.Param(skuParam)
+		if err := repository.ValidateSKU(sku); err != nil {
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, err.Error(), "use letters, digits, '.', '-' and '_'")
+			c.Abort()
+
+			return
+		}
+
+		c.Set(namespaceKey, repository.SKUNamespace(namespace(c), sku))
+		c.Next()
+	}
+}
+
+// namespace returns the pack set namespace resolved for the request
+func namespace(c *gin.Context) string {
+	return c.GetString(namespaceKey)
+}
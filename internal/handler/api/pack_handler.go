@@ -1,14 +1,19 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
 	"github.com/Amir-Sadati/order-packing/internal/handler/api/response"
+	"github.com/Amir-Sadati/order-packing/internal/repository"
 	"github.com/Amir-Sadati/order-packing/internal/service/pack"
 	"github.com/gin-gonic/gin"
 )
 
+// actorHeader names the request header identifying who changes the pack set
+const actorHeader = "X-Actor"
+
 // PackHandler handles HTTP requests related to pack operations
 type PackHandler struct {
 	packService *pack.Service
@@ -24,15 +29,56 @@ func NewPackHandler(packService *pack.Service) *PackHandler {
 // CalculatePack godoc
 //
 //	@Summary		Calculate a new pack by order-items
-//	@Description	Calculates an optimal pack combination using orderItemQuantity as query param
+//	@Description	Calculates an optimal pack combination using orderItemQuantity as query param.
+//	@Description	With alternatives=K it also returns up to K ranked runner-up combinations.
+//	@Description	With explain=true it also traces which branch produced the result and why it beat the next-best combination.
+//	@Description	With respectStock=true it falls back to the best combination the stock allows, or fails with 409 when none exists.
+//	@Description	With objective=cost it returns the cheapest combination counting pack and surplus item costs, with its cost breakdown.
+//	@Description	With reserve=true it also holds the chosen packs in stock under a reservation that expires unless it is confirmed.
+//	@Description	With rank it orders combinations by the given criteria in turn, and with maxOvershoot it fails with 409 when every combination ships more surplus items than allowed.
+//	@Description	With maxShortfall it may ship up to that many items short when that is closer to the order, flagging the accepted shortfall.
+//	@Description	With maxShipmentWeight or maxParcels it returns the best combination within those limits, or fails with 409 when none fits.
+//	@Description	With a shipment limit or split, the total weight and volume are reported whenever every pack size used has a spec.
+//	@Description	With customer, allowedSizes or excludedSizes it narrows the pack set first, reporting the sizes excluded and whether that raised the overshoot.
+//	@Description	The stored pack count constraints apply from their minOrderQuantity up, with minPacks and maxPacks overriding them size by size; it fails with 400 when they apply along with alternatives, explain, rank, a shortfall, objective=cost or a shipment limit, and with 409 when no combination meets them.
+//	@Description	With splitMaxPacks, splitMaxItems or splitMaxWeight it also spreads the packs across balanced shipments within those limits, listing each with its own totals.
+//	@Description	With packaging=true it also puts the packs into cartons and the cartons onto pallets, as the packaging hierarchy sets out.
 //	@Tags			packs
 //	@Accept			json
 //	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
 //	@Param			orderItemQuantity	query		uint64	true	"Number of items to order"
+//	@Param			alternatives		query		int		false	"Number of alternative combinations to return (0-10)"
+//	@Param			explain				query		bool	false	"Include a trace of how the result was calculated"
+//	@Param			packSetVersion		query		int		false	"Recorded pack set version to calculate against, the live set by default"
+//	@Param			asOf				query		string	false	"RFC 3339 time to calculate against the pack set active at, now by default"
+//	@Param			respectStock		query		bool	false	"Never use more packs of a size than are in stock"
+//	@Param			objective			query		string	false	"What to optimise for: items (default) or cost"
+//	@Param			reserve				query		bool	false	"Respect the stock and hold the chosen packs until the reservation is confirmed or released"
+//	@Param			rank				query		[]string	false	"Criteria to rank combinations by, in order: items, packs, sizes, largest (default: items, packs, largest)"	collectionFormat(multi)
+//	@Param			maxOvershoot		query		int		false	"Most surplus items allowed"
+//	@Param			maxOvershootPercent	query		number	false	"Most surplus items allowed, as a percentage of the order"
+//	@Param			maxShortfall		query		int		false	"Most items the shipment may fall short of the order"
+//	@Param			maxShortfallPercent	query		number	false	"Most items the shipment may fall short of the order, as a percentage of it"
+//	@Param			maxShipmentWeight	query		int		false	"Most grams the shipment may weigh"
+//	@Param			maxParcels			query		int		false	"Most parcels the shipment may take, every pack shipping as one"
+//	@Param			customer			query		string	false	"Customer whose stored profile narrows the pack set"
+//	@Param			allowedSizes		query		[]int	false	"Only ship in these pack sizes"	collectionFormat(multi)
+//	@Param			excludedSizes		query		[]int	false	"Never ship in these pack sizes"	collectionFormat(multi)
+//	@Param			minPacks			query		string	false	"Fewest packs of each size as a JSON object, such as {\"5000\":1}"
+//	@Param			maxPacks			query		string	false	"Most packs of each size as a JSON object, such as {\"250\":3}"
+//	@Param			splitMaxPacks		query		int		false	"Split the order into shipments of at most this many packs"
+//	@Param			splitMaxItems		query		int		false	"Split the order into shipments of at most this many items"
+//	@Param			splitMaxWeight		query		int		false	"Split the order into shipments of at most this many grams"
+//	@Param			packaging			query		bool	false	"Include the cartons and pallets the packs ship in"
+//	@Param			X-Actor				header		string	false	"Who reserves the packs, recorded on the reservation"
 //	@Success		200	{object}	pack.CalculatePackResponse
-//	@Failure		400	{object}	response.ApiResponseNoData
-//	@Failure		404	{object}	response.ApiResponseNoData
-//	@Failure		500	{object}	response.ApiResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		404	{object}	response.APIResponseNoData
+//	@Failure		409	{object}	response.APIResponseNoData
+//	@Failure		422	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Failure		503	{object}	response.APIResponseNoData
 //	@Router			/api/v1/packs/calculate [get]
 func (h *PackHandler) CalculatePack(c *gin.Context) {
 	var req pack.CalculatePackRequest
@@ -42,31 +88,632 @@ func (h *PackHandler) CalculatePack(c *gin.Context) {
 		return
 	}
 
+	req.Actor = actor(c)
+	req.Namespace = namespace(c)
+
 	result, err := h.packService.CalculatePack(c.Request.Context(), req)
 	if err != nil {
-		if errors.Is(err, pack.ErrInvalidOrderItemQuantity) {
-			response.WriteFailNoData(c.Writer, http.StatusBadRequest, err.Error(), "")
-		} else {
-			response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		writeCalculateError(c, err)
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack calculated successfully")
+}
+
+// CalculatePackBatch godoc
+//
+//	@Summary		Calculate packs for many order lines
+//	@Description	Calculates an optimal pack combination for every line of a batch using a single read of the pack sizes.
+//	@Description	A line that fails reports its own error without failing the rest of the batch.
+//	@Description	The lines share the deadline and node budget of a single calculation, lines past them reporting computation budget exceeded.
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.CalculatePackBatchRequest	true	"Order lines to calculate"
+//	@Success		200	{object}	pack.CalculatePackBatchResponse
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		409	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/calculate/batch [post]
+func (h *PackHandler) CalculatePackBatch(c *gin.Context) {
+	var req pack.CalculatePackBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.CalculatePackBatch(c.Request.Context(), req)
+	if err != nil {
+		writeCalculateError(c, err)
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "batch calculated successfully")
+}
+
+// CalculateOrder godoc
+//
+//	@Summary		Calculate packs for a multi-SKU order
+//	@Description	Calculates an optimal pack combination for every line of an order against the pack set of the line's SKU
+//	@Description	within the namespace, as managed under /skus/{sku}/packs. Returns per-line results and an order-level shipment summary.
+//	@Description	A line that fails reports its own error without failing the rest of the order.
+//	@Description	The lines share the deadline and node budget of a single calculation, lines past them reporting computation budget exceeded.
+//	@Tags			orders
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.CalculateOrderRequest	true	"Order lines to pack"
+//	@Success		200	{object}	pack.CalculateOrderResponse
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/orders/calculate [post]
+func (h *PackHandler) CalculateOrder(c *gin.Context) {
+	var req pack.CalculateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.CalculateOrder(c.Request.Context(), req)
+	if err != nil {
+		writeCalculateError(c, err)
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "order calculated successfully")
+}
+
+// GetPackStock godoc
+//
+//	@Summary		Get pack stock
+//	@Description	Returns the packs in stock by size. Sizes without a stock level are unlimited.
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Success		200	{object}	pack.GetPackStockResponse
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/stock [get]
+func (h *PackHandler) GetPackStock(c *gin.Context) {
+	result, err := h.packService.GetPackStock(c.Request.Context(), namespace(c))
+	if err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack stock fetched successfully")
+}
+
+// SetPackStock godoc
+//
+//	@Summary		Set pack stock
+//	@Description	Sets the stock levels of pack sizes in the live set
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.SetPackStockRequest	true	"Stock levels by pack size"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/stock [put]
+func (h *PackHandler) SetPackStock(c *gin.Context) {
+	var req pack.SetPackStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	if err := h.packService.SetPackStock(c.Request.Context(), req); err != nil {
+		if errors.Is(err, pack.ErrInvalidStockLevel) || errors.Is(err, pack.ErrNotFoundPackSize) {
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid stock", err.Error())
+			return
 		}
 
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
 		return
 	}
 
-	response.WriteSuccess(c.Writer, result, "pack calculated successfully")
+	response.WriteSuccessNoData(c.Writer, "pack stock set successfully")
+}
+
+// ClearPackStock godoc
+//
+//	@Summary		Clear pack stock
+//	@Description	Stops tracking the stock of a pack size, making it unlimited again
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.ClearPackStockRequest	true	"Pack size to stop tracking"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/stock [delete]
+func (h *PackHandler) ClearPackStock(c *gin.Context) {
+	var req pack.ClearPackStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	if err := h.packService.ClearPackStock(c.Request.Context(), req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	response.WriteSuccessNoData(c.Writer, "pack stock cleared successfully")
+}
+
+// GetPackCosts godoc
+//
+//	@Summary		Get pack costs
+//	@Description	Returns what every pack size and every surplus item costs, in minor currency units
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Success		200	{object}	model.PackCosts
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/costs [get]
+func (h *PackHandler) GetPackCosts(c *gin.Context) {
+	result, err := h.packService.GetPackCosts(c.Request.Context(), namespace(c))
+	if err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack costs fetched successfully")
+}
+
+// SetPackCosts godoc
+//
+//	@Summary		Set pack costs
+//	@Description	Replaces what the pack sizes of the live set and surplus items cost, in minor currency units
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.SetPackCostsRequest	true	"Pack and surplus item costs"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/costs [put]
+func (h *PackHandler) SetPackCosts(c *gin.Context) {
+	var req pack.SetPackCostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	if err := h.packService.SetPackCosts(c.Request.Context(), req); err != nil {
+		if errors.Is(err, pack.ErrInvalidPackCost) || errors.Is(err, pack.ErrNotFoundPackSize) {
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid costs", err.Error())
+			return
+		}
+
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	response.WriteSuccessNoData(c.Writer, "pack costs set successfully")
+}
+
+// GetPackSpecs godoc
+//
+//	@Summary		Get pack specs
+//	@Description	Returns the gross weight in grams and the outer dimensions in centimetres of every pack size
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Success		200	{object}	pack.GetPackSpecsResponse
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/specs [get]
+func (h *PackHandler) GetPackSpecs(c *gin.Context) {
+	result, err := h.packService.GetPackSpecs(c.Request.Context(), namespace(c))
+	if err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack specs fetched successfully")
+}
+
+// SetPackSpecs godoc
+//
+//	@Summary		Set pack specs
+//	@Description	Replaces the gross weight in grams and the outer dimensions in centimetres of the pack sizes of the live set
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.SetPackSpecsRequest	true	"Pack weights and dimensions"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/specs [put]
+func (h *PackHandler) SetPackSpecs(c *gin.Context) {
+	var req pack.SetPackSpecsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	if err := h.packService.SetPackSpecs(c.Request.Context(), req); err != nil {
+		if errors.Is(err, pack.ErrInvalidPackSpec) || errors.Is(err, pack.ErrNotFoundPackSize) {
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid specs", err.Error())
+			return
+		}
+
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	response.WriteSuccessNoData(c.Writer, "pack specs set successfully")
+}
+
+// GetPackConstraints godoc
+//
+//	@Summary		Get pack constraints
+//	@Description	Returns the fewest and most packs of each size every combination holds
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Success		200	{object}	model.PackConstraints
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/constraints [get]
+func (h *PackHandler) GetPackConstraints(c *gin.Context) {
+	result, err := h.packService.GetPackConstraints(c.Request.Context(), namespace(c))
+	if err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack constraints fetched successfully")
+}
+
+// SetPackConstraints godoc
+//
+//	@Summary		Set pack constraints
+//	@Description	Replaces the fewest and most packs of each size of the live set every combination holds
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.SetPackConstraintsRequest	true	"Pack count constraints by size"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/constraints [put]
+func (h *PackHandler) SetPackConstraints(c *gin.Context) {
+	var req pack.SetPackConstraintsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	if err := h.packService.SetPackConstraints(c.Request.Context(), req); err != nil {
+		if errors.Is(err, pack.ErrInvalidPackConstraint) || errors.Is(err, pack.ErrNotFoundPackSize) {
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid constraints", err.Error())
+			return
+		}
+
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	response.WriteSuccessNoData(c.Writer, "pack constraints set successfully")
+}
+
+// GetCustomers godoc
+//
+//	@Summary		Get customer profiles
+//	@Description	Returns every customer profile with the pack sizes the customer's orders may ship in
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Success		200	{object}	pack.GetCustomersResponse
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/customers [get]
+func (h *PackHandler) GetCustomers(c *gin.Context) {
+	result, err := h.packService.GetCustomers(c.Request.Context(), namespace(c))
+	if err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "customer profiles fetched successfully")
+}
+
+// GetCustomer godoc
+//
+//	@Summary		Get a customer profile
+//	@Description	Returns the pack sizes a customer's orders may ship in
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			id	path		string	true	"Customer id"
+//	@Success		200	{object}	model.CustomerProfile
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		404	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/customers/{id} [get]
+func (h *PackHandler) GetCustomer(c *gin.Context) {
+	var req pack.CustomerRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid customer id", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.GetCustomer(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, repository.ErrCustomerNotFound) {
+			response.WriteFailNoData(c.Writer, http.StatusNotFound, err.Error(), "")
+			return
+		}
+
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "customer profile fetched successfully")
+}
+
+// SetCustomer godoc
+//
+//	@Summary		Set a customer profile
+//	@Description	Stores the pack sizes a customer's orders may ship in, replacing any profile the customer had.
+//	@Description	An empty allowedSizes allows every size that is not excluded.
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			id		path		string						true	"Customer id"
+//	@Param			body	body		pack.SetCustomerRequest		true	"Allowed and excluded pack sizes"
+//	@Success		200	{object}	model.CustomerProfile
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/customers/{id} [put]
+func (h *PackHandler) SetCustomer(c *gin.Context) {
+	var req pack.SetCustomerRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid customer id", err.Error())
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.SetCustomer(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, pack.ErrInvalidCustomerProfile) {
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid customer profile", err.Error())
+			return
+		}
+
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "customer profile set successfully")
+}
+
+// DeleteCustomer godoc
+//
+//	@Summary		Delete a customer profile
+//	@Description	Removes a customer profile, so the customer's orders may ship in every pack size again
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			id	path		string	true	"Customer id"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		404	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/customers/{id} [delete]
+func (h *PackHandler) DeleteCustomer(c *gin.Context) {
+	var req pack.CustomerRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid customer id", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	if err := h.packService.DeleteCustomer(c.Request.Context(), req); err != nil {
+		if errors.Is(err, repository.ErrCustomerNotFound) {
+			response.WriteFailNoData(c.Writer, http.StatusNotFound, err.Error(), "")
+			return
+		}
+
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	response.WriteSuccessNoData(c.Writer, "customer profile deleted successfully")
+}
+
+// GetPackaging godoc
+//
+//	@Summary		Get packaging
+//	@Description	Returns the carton types packs go into and the pallet type cartons are stacked on
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Success		200	{object}	model.Packaging
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/packaging [get]
+func (h *PackHandler) GetPackaging(c *gin.Context) {
+	result, err := h.packService.GetPackaging(c.Request.Context(), namespace(c))
+	if err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "packaging fetched successfully")
+}
+
+// SetPackaging godoc
+//
+//	@Summary		Set packaging
+//	@Description	Replaces the carton types packs go into, each holding a number of packs of the listed sizes, and the pallet type holding a number of cartons
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.SetPackagingRequest	true	"Carton and pallet types"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/packaging [put]
+func (h *PackHandler) SetPackaging(c *gin.Context) {
+	var req pack.SetPackagingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	if err := h.packService.SetPackaging(c.Request.Context(), req); err != nil {
+		if errors.Is(err, pack.ErrInvalidPackaging) || errors.Is(err, pack.ErrNotFoundPackSize) {
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid packaging", err.Error())
+			return
+		}
+
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	response.WriteSuccessNoData(c.Writer, "packaging set successfully")
+}
+
+// ConfirmPackReservation godoc
+//
+//	@Summary		Confirm a pack reservation
+//	@Description	Confirms a held reservation once its order ships, keeping its packs out of stock
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			id	path		string	true	"Reservation id"
+//	@Success		200	{object}	model.PackReservation
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		404	{object}	response.APIResponseNoData
+//	@Failure		409	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/reservations/{id}/confirm [post]
+func (h *PackHandler) ConfirmPackReservation(c *gin.Context) {
+	var req pack.PackReservationRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid reservation id", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.ConfirmPackReservation(c.Request.Context(), req)
+	if err != nil {
+		writeReservationError(c, err)
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack reservation confirmed successfully")
+}
+
+// ReleasePackReservation godoc
+//
+//	@Summary		Release a pack reservation
+//	@Description	Releases a held reservation, returning its packs to stock
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			id	path		string	true	"Reservation id"
+//	@Success		200	{object}	model.PackReservation
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		404	{object}	response.APIResponseNoData
+//	@Failure		409	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/reservations/{id}/release [post]
+func (h *PackHandler) ReleasePackReservation(c *gin.Context) {
+	var req pack.PackReservationRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid reservation id", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.ReleasePackReservation(c.Request.Context(), req)
+	if err != nil {
+		writeReservationError(c, err)
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack reservation released successfully")
+}
+
+// writeReservationError maps errors of settling a pack reservation to HTTP responses
+func writeReservationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrReservationNotFound):
+		response.WriteFailNoData(c.Writer, http.StatusNotFound, err.Error(), "")
+	case errors.Is(err, repository.ErrReservationExpired), errors.Is(err, repository.ErrConcurrentUpdate):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, err.Error(), "")
+	default:
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// GetNamespaces godoc
+//
+//	@Summary		List namespaces
+//	@Description	Returns every namespace holding a pack set or a schedule
+//	@Tags			namespaces
+//	@Produce		json
+//	@Success		200	{object}	pack.GetNamespacesResponse
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/namespaces [get]
+func (h *PackHandler) GetNamespaces(c *gin.Context) {
+	result, err := h.packService.GetNamespaces(c.Request.Context())
+	if err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "namespaces fetched successfully")
 }
 
 // GetPackSizes godoc
 //
 //	@Summary		Get all pack sizes
-//	@Description	Returns all available pack sizes from Redis
+//	@Description	Returns all available pack sizes
 //	@Tags			packs
 //	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
 //	@Success		200	{object}	pack.GetPackSizesResponse
-//	@Failure		500	{object}	response.ApiResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
 //	@Router			/api/v1/packs/sizes [get]
 func (h *PackHandler) GetPackSizes(c *gin.Context) {
-	result, err := h.packService.GetPackSizes(c.Request.Context())
+	result, err := h.packService.GetPackSizes(c.Request.Context(), namespace(c))
 	if err != nil {
 		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
 		return
@@ -78,14 +725,17 @@ func (h *PackHandler) GetPackSizes(c *gin.Context) {
 // AddPackSize godoc
 //
 //	@Summary		Add a new pack size
-//	@Description	Adds a new pack size to the Redis sorted set
+//	@Description	Adds a new pack size to the pack set
 //	@Tags			packs
 //	@Accept			json
 //	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
 //	@Param			body	body		pack.AddPackSizeRequest	true	"Pack size to add"
-//	@Success		200	{object}	response.ApiResponseNoData
-//	@Failure		400	{object}	response.ApiResponseNoData
-//	@Failure		500	{object}	response.ApiResponseNoData
+//	@Param			X-Actor	header		string					false	"Who makes the change, recorded in the version history"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		409	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
 //	@Router			/api/v1/packs/sizes [post]
 func (h *PackHandler) AddPackSize(c *gin.Context) {
 	var req pack.AddPackSizeRequest
@@ -94,8 +744,19 @@ func (h *PackHandler) AddPackSize(c *gin.Context) {
 		return
 	}
 
+	req.Actor = actor(c)
+	req.Namespace = namespace(c)
+
 	if err := h.packService.AddPackSize(c.Request.Context(), req); err != nil {
-		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		switch {
+		case errors.Is(err, pack.ErrInvalidPackSize):
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, pack.ErrInvalidPackSize.Error(), err.Error())
+		case errors.Is(err, pack.ErrPackSizeExists), errors.Is(err, pack.ErrTooManyPackSizes):
+			response.WriteFailNoData(c.Writer, http.StatusConflict, err.Error(), "")
+		default:
+			response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		}
+
 		return
 	}
 
@@ -105,14 +766,17 @@ func (h *PackHandler) AddPackSize(c *gin.Context) {
 // RemovePackSize godoc
 //
 //	@Summary		Remove a pack size
-//	@Description	Removes a pack size from the Redis sorted set
+//	@Description	Removes a pack size from the pack set
 //	@Tags			packs
 //	@Accept			json
 //	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
 //	@Param			body	body		pack.RemovePackSizeRequest	true	"Pack size to remove"
-//	@Success		200	{object}	response.ApiResponseNoData
-//	@Failure		400	{object}	response.ApiResponseNoData
-//	@Failure		500	{object}	response.ApiResponseNoData
+//	@Param			X-Actor	header		string						false	"Who makes the change, recorded in the version history"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		409	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
 //	@Router			/api/v1/packs/sizes [delete]
 func (h *PackHandler) RemovePackSize(c *gin.Context) {
 	var req pack.RemovePackSizeRequest
@@ -121,15 +785,315 @@ func (h *PackHandler) RemovePackSize(c *gin.Context) {
 		return
 	}
 
+	req.Actor = actor(c)
+	req.Namespace = namespace(c)
+
 	if err := h.packService.RemovePackSize(c.Request.Context(), req); err != nil {
 		if errors.Is(err, pack.ErrNotFoundPackSize) {
 			response.WriteFailNoData(c.Writer, http.StatusBadRequest, err.Error(), "")
 			return
 		}
 
+		if errors.Is(err, pack.ErrLastPackSize) {
+			response.WriteFailNoData(c.Writer, http.StatusConflict, err.Error(), "")
+			return
+		}
+
 		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, err.Error(), "")
 		return
 	}
 
 	response.WriteSuccessNoData(c.Writer, "pack size removed successfully")
 }
+
+// writeCalculateError maps pack calculation errors to HTTP responses
+func writeCalculateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pack.ErrInvalidOrderItemQuantity), errors.Is(err, pack.ErrInvalidAlternatives),
+		errors.Is(err, pack.ErrInvalidBatchSize), errors.Is(err, pack.ErrInvalidOrderLines),
+		errors.Is(err, pack.ErrAmbiguousPackSet), errors.Is(err, pack.ErrInvalidObjective),
+		errors.Is(err, pack.ErrUnsupportedCostOption), errors.Is(err, pack.ErrInvalidRanking),
+		errors.Is(err, pack.ErrUnsupportedRanking), errors.Is(err, pack.ErrInvalidTolerance),
+		errors.Is(err, pack.ErrUnsupportedShortfall), errors.Is(err, pack.ErrInvalidShipmentLimit),
+		errors.Is(err, pack.ErrUnsupportedShipmentLimit), errors.Is(err, pack.ErrInvalidSplitLimit),
+		errors.Is(err, pack.ErrInvalidPackConstraint), errors.Is(err, pack.ErrUnsupportedConstraint),
+		errors.Is(err, pack.ErrNotFoundPackSize):
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, err.Error(), "")
+	case errors.Is(err, repository.ErrVersionNotFound), errors.Is(err, repository.ErrCustomerNotFound):
+		response.WriteFailNoData(c.Writer, http.StatusNotFound, err.Error(), "")
+	case errors.Is(err, repository.ErrConcurrentUpdate):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, err.Error(), "stock kept changing, try again")
+	case errors.Is(err, pack.ErrNoPackSizesConfigured):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, err.Error(), "add a pack size before calculating")
+	case errors.Is(err, pack.ErrAllPackSizesExcluded):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, err.Error(), "allow at least one size of the pack set")
+	case errors.Is(err, pack.ErrNoPackagingConfigured):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, err.Error(), "set the packaging before asking for a shipment")
+	case errors.Is(err, pack.ErrMissingPackCost):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, pack.ErrMissingPackCost.Error(), err.Error())
+	case errors.Is(err, pack.ErrOvershootExceeded):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, pack.ErrOvershootExceeded.Error(), err.Error())
+	case errors.Is(err, pack.ErrMissingPackSpec):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, pack.ErrMissingPackSpec.Error(), err.Error())
+	case errors.Is(err, pack.ErrUnsplittablePack), errors.Is(err, pack.ErrTooManyShipments):
+		response.WriteFailNoData(c.Writer, http.StatusUnprocessableEntity, err.Error(), "raise the per-shipment limits")
+	case errors.Is(err, pack.ErrShipmentLimitExceeded):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, pack.ErrShipmentLimitExceeded.Error(), err.Error())
+	case errors.Is(err, pack.ErrConstraintsInfeasible):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, pack.ErrConstraintsInfeasible.Error(), err.Error())
+	case errors.Is(err, pack.ErrInsufficientStock):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, pack.ErrInsufficientStock.Error(), err.Error())
+	case errors.Is(err, pack.ErrComputationBudgetExceeded) &&
+		(errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)):
+		response.WriteFailNoData(c.Writer, http.StatusServiceUnavailable, pack.ErrComputationBudgetExceeded.Error(), "calculation timed out, try again later")
+	case errors.Is(err, pack.ErrComputationBudgetExceeded):
+		response.WriteFailNoData(c.Writer, http.StatusUnprocessableEntity, pack.ErrComputationBudgetExceeded.Error(), "order is too complex for the configured pack sizes")
+	default:
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+	}
+}
+
+// ReplacePackSizes godoc
+//
+//	@Summary		Replace all pack sizes
+//	@Description	Validates a new pack set and atomically swaps it in for the whole current set
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.ReplacePackSizesRequest	true	"New pack set"
+//	@Param			X-Actor	header		string							false	"Who makes the change, recorded in the version history"
+//	@Success		200	{object}	pack.PackSetChangeResponse
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		409	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes [put]
+func (h *PackHandler) ReplacePackSizes(c *gin.Context) {
+	var req pack.ReplacePackSizesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Actor = actor(c)
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.ReplacePackSizes(c.Request.Context(), req)
+	if err != nil {
+		writePackSetChangeError(c, err)
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack sizes replaced successfully")
+}
+
+// GetPackSetVersions godoc
+//
+//	@Summary		List pack set versions
+//	@Description	Returns every recorded version of the pack set, oldest first
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Success		200	{object}	pack.GetPackSetVersionsResponse
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/versions [get]
+func (h *PackHandler) GetPackSetVersions(c *gin.Context) {
+	result, err := h.packService.GetPackSetVersions(c.Request.Context(), namespace(c))
+	if err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack set versions fetched successfully")
+}
+
+// GetPackSetVersion godoc
+//
+//	@Summary		Get a pack set version
+//	@Description	Returns a single recorded version of the pack set
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			version	path		int	true	"Pack set version"
+//	@Success		200	{object}	model.PackSetVersion
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		404	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/versions/{version} [get]
+func (h *PackHandler) GetPackSetVersion(c *gin.Context) {
+	var req pack.GetPackSetVersionRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid version", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.GetPackSetVersion(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionNotFound) {
+			response.WriteFailNoData(c.Writer, http.StatusNotFound, err.Error(), "")
+			return
+		}
+
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack set version fetched successfully")
+}
+
+// RollbackPackSizes godoc
+//
+//	@Summary		Roll back the pack set
+//	@Description	Swaps a recorded version back in as the live pack set, recorded as a new version
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			version	path		int		true	"Pack set version to roll back to"
+//	@Param			X-Actor	header		string	false	"Who makes the change, recorded in the version history"
+//	@Success		200	{object}	pack.PackSetChangeResponse
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		404	{object}	response.APIResponseNoData
+//	@Failure		409	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/versions/{version}/rollback [post]
+func (h *PackHandler) RollbackPackSizes(c *gin.Context) {
+	var req pack.RollbackPackSizesRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid version", err.Error())
+		return
+	}
+
+	req.Actor = actor(c)
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.RollbackPackSizes(c.Request.Context(), req)
+	if err != nil {
+		writePackSetChangeError(c, err)
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack sizes rolled back successfully")
+}
+
+// SchedulePackSizes godoc
+//
+//	@Summary		Schedule a pack set
+//	@Description	Validates a pack set and stages it to become the live set at effectiveFrom
+//	@Tags			packs
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			body	body		pack.SchedulePackSizesRequest	true	"Pack set and the time it takes effect"
+//	@Param			X-Actor	header		string							false	"Who makes the change, recorded in the version history"
+//	@Success		200	{object}	model.PackSetSchedule
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/schedules [post]
+func (h *PackHandler) SchedulePackSizes(c *gin.Context) {
+	var req pack.SchedulePackSizesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	req.Actor = actor(c)
+	req.Namespace = namespace(c)
+
+	result, err := h.packService.SchedulePackSizes(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, pack.ErrInvalidEffectiveFrom) {
+			response.WriteFailNoData(c.Writer, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+
+		writePackSetChangeError(c, err)
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack set scheduled successfully")
+}
+
+// GetPackSetSchedules godoc
+//
+//	@Summary		List pack set schedules
+//	@Description	Returns every scheduled pack set ordered by effective time, with its status
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Success		200	{object}	pack.GetPackSetSchedulesResponse
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/schedules [get]
+func (h *PackHandler) GetPackSetSchedules(c *gin.Context) {
+	result, err := h.packService.GetPackSetSchedules(c.Request.Context(), namespace(c))
+	if err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", "Something went wrong")
+		return
+	}
+
+	response.WriteSuccess(c.Writer, result, "pack set schedules fetched successfully")
+}
+
+// CancelPackSetSchedule godoc
+//
+//	@Summary		Cancel a pack set schedule
+//	@Description	Cancels a scheduled pack set that has not taken effect yet
+//	@Tags			packs
+//	@Produce		json
+//	@Param			X-Pack-Namespace	header	string	false	"Pack set namespace, such as a tenant or product line (default: default)"
+//	@Param			id	path		int	true	"Schedule id"
+//	@Success		200	{object}	response.APIResponseNoData
+//	@Failure		400	{object}	response.APIResponseNoData
+//	@Failure		404	{object}	response.APIResponseNoData
+//	@Failure		409	{object}	response.APIResponseNoData
+//	@Failure		500	{object}	response.APIResponseNoData
+//	@Router			/api/v1/packs/sizes/schedules/{id} [delete]
+func (h *PackHandler) CancelPackSetSchedule(c *gin.Context) {
+	var req pack.CancelPackSetScheduleRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid schedule id", err.Error())
+		return
+	}
+
+	req.Namespace = namespace(c)
+
+	if err := h.packService.CancelPackSetSchedule(c.Request.Context(), req); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrScheduleNotFound):
+			response.WriteFailNoData(c.Writer, http.StatusNotFound, err.Error(), "")
+		case errors.Is(err, repository.ErrScheduleNotPending), errors.Is(err, repository.ErrConcurrentUpdate):
+			response.WriteFailNoData(c.Writer, http.StatusConflict, err.Error(), "")
+		default:
+			response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+		}
+
+		return
+	}
+
+	response.WriteSuccessNoData(c.Writer, "pack set schedule cancelled successfully")
+}
+
+// writePackSetChangeError maps errors of whole pack set changes to HTTP responses
+func writePackSetChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pack.ErrEmptyPackSet), errors.Is(err, pack.ErrInvalidPackSize),
+		errors.Is(err, pack.ErrPackSizeExists), errors.Is(err, pack.ErrTooManyPackSizes):
+		response.WriteFailNoData(c.Writer, http.StatusBadRequest, "Invalid pack set", err.Error())
+	case errors.Is(err, repository.ErrVersionNotFound):
+		response.WriteFailNoData(c.Writer, http.StatusNotFound, err.Error(), "")
+	case errors.Is(err, repository.ErrConcurrentUpdate):
+		response.WriteFailNoData(c.Writer, http.StatusConflict, err.Error(), "")
+	default:
+		response.WriteFailNoData(c.Writer, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// actor returns who makes a pack set change, as identified by the request
+func actor(c *gin.Context) string {
+	if a := c.GetHeader(actorHeader); a != "" {
+		return a
+	}
+
+	return "anonymous"
+}
@@ -0,0 +1,10 @@
+package model
+
+// CustomerProfile narrows the pack sizes a customer's orders ship in, such as when their
+// receiving dock cannot handle the largest packs. An empty AllowedSizes allows every size
+// that is not excluded.
+type CustomerProfile struct {
+	ID            string `json:"id"`
+	AllowedSizes  []int  `json:"allowedSizes"`
+	ExcludedSizes []int  `json:"excludedSizes"`
+}
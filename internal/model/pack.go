@@ -1,7 +1,46 @@
 // Package model provides data structures for the application
 package model
 
-// Pack represents a pack with a specific size
+// Pack represents a pack with a specific size along with what using one costs.
+// Costs are in minor currency units, such as cents.
 type Pack struct {
-	Size int
+	Size         int `json:"size"`
+	MaterialCost int `json:"materialCost"`
+	HandlingCost int `json:"handlingCost"`
+}
+
+// Cost returns what using one pack costs
+func (p Pack) Cost() int {
+	return p.MaterialCost + p.HandlingCost
+}
+
+// PackCosts represents what packing an order costs: every pack size's cost and the
+// cost of each item shipped beyond the order
+type PackCosts struct {
+	Packs           []Pack `json:"packs"`
+	SurplusItemCost int    `json:"surplusItemCost"`
+}
+
+// PackSpec represents the physical attributes of a pack size: its gross weight in grams
+// and its outer dimensions in centimetres
+type PackSpec struct {
+	Size        int `json:"size"`
+	GrossWeight int `json:"grossWeight"`
+	Length      int `json:"length"`
+	Width       int `json:"width"`
+	Height      int `json:"height"`
+}
+
+// Volume returns the outer volume of one pack in cubic centimetres
+func (s PackSpec) Volume() int {
+	return s.Length * s.Width * s.Height
+}
+
+// PackConstraints bounds how many packs of each size a combination holds: at least
+// MinPacks and at most MaxPacks of a size, sizes missing from either being unbounded.
+// Stored constraints only bound orders of at least MinOrderQuantity items.
+type PackConstraints struct {
+	MinPacks         map[int]int `json:"minPacks"`
+	MaxPacks         map[int]int `json:"maxPacks"`
+	MinOrderQuantity int         `json:"minOrderQuantity,omitempty"`
 }
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// ReservationStatus represents the lifecycle state of a pack reservation
+type ReservationStatus string
+
+const (
+	// ReservationStatusHeld marks a reservation whose packs are held until it is confirmed, released or expires
+	ReservationStatusHeld ReservationStatus = "held"
+	// ReservationStatusConfirmed marks a reservation whose packs were shipped, so they stay out of stock
+	ReservationStatusConfirmed ReservationStatus = "confirmed"
+	// ReservationStatusReleased marks a reservation whose packs were returned to stock
+	ReservationStatusReleased ReservationStatus = "released"
+	// ReservationStatusExpired marks a reservation whose packs were returned to stock after its TTL ran out
+	ReservationStatusExpired ReservationStatus = "expired"
+)
+
+// PackReservation represents packs taken out of stock for an order until the order
+// is confirmed or the packs are released. Packs only holds sizes with a stock level,
+// as packs of unlimited sizes need no holding.
+type PackReservation struct {
+	ID        string            `json:"id"`
+	Namespace string            `json:"namespace,omitempty"`
+	Packs     map[int]int       `json:"packs"`
+	CreatedAt time.Time         `json:"createdAt"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+	Actor     string            `json:"actor"`
+	Status    ReservationStatus `json:"status"`
+}
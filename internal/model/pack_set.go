@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// PackSetVersion represents an immutable snapshot of the pack set taken after a change
+type PackSetVersion struct {
+	Version   int       `json:"version"`
+	Sizes     []int     `json:"sizes"`
+	CreatedAt time.Time `json:"createdAt"`
+	Actor     string    `json:"actor"`
+}
+
+// ScheduleStatus represents the lifecycle state of a scheduled pack set
+type ScheduleStatus string
+
+const (
+	// ScheduleStatusPending marks a schedule that has not been activated yet
+	ScheduleStatusPending ScheduleStatus = "pending"
+	// ScheduleStatusActivated marks a schedule that was swapped in as the live pack set
+	ScheduleStatusActivated ScheduleStatus = "activated"
+	// ScheduleStatusSuperseded marks a schedule that a later change made before its activation overrode
+	ScheduleStatusSuperseded ScheduleStatus = "superseded"
+)
+
+// PackSetSchedule represents a pack set staged to become live at a future time
+type PackSetSchedule struct {
+	ID               int            `json:"id"`
+	Namespace        string         `json:"namespace,omitempty"`
+	Sizes            []int          `json:"sizes"`
+	EffectiveFrom    time.Time      `json:"effectiveFrom"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	Actor            string         `json:"actor"`
+	Status           ScheduleStatus `json:"status"`
+	ActivatedVersion int            `json:"activatedVersion,omitempty"`
+}
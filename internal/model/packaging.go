@@ -0,0 +1,21 @@
+package model
+
+// CartonType represents a carton holding up to Capacity packs of the listed sizes, in any mix
+type CartonType struct {
+	Name      string `json:"name"`
+	PackSizes []int  `json:"packSizes"`
+	Capacity  int    `json:"capacity"`
+}
+
+// PalletType represents a pallet holding up to Capacity cartons of any type
+type PalletType struct {
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"`
+}
+
+// Packaging represents the hierarchy packs ship in: packs go into cartons and cartons onto
+// pallets. Without a pallet type cartons ship on their own.
+type Packaging struct {
+	Cartons []CartonType `json:"cartons"`
+	Pallet  *PalletType  `json:"pallet,omitempty"`
+}
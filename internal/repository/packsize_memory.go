@@ -0,0 +1,576 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// MemoryPackSizeRepository stores pack sizes and their versions in memory, for local
+// development and tests. It is safe for concurrent use.
+type MemoryPackSizeRepository struct {
+	mu             sync.RWMutex
+	store          *MemoryPackSizeStore
+	namespace      string
+	sizes          []int
+	versions       []model.PackSetVersion
+	schedules      []model.PackSetSchedule
+	lastScheduleID int
+	stock          map[int]int
+	costs          model.PackCosts
+	specs          []model.PackSpec
+	constraints    model.PackConstraints
+	packaging      model.Packaging
+	customers      map[string]model.CustomerProfile
+	reservations   map[string]model.PackReservation
+}
+
+// NewMemoryPackSizeRepository creates and returns a new MemoryPackSizeRepository holding
+// the given sizes without any recorded version
+func NewMemoryPackSizeRepository(sizes ...int) *MemoryPackSizeRepository {
+	return &MemoryPackSizeRepository{
+		namespace:    DefaultNamespace,
+		sizes:        normalizePackSizes(sizes),
+		stock:        make(map[int]int),
+		customers:    make(map[string]model.CustomerProfile),
+		reservations: make(map[string]model.PackReservation),
+	}
+}
+
+// MemoryPackSizeStore hands out in-memory pack size repositories by namespace.
+// It is safe for concurrent use.
+type MemoryPackSizeStore struct {
+	mu    sync.Mutex
+	repos map[string]*MemoryPackSizeRepository
+}
+
+// NewMemoryPackSizeStore creates and returns a new, empty MemoryPackSizeStore
+func NewMemoryPackSizeStore() *MemoryPackSizeStore {
+	return &MemoryPackSizeStore{
+		repos: make(map[string]*MemoryPackSizeRepository),
+	}
+}
+
+// Namespace returns the repository of a namespace or of a SKU within a namespace. A
+// namespace not stored yet gets a fresh repository, kept only once it is written to.
+func (s *MemoryPackSizeStore) Namespace(namespace string) (PackSizeRepository, error) {
+	if _, _, err := parseNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if repo, ok := s.repos[namespace]; ok {
+		return repo, nil
+	}
+
+	repo := NewMemoryPackSizeRepository()
+	repo.store = s
+	repo.namespace = namespace
+
+	return repo, nil
+}
+
+// register keeps a repository handed out for a namespace not stored yet, and returns the
+// repository stored for its namespace, which another write may have kept first
+func (s *MemoryPackSizeStore) register(repo *MemoryPackSizeRepository) *MemoryPackSizeRepository {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.repos[repo.namespace]; ok {
+		return stored
+	}
+
+	s.repos[repo.namespace] = repo
+
+	return repo
+}
+
+// Namespaces returns every namespace and SKU pack set holding a pack set or a schedule,
+// sorted by name
+func (s *MemoryPackSizeStore) Namespaces(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	namespaces := []string{DefaultNamespace}
+	for namespace, repo := range s.repos {
+		if namespace != DefaultNamespace && !repo.empty() {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	slices.Sort(namespaces)
+
+	return namespaces, nil
+}
+
+// Current returns the live pack set along with the version it was recorded as
+func (r *MemoryPackSizeRepository) Current(_ context.Context) (model.PackSetVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.current(), nil
+}
+
+// empty reports whether the repository holds neither a pack set nor a schedule
+func (r *MemoryPackSizeRepository) empty() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.sizes) == 0 && len(r.versions) == 0 && len(r.schedules) == 0
+}
+
+// lock registers the repository with its store on its first write and returns the
+// registered repository locked for writing, the caller must unlock it
+func (r *MemoryPackSizeRepository) lock() *MemoryPackSizeRepository {
+	if r.store != nil {
+		r = r.store.register(r)
+	}
+
+	r.mu.Lock()
+
+	return r
+}
+
+// current returns the live pack set, the caller must hold the lock
+func (r *MemoryPackSizeRepository) current() model.PackSetVersion {
+	var current model.PackSetVersion
+	if len(r.versions) > 0 {
+		current = r.versions[len(r.versions)-1]
+	}
+
+	current.Sizes = slices.Clone(r.sizes)
+
+	return current
+}
+
+// Update atomically applies fn to the current set, stores the result and records it as a new version
+func (r *MemoryPackSizeRepository) Update(_ context.Context, actor string, fn UpdateFunc) ([]int, model.PackSetVersion, error) {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	current := r.current()
+
+	next, err := fn(slices.Clone(current.Sizes))
+	if err != nil {
+		return nil, model.PackSetVersion{}, err
+	}
+
+	version, changed := nextVersion(current, next, actor)
+	if changed {
+		r.record(version)
+	}
+
+	return current.Sizes, cloneVersion(version), nil
+}
+
+// record stores version as the live set, the caller must hold the lock
+func (r *MemoryPackSizeRepository) record(version model.PackSetVersion) {
+	r.sizes = version.Sizes
+	r.versions = append(r.versions, version)
+}
+
+// Versions returns every recorded version, oldest first
+func (r *MemoryPackSizeRepository) Versions(_ context.Context) ([]model.PackSetVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := make([]model.PackSetVersion, 0, len(r.versions))
+	for _, version := range r.versions {
+		versions = append(versions, cloneVersion(version))
+	}
+
+	return versions, nil
+}
+
+// Version returns a single recorded version or ErrVersionNotFound
+func (r *MemoryPackSizeRepository) Version(_ context.Context, version int) (model.PackSetVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if version < 1 || version > len(r.versions) {
+		return model.PackSetVersion{}, ErrVersionNotFound
+	}
+
+	return cloneVersion(r.versions[version-1]), nil
+}
+
+// cloneVersion returns a copy of version that does not share its sizes
+func cloneVersion(version model.PackSetVersion) model.PackSetVersion {
+	version.Sizes = slices.Clone(version.Sizes)
+	return version
+}
+
+// Schedule stages sizes by actor to become the live set at effectiveFrom
+func (r *MemoryPackSizeRepository) Schedule(_ context.Context, actor string, sizes []int, effectiveFrom time.Time) (model.PackSetSchedule, error) {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	r.lastScheduleID++
+
+	schedule := model.PackSetSchedule{
+		ID:            r.lastScheduleID,
+		Namespace:     r.namespace,
+		Sizes:         normalizePackSizes(sizes),
+		EffectiveFrom: effectiveFrom.UTC(),
+		CreatedAt:     time.Now().UTC(),
+		Actor:         actor,
+		Status:        model.ScheduleStatusPending,
+	}
+
+	r.schedules = append(r.schedules, schedule)
+
+	return cloneSchedule(schedule), nil
+}
+
+// Schedules returns every schedule ordered by effective time
+func (r *MemoryPackSizeRepository) Schedules(_ context.Context) ([]model.PackSetSchedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedules := make([]model.PackSetSchedule, 0, len(r.schedules))
+	for _, schedule := range r.schedules {
+		schedules = append(schedules, cloneSchedule(schedule))
+	}
+
+	sortSchedules(schedules)
+
+	return schedules, nil
+}
+
+// CancelSchedule deletes a pending schedule
+func (r *MemoryPackSizeRepository) CancelSchedule(_ context.Context, id int) error {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	i, err := r.pendingSchedule(id)
+	if err != nil {
+		return err
+	}
+
+	r.schedules = slices.Delete(r.schedules, i, i+1)
+
+	return nil
+}
+
+// ActivateSchedule atomically swaps a pending schedule in as the live set
+func (r *MemoryPackSizeRepository) ActivateSchedule(_ context.Context, id int) (model.PackSetSchedule, error) {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	i, err := r.pendingSchedule(id)
+	if err != nil {
+		return model.PackSetSchedule{}, err
+	}
+
+	version, changed := activateSchedule(r.current(), &r.schedules[i])
+	if changed {
+		r.record(version)
+	}
+
+	return cloneSchedule(r.schedules[i]), nil
+}
+
+// Stock returns the packs in stock by size
+func (r *MemoryPackSizeRepository) Stock(_ context.Context) (map[int]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return maps.Clone(r.stock), nil
+}
+
+// SetStock sets the stock levels of the given sizes
+func (r *MemoryPackSizeRepository) SetStock(_ context.Context, levels map[int]int) error {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	maps.Copy(r.stock, levels)
+
+	return nil
+}
+
+// ClearStock drops the stock level of a size
+func (r *MemoryPackSizeRepository) ClearStock(_ context.Context, size int) error {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	delete(r.stock, size)
+
+	return nil
+}
+
+// Costs returns what every pack size and every surplus item costs
+func (r *MemoryPackSizeRepository) Costs(_ context.Context) (model.PackCosts, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return cloneCosts(r.costs), nil
+}
+
+// SetCosts replaces the pack costs
+func (r *MemoryPackSizeRepository) SetCosts(_ context.Context, costs model.PackCosts) error {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	r.costs = cloneCosts(costs)
+
+	return nil
+}
+
+// Specs returns the weight and dimensions of every pack size
+func (r *MemoryPackSizeRepository) Specs(_ context.Context) ([]model.PackSpec, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.specs), nil
+}
+
+// SetSpecs replaces the pack specs
+func (r *MemoryPackSizeRepository) SetSpecs(_ context.Context, specs []model.PackSpec) error {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	r.specs = slices.Clone(specs)
+
+	return nil
+}
+
+// Constraints returns the fewest and most packs of each size a combination may hold
+func (r *MemoryPackSizeRepository) Constraints(_ context.Context) (model.PackConstraints, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return cloneConstraints(r.constraints), nil
+}
+
+// SetConstraints replaces the pack count constraints
+func (r *MemoryPackSizeRepository) SetConstraints(_ context.Context, constraints model.PackConstraints) error {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	r.constraints = cloneConstraints(constraints)
+
+	return nil
+}
+
+// Customers returns every customer profile ordered by id
+func (r *MemoryPackSizeRepository) Customers(_ context.Context) ([]model.CustomerProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	profiles := make([]model.CustomerProfile, 0, len(r.customers))
+	for _, profile := range r.customers {
+		profiles = append(profiles, cloneCustomer(profile))
+	}
+
+	sortCustomers(profiles)
+
+	return profiles, nil
+}
+
+// Customer returns a single customer profile or ErrCustomerNotFound
+func (r *MemoryPackSizeRepository) Customer(_ context.Context, id string) (model.CustomerProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	profile, ok := r.customers[id]
+	if !ok {
+		return model.CustomerProfile{}, ErrCustomerNotFound
+	}
+
+	return cloneCustomer(profile), nil
+}
+
+// SetCustomer stores a customer profile, replacing the one with the same id
+func (r *MemoryPackSizeRepository) SetCustomer(_ context.Context, profile model.CustomerProfile) error {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	r.customers[profile.ID] = cloneCustomer(profile)
+
+	return nil
+}
+
+// DeleteCustomer removes a customer profile or returns ErrCustomerNotFound
+func (r *MemoryPackSizeRepository) DeleteCustomer(_ context.Context, id string) error {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.customers[id]; !ok {
+		return ErrCustomerNotFound
+	}
+
+	delete(r.customers, id)
+
+	return nil
+}
+
+// Packaging returns the cartons and pallet packs ship in
+func (r *MemoryPackSizeRepository) Packaging(_ context.Context) (model.Packaging, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return clonePackaging(r.packaging), nil
+}
+
+// SetPackaging replaces the packaging hierarchy
+func (r *MemoryPackSizeRepository) SetPackaging(_ context.Context, packaging model.Packaging) error {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	r.packaging = clonePackaging(packaging)
+
+	return nil
+}
+
+// Reserve atomically takes packs out of stock and holds them under a new reservation
+func (r *MemoryPackSizeRepository) Reserve(_ context.Context, actor string, packs map[int]int, ttl time.Duration) (model.PackReservation, error) {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	held, err := holdStock(r.stock, packs)
+	if err != nil {
+		return model.PackReservation{}, err
+	}
+
+	for size, n := range held {
+		r.stock[size] -= n
+	}
+
+	reservation := newReservation(r.namespace, actor, held, ttl)
+	r.reservations[reservation.ID] = reservation
+
+	return cloneReservation(reservation), nil
+}
+
+// ConfirmReservation settles a held reservation, keeping its packs out of stock
+func (r *MemoryPackSizeRepository) ConfirmReservation(_ context.Context, id string) (model.PackReservation, error) {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	return r.settleReservation(id, model.ReservationStatusConfirmed, time.Now())
+}
+
+// ReleaseReservation settles a held reservation, returning its packs to stock
+func (r *MemoryPackSizeRepository) ReleaseReservation(_ context.Context, id string) (model.PackReservation, error) {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	return r.settleReservation(id, model.ReservationStatusReleased, time.Now())
+}
+
+// ExpireReservations settles every reservation expired at now, returning its packs to stock
+func (r *MemoryPackSizeRepository) ExpireReservations(_ context.Context, now time.Time) ([]model.PackReservation, error) {
+	r = r.lock()
+	defer r.mu.Unlock()
+
+	var expired []model.PackReservation
+	for id, reservation := range r.reservations {
+		if now.Before(reservation.ExpiresAt) {
+			continue
+		}
+
+		reservation, err := r.settleReservation(id, model.ReservationStatusExpired, now)
+		if err != nil {
+			return expired, err
+		}
+
+		expired = append(expired, reservation)
+	}
+
+	return expired, nil
+}
+
+// settleReservation moves a held reservation to status and drops it, the caller must hold the lock
+func (r *MemoryPackSizeRepository) settleReservation(id string, status model.ReservationStatus, now time.Time) (model.PackReservation, error) {
+	reservation, ok := r.reservations[id]
+	if !ok {
+		return model.PackReservation{}, ErrReservationNotFound
+	}
+
+	restock, err := settleReservation(&reservation, status, now)
+	if err != nil {
+		return model.PackReservation{}, err
+	}
+
+	if restock {
+		// Packs of a size whose stock stopped being tracked return to unlimited stock
+		for size, n := range trackedPacks(r.stock, reservation.Packs) {
+			r.stock[size] += n
+		}
+	}
+
+	delete(r.reservations, id)
+
+	return cloneReservation(reservation), nil
+}
+
+// pendingSchedule returns the index of a pending schedule, the caller must hold the lock
+func (r *MemoryPackSizeRepository) pendingSchedule(id int) (int, error) {
+	i := slices.IndexFunc(r.schedules, func(s model.PackSetSchedule) bool {
+		return s.ID == id
+	})
+	if i < 0 {
+		return 0, ErrScheduleNotFound
+	}
+
+	if r.schedules[i].Status != model.ScheduleStatusPending {
+		return 0, ErrScheduleNotPending
+	}
+
+	return i, nil
+}
+
+// cloneSchedule returns a copy of schedule that does not share its sizes
+func cloneSchedule(schedule model.PackSetSchedule) model.PackSetSchedule {
+	schedule.Sizes = slices.Clone(schedule.Sizes)
+	return schedule
+}
+
+// cloneReservation returns a copy of reservation that does not share its packs
+func cloneReservation(reservation model.PackReservation) model.PackReservation {
+	reservation.Packs = maps.Clone(reservation.Packs)
+	return reservation
+}
+
+// cloneCosts returns a copy of costs that does not share its packs
+func cloneCosts(costs model.PackCosts) model.PackCosts {
+	costs.Packs = slices.Clone(costs.Packs)
+	return costs
+}
+
+// cloneConstraints returns a copy of constraints that shares none of its maps
+func cloneConstraints(constraints model.PackConstraints) model.PackConstraints {
+	constraints.MinPacks = maps.Clone(constraints.MinPacks)
+	constraints.MaxPacks = maps.Clone(constraints.MaxPacks)
+
+	return constraints
+}
+
+// cloneCustomer returns a copy of a customer profile that shares none of its sizes
+func cloneCustomer(profile model.CustomerProfile) model.CustomerProfile {
+	profile.AllowedSizes = slices.Clone(profile.AllowedSizes)
+	profile.ExcludedSizes = slices.Clone(profile.ExcludedSizes)
+
+	return profile
+}
+
+// clonePackaging returns a copy of packaging that shares none of its cartons or pallet
+func clonePackaging(packaging model.Packaging) model.Packaging {
+	packaging.Cartons = slices.Clone(packaging.Cartons)
+	for i := range packaging.Cartons {
+		packaging.Cartons[i].PackSizes = slices.Clone(packaging.Cartons[i].PackSizes)
+	}
+
+	if packaging.Pallet != nil {
+		pallet := *packaging.Pallet
+		packaging.Pallet = &pallet
+	}
+
+	return packaging
+}
@@ -0,0 +1,548 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+func TestMemoryPackSizeRepositoryUpdate(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository(500, 250)
+
+	previous, version, err := r.Update(ctx, "alice", func(current []int) ([]int, error) {
+		return append(current, 1000), nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(previous, []int{500, 250}) {
+		t.Errorf("Update() previous = %v, want [500 250]", previous)
+	}
+
+	if version.Version != 1 || version.Actor != "alice" || !reflect.DeepEqual(version.Sizes, []int{1000, 500, 250}) {
+		t.Errorf("Update() version = %+v, want version 1 by alice with [1000 500 250]", version)
+	}
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if current.Version != 1 || !reflect.DeepEqual(current.Sizes, []int{1000, 500, 250}) {
+		t.Errorf("Current() = %+v, want version 1 with [1000 500 250]", current)
+	}
+}
+
+func TestMemoryPackSizeRepositoryUpdateWithoutChange(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository(500, 250)
+
+	_, version, err := r.Update(ctx, "alice", func(current []int) ([]int, error) {
+		// Duplicates and order do not make a new set
+		return append(current, 250), nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if version.Version != 0 {
+		t.Errorf("Update() without change recorded version %d, want 0", version.Version)
+	}
+
+	versions, err := r.Versions(ctx)
+	if err != nil {
+		t.Fatalf("Versions() error = %v", err)
+	}
+
+	if len(versions) != 0 {
+		t.Errorf("Versions() = %v, want none", versions)
+	}
+}
+
+func TestMemoryPackSizeRepositoryUpdateError(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository(500, 250)
+	errRejected := errors.New("rejected")
+
+	if _, _, err := r.Update(ctx, "alice", func([]int) ([]int, error) {
+		return nil, errRejected
+	}); !errors.Is(err, errRejected) {
+		t.Fatalf("Update() error = %v, want %v", err, errRejected)
+	}
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(current.Sizes, []int{500, 250}) {
+		t.Errorf("Current() after a rejected update = %v, want [500 250]", current.Sizes)
+	}
+}
+
+func TestMemoryPackSizeRepositoryVersions(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository()
+
+	sets := [][]int{{250, 500}, {23, 31, 53}, {250, 500}}
+	for _, set := range sets {
+		if _, _, err := r.Update(ctx, "alice", func([]int) ([]int, error) {
+			return set, nil
+		}); err != nil {
+			t.Fatalf("Update(%v) error = %v", set, err)
+		}
+	}
+
+	versions, err := r.Versions(ctx)
+	if err != nil {
+		t.Fatalf("Versions() error = %v", err)
+	}
+
+	if len(versions) != len(sets) {
+		t.Fatalf("Versions() returned %d versions, want %d", len(versions), len(sets))
+	}
+
+	for i, version := range versions {
+		expected := slices.Clone(sets[i])
+		slices.Sort(expected)
+		slices.Reverse(expected)
+
+		if version.Version != i+1 || !reflect.DeepEqual(version.Sizes, expected) {
+			t.Errorf("Versions()[%d] = %+v, want version %d with %v", i, version, i+1, expected)
+		}
+	}
+
+	version, err := r.Version(ctx, 2)
+	if err != nil {
+		t.Fatalf("Version(2) error = %v", err)
+	}
+
+	if !reflect.DeepEqual(version.Sizes, []int{53, 31, 23}) {
+		t.Errorf("Version(2) = %v, want [53 31 23]", version.Sizes)
+	}
+
+	for _, n := range []int{0, 4} {
+		if _, err := r.Version(ctx, n); !errors.Is(err, ErrVersionNotFound) {
+			t.Errorf("Version(%d) error = %v, want %v", n, err, ErrVersionNotFound)
+		}
+	}
+}
+
+func TestMemoryPackSizeRepositoryConcurrentUpdate(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository()
+
+	var wg sync.WaitGroup
+	for size := 1; size <= 100; size++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = r.Update(ctx, "alice", func(current []int) ([]int, error) {
+				return append(current, size), nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if len(current.Sizes) != 100 || current.Version != 100 {
+		t.Errorf("Current() = %d sizes at version %d, want 100 sizes at version 100", len(current.Sizes), current.Version)
+	}
+}
+
+func TestMemoryPackSizeRepositoryActivateSchedule(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository(500, 250)
+
+	schedule, err := r.Schedule(ctx, "alice", []int{23, 53, 31}, time.Now())
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	activated, err := r.ActivateSchedule(ctx, schedule.ID)
+	if err != nil {
+		t.Fatalf("ActivateSchedule() error = %v", err)
+	}
+
+	if activated.Status != model.ScheduleStatusActivated || activated.ActivatedVersion != 1 {
+		t.Errorf("ActivateSchedule() = %+v, want activated as version 1", activated)
+	}
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if current.Actor != "alice" || !reflect.DeepEqual(current.Sizes, []int{53, 31, 23}) {
+		t.Errorf("Current() = %+v, want [53 31 23] by alice", current)
+	}
+
+	if _, err := r.ActivateSchedule(ctx, schedule.ID); !errors.Is(err, ErrScheduleNotPending) {
+		t.Errorf("ActivateSchedule() again error = %v, want %v", err, ErrScheduleNotPending)
+	}
+
+	if _, err := r.ActivateSchedule(ctx, 99); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("ActivateSchedule() of a missing schedule error = %v, want %v", err, ErrScheduleNotFound)
+	}
+}
+
+func TestMemoryPackSizeRepositoryActivateSupersededSchedule(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository()
+
+	schedule, err := r.Schedule(ctx, "alice", []int{23, 53, 31}, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	// A change recorded after the schedule took effect but before it was activated
+	if _, _, err := r.Update(ctx, "bob", func([]int) ([]int, error) {
+		return []int{500, 250}, nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	settled, err := r.ActivateSchedule(ctx, schedule.ID)
+	if err != nil {
+		t.Fatalf("ActivateSchedule() error = %v", err)
+	}
+
+	if settled.Status != model.ScheduleStatusSuperseded {
+		t.Errorf("ActivateSchedule() status = %v, want %v", settled.Status, model.ScheduleStatusSuperseded)
+	}
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(current.Sizes, []int{500, 250}) {
+		t.Errorf("Current() after a superseded schedule = %v, want [500 250]", current.Sizes)
+	}
+}
+
+func TestMemoryPackSizeRepositorySchedules(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository()
+	now := time.Now()
+
+	for _, offset := range []time.Duration{2 * time.Hour, time.Hour, 3 * time.Hour} {
+		if _, err := r.Schedule(ctx, "alice", []int{250}, now.Add(offset)); err != nil {
+			t.Fatalf("Schedule() error = %v", err)
+		}
+	}
+
+	if err := r.CancelSchedule(ctx, 3); err != nil {
+		t.Fatalf("CancelSchedule() error = %v", err)
+	}
+
+	if err := r.CancelSchedule(ctx, 3); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("CancelSchedule() again error = %v, want %v", err, ErrScheduleNotFound)
+	}
+
+	schedules, err := r.Schedules(ctx)
+	if err != nil {
+		t.Fatalf("Schedules() error = %v", err)
+	}
+
+	ids := make([]int, 0, len(schedules))
+	for _, schedule := range schedules {
+		ids = append(ids, schedule.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []int{2, 1}) {
+		t.Errorf("Schedules() ids = %v, want [2 1] ordered by effective time", ids)
+	}
+}
+
+func TestMemoryPackSizeStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryPackSizeStore()
+
+	apparel, err := s.Namespace("apparel")
+	if err != nil {
+		t.Fatalf("Namespace(apparel) error = %v", err)
+	}
+
+	if _, _, err := apparel.Update(ctx, "alice", func([]int) ([]int, error) {
+		return []int{12, 6}, nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// Reading a namespace does not make it hold anything
+	if _, err := s.Namespace("unused"); err != nil {
+		t.Fatalf("Namespace(unused) error = %v", err)
+	}
+
+	defaultRepo, err := s.Namespace(DefaultNamespace)
+	if err != nil {
+		t.Fatalf("Namespace(default) error = %v", err)
+	}
+
+	current, err := defaultRepo.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if len(current.Sizes) != 0 {
+		t.Errorf("Current() of the default namespace = %v, want no sizes", current.Sizes)
+	}
+
+	namespaces, err := s.Namespaces(ctx)
+	if err != nil {
+		t.Fatalf("Namespaces() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(namespaces, []string{"apparel", DefaultNamespace}) {
+		t.Errorf("Namespaces() = %v, want [apparel default]", namespaces)
+	}
+
+	for _, namespace := range []string{"", "Apparel", "a:b", "-apparel", strings.Repeat("a", 65), SKUNamespace("Apparel", "SKU-1")} {
+		if _, err := s.Namespace(namespace); !errors.Is(err, ErrInvalidNamespace) {
+			t.Errorf("Namespace(%q) error = %v, want %v", namespace, err, ErrInvalidNamespace)
+		}
+	}
+}
+
+func TestMemoryPackSizeStoreSKU(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryPackSizeStore()
+
+	for _, name := range []string{SKUNamespace("apparel", "SKU-123"), SKUNamespace("apparel", "widget.blue")} {
+		repo, err := s.Namespace(name)
+		if err != nil {
+			t.Fatalf("Namespace(%s) error = %v", name, err)
+		}
+
+		if _, _, err := repo.Update(ctx, "alice", func([]int) ([]int, error) {
+			return []int{12, 6}, nil
+		}); err != nil {
+			t.Fatalf("Update() in %s error = %v", name, err)
+		}
+	}
+
+	// A SKU's pack set is kept apart from the pack set of its namespace
+	apparel, err := s.Namespace("apparel")
+	if err != nil {
+		t.Fatalf("Namespace(apparel) error = %v", err)
+	}
+
+	current, err := apparel.Current(ctx)
+	if err != nil || len(current.Sizes) != 0 {
+		t.Errorf("Current() of apparel = %v, error = %v, want no sizes", current.Sizes, err)
+	}
+
+	namespaces, err := s.Namespaces(ctx)
+	if err != nil {
+		t.Fatalf("Namespaces() error = %v", err)
+	}
+
+	expected := []string{"apparel/SKU-123", "apparel/widget.blue", DefaultNamespace}
+	if !reflect.DeepEqual(namespaces, expected) {
+		t.Errorf("Namespaces() = %v, want %v", namespaces, expected)
+	}
+
+	for _, sku := range []string{"", "-sku", "a:b", "a/b", "a b", strings.Repeat("a", 129)} {
+		if _, err := s.Namespace(SKUNamespace("apparel", sku)); !errors.Is(err, ErrInvalidSKU) {
+			t.Errorf("Namespace(%q) error = %v, want %v", SKUNamespace("apparel", sku), err, ErrInvalidSKU)
+		}
+	}
+}
+
+func TestMemoryPackSizeStoreKeepsWrittenNamespaces(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryPackSizeStore()
+
+	// Reading a namespace or SKU not stored yet keeps nothing
+	for _, name := range []string{"unknown", SKUNamespace("unknown", "SKU-1")} {
+		repo, err := s.Namespace(name)
+		if err != nil {
+			t.Fatalf("Namespace(%s) error = %v", name, err)
+		}
+
+		if _, err := repo.Current(ctx); err != nil {
+			t.Fatalf("Current() in %s error = %v", name, err)
+		}
+
+		if _, ok := s.repos[name]; ok {
+			t.Errorf("Namespace(%s) kept a repository after a read", name)
+		}
+	}
+
+	// Repositories handed out before the first write all write to the one kept
+	first, err := s.Namespace("apparel")
+	if err != nil {
+		t.Fatalf("Namespace(apparel) error = %v", err)
+	}
+
+	second, err := s.Namespace("apparel")
+	if err != nil {
+		t.Fatalf("Namespace(apparel) error = %v", err)
+	}
+
+	if err := first.SetStock(ctx, map[int]int{12: 3}); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	if err := second.SetCosts(ctx, model.PackCosts{SurplusItemCost: 2}); err != nil {
+		t.Fatalf("SetCosts() error = %v", err)
+	}
+
+	stored, ok := s.repos["apparel"]
+	if !ok || stored != first {
+		t.Fatalf("Namespace(apparel) kept %p, want %p", stored, first)
+	}
+
+	stock, err := stored.Stock(ctx)
+	if err != nil || !reflect.DeepEqual(stock, map[int]int{12: 3}) {
+		t.Errorf("Stock() = %v, error = %v, want map[12:3]", stock, err)
+	}
+
+	costs, err := stored.Costs(ctx)
+	if err != nil || costs.SurplusItemCost != 2 {
+		t.Errorf("Costs() = %+v, error = %v, want a surplus item cost of 2", costs, err)
+	}
+}
+
+func TestMemoryPackSizeRepositoryStock(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository(500, 250)
+
+	if err := r.SetStock(ctx, map[int]int{500: 4, 250: 0}); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	if err := r.SetStock(ctx, map[int]int{500: 2}); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	if err := r.ClearStock(ctx, 250); err != nil {
+		t.Fatalf("ClearStock() error = %v", err)
+	}
+
+	stock, err := r.Stock(ctx)
+	if err != nil {
+		t.Fatalf("Stock() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(stock, map[int]int{500: 2}) {
+		t.Errorf("Stock() = %v, want {500: 2}", stock)
+	}
+}
+
+func TestMemoryPackSizeRepositoryCustomers(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository(500, 250)
+
+	profiles := []model.CustomerProfile{
+		{ID: "dock-b", ExcludedSizes: []int{500}},
+		{ID: "dock-a", AllowedSizes: []int{250}},
+	}
+
+	for _, profile := range profiles {
+		if err := r.SetCustomer(ctx, profile); err != nil {
+			t.Fatalf("SetCustomer() error = %v", err)
+		}
+	}
+
+	// Stored profiles share nothing with the caller's
+	profiles[1].AllowedSizes[0] = 500
+
+	got, err := r.Customers(ctx)
+	if err != nil {
+		t.Fatalf("Customers() error = %v", err)
+	}
+
+	if len(got) != 2 || got[0].ID != "dock-a" || !slices.Equal(got[0].AllowedSizes, []int{250}) || got[1].ID != "dock-b" {
+		t.Errorf("Customers() = %+v, want dock-a allowing 250, then dock-b", got)
+	}
+
+	if err := r.DeleteCustomer(ctx, "dock-a"); err != nil {
+		t.Fatalf("DeleteCustomer() error = %v", err)
+	}
+
+	if _, err := r.Customer(ctx, "dock-a"); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("Customer() error = %v, want %v", err, ErrCustomerNotFound)
+	}
+
+	if err := r.DeleteCustomer(ctx, "dock-a"); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("DeleteCustomer() error = %v, want %v", err, ErrCustomerNotFound)
+	}
+}
+
+func TestMemoryPackSizeRepositoryReservations(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPackSizeRepository(500, 250)
+
+	if err := r.SetStock(ctx, map[int]int{500: 2}); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	if _, err := r.Reserve(ctx, "alice", map[int]int{500: 3}, time.Minute); !errors.Is(err, ErrStockExhausted) {
+		t.Fatalf("Reserve() beyond the stock error = %v, want %v", err, ErrStockExhausted)
+	}
+
+	held, err := r.Reserve(ctx, "alice", map[int]int{500: 2, 250: 1}, time.Minute)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+
+	// Unlimited sizes are not held
+	if held.Status != model.ReservationStatusHeld || !reflect.DeepEqual(held.Packs, map[int]int{500: 2}) {
+		t.Errorf("Reserve() = %+v, want {500: 2} held", held)
+	}
+
+	expiring, err := r.Reserve(ctx, "bob", map[int]int{250: 4}, 0)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+
+	if _, err := r.ConfirmReservation(ctx, expiring.ID); !errors.Is(err, ErrReservationExpired) {
+		t.Errorf("ConfirmReservation() of an expired reservation error = %v, want %v", err, ErrReservationExpired)
+	}
+
+	expired, err := r.ExpireReservations(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("ExpireReservations() error = %v", err)
+	}
+
+	if len(expired) != 1 || expired[0].ID != expiring.ID || expired[0].Status != model.ReservationStatusExpired {
+		t.Errorf("ExpireReservations() = %+v, want reservation %s expired", expired, expiring.ID)
+	}
+
+	released, err := r.ReleaseReservation(ctx, held.ID)
+	if err != nil {
+		t.Fatalf("ReleaseReservation() error = %v", err)
+	}
+
+	if released.Status != model.ReservationStatusReleased {
+		t.Errorf("ReleaseReservation() status = %v, want %v", released.Status, model.ReservationStatusReleased)
+	}
+
+	if _, err := r.ReleaseReservation(ctx, held.ID); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("ReleaseReservation() again error = %v, want %v", err, ErrReservationNotFound)
+	}
+
+	stock, err := r.Stock(ctx)
+	if err != nil {
+		t.Fatalf("Stock() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(stock, map[int]int{500: 2}) {
+		t.Errorf("Stock() after releasing = %v, want {500: 2}", stock)
+	}
+}
@@ -0,0 +1,830 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Amir-Sadati/order-packing/internal/constants"
+	"github.com/Amir-Sadati/order-packing/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisPackSizeRepository stores pack sizes in a Redis sorted set scored by size, stock
+// levels in a Redis hash by size, costs, specs, constraints and packaging as JSON in Redis strings, every recorded version
+// as JSON in a Redis list and schedules, customer profiles and held reservations as JSON in Redis hashes
+type RedisPackSizeRepository struct {
+	rdb  *redis.Client
+	keys redisKeys
+}
+
+// NewRedisPackSizeRepository creates and returns a new RedisPackSizeRepository instance
+// for the given namespace
+func NewRedisPackSizeRepository(rdb *redis.Client, namespace string) *RedisPackSizeRepository {
+	return &RedisPackSizeRepository{
+		rdb:  rdb,
+		keys: newRedisKeys(namespace),
+	}
+}
+
+// RedisPackSizeStore hands out Redis pack size repositories by namespace
+type RedisPackSizeStore struct {
+	rdb *redis.Client
+}
+
+// NewRedisPackSizeStore creates and returns a new RedisPackSizeStore instance
+func NewRedisPackSizeStore(rdb *redis.Client) *RedisPackSizeStore {
+	return &RedisPackSizeStore{
+		rdb: rdb,
+	}
+}
+
+// Namespace returns the repository of a namespace or of a SKU within a namespace
+func (s *RedisPackSizeStore) Namespace(namespace string) (PackSizeRepository, error) {
+	if _, _, err := parseNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	return NewRedisPackSizeRepository(s.rdb, namespace), nil
+}
+
+// Namespaces returns every namespace and SKU pack set holding a pack set or a schedule,
+// sorted by name
+func (s *RedisPackSizeStore) Namespaces(ctx context.Context) ([]string, error) {
+	namespaces, err := s.rdb.SMembers(ctx, string(constants.RedisKeyNamespaces)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	// The default namespace may predate the namespace set
+	if !slices.Contains(namespaces, DefaultNamespace) {
+		namespaces = append(namespaces, DefaultNamespace)
+	}
+
+	slices.Sort(namespaces)
+
+	return namespaces, nil
+}
+
+// Current returns the live pack set along with the version it was recorded as
+func (r *RedisPackSizeRepository) Current(ctx context.Context) (model.PackSetVersion, error) {
+	return currentPackSet(ctx, r.rdb, r.keys)
+}
+
+// Update atomically applies fn to the current set, stores the result and records it
+// as a new version. The set and its versions are watched while they are read, so a
+// concurrent change retries the update instead of mixing both.
+func (r *RedisPackSizeRepository) Update(ctx context.Context, actor string, fn UpdateFunc) ([]int, model.PackSetVersion, error) {
+	var (
+		previous []int
+		version  model.PackSetVersion
+	)
+
+	txf := func(tx *redis.Tx) error {
+		current, err := currentPackSet(ctx, tx, r.keys)
+		if err != nil {
+			return err
+		}
+
+		next, err := fn(slices.Clone(current.Sizes))
+		if err != nil {
+			return err
+		}
+
+		previous = current.Sizes
+
+		var changed bool
+		if version, changed = nextVersion(current, next, actor); !changed {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return recordVersion(ctx, pipe, r.keys, version)
+		})
+
+		return err
+	}
+
+	err := watch(ctx, r.rdb, txf, r.keys.sizes, r.keys.versions)
+	if err != nil {
+		return nil, model.PackSetVersion{}, err
+	}
+
+	return previous, version, nil
+}
+
+// Versions returns every recorded version, oldest first
+func (r *RedisPackSizeRepository) Versions(ctx context.Context) ([]model.PackSetVersion, error) {
+	vals, err := r.rdb.LRange(ctx, r.keys.versions, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]model.PackSetVersion, 0, len(vals))
+	for _, v := range vals {
+		var version model.PackSetVersion
+		if err := json.Unmarshal([]byte(v), &version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// Version returns a single recorded version or ErrVersionNotFound
+func (r *RedisPackSizeRepository) Version(ctx context.Context, version int) (model.PackSetVersion, error) {
+	if version < 1 {
+		return model.PackSetVersion{}, ErrVersionNotFound
+	}
+
+	v, err := r.rdb.LIndex(ctx, r.keys.versions, int64(version-1)).Result()
+	if errors.Is(err, redis.Nil) {
+		return model.PackSetVersion{}, ErrVersionNotFound
+	}
+
+	if err != nil {
+		return model.PackSetVersion{}, err
+	}
+
+	var out model.PackSetVersion
+	if err := json.Unmarshal([]byte(v), &out); err != nil {
+		return model.PackSetVersion{}, err
+	}
+
+	return out, nil
+}
+
+// Schedule stages sizes by actor to become the live set at effectiveFrom
+func (r *RedisPackSizeRepository) Schedule(ctx context.Context, actor string, sizes []int, effectiveFrom time.Time) (model.PackSetSchedule, error) {
+	id, err := r.rdb.Incr(ctx, r.keys.scheduleSeq).Result()
+	if err != nil {
+		return model.PackSetSchedule{}, err
+	}
+
+	schedule := model.PackSetSchedule{
+		ID:            int(id),
+		Namespace:     r.keys.namespace,
+		Sizes:         normalizePackSizes(sizes),
+		EffectiveFrom: effectiveFrom.UTC(),
+		CreatedAt:     time.Now().UTC(),
+		Actor:         actor,
+		Status:        model.ScheduleStatusPending,
+	}
+
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return model.PackSetSchedule{}, err
+	}
+
+	_, err = r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.keys.schedules, strconv.Itoa(schedule.ID), data)
+		pipe.SAdd(ctx, string(constants.RedisKeyNamespaces), r.keys.namespace)
+
+		return nil
+	})
+	if err != nil {
+		return model.PackSetSchedule{}, err
+	}
+
+	return schedule, nil
+}
+
+// Schedules returns every schedule ordered by effective time
+func (r *RedisPackSizeRepository) Schedules(ctx context.Context) ([]model.PackSetSchedule, error) {
+	vals, err := r.rdb.HVals(ctx, r.keys.schedules).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]model.PackSetSchedule, 0, len(vals))
+	for _, v := range vals {
+		var schedule model.PackSetSchedule
+		if err := json.Unmarshal([]byte(v), &schedule); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	sortSchedules(schedules)
+
+	return schedules, nil
+}
+
+// CancelSchedule deletes a pending schedule. The schedule is watched while it is read,
+// so it cannot be cancelled while it is being activated.
+func (r *RedisPackSizeRepository) CancelSchedule(ctx context.Context, id int) error {
+	key := r.keys.schedules
+
+	txf := func(tx *redis.Tx) error {
+		if _, err := pendingSchedule(ctx, tx, key, id); err != nil {
+			return err
+		}
+
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, key, strconv.Itoa(id))
+			return nil
+		})
+
+		return err
+	}
+
+	return watch(ctx, r.rdb, txf, key)
+}
+
+// ActivateSchedule atomically swaps a pending schedule in as the live set. The set, its
+// versions and the schedules are watched while they are read.
+func (r *RedisPackSizeRepository) ActivateSchedule(ctx context.Context, id int) (model.PackSetSchedule, error) {
+	var schedule model.PackSetSchedule
+
+	txf := func(tx *redis.Tx) error {
+		var err error
+
+		schedule, err = pendingSchedule(ctx, tx, r.keys.schedules, id)
+		if err != nil {
+			return err
+		}
+
+		current, err := currentPackSet(ctx, tx, r.keys)
+		if err != nil {
+			return err
+		}
+
+		version, changed := activateSchedule(current, &schedule)
+
+		data, err := json.Marshal(schedule)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, r.keys.schedules, strconv.Itoa(id), data)
+			if !changed {
+				return nil
+			}
+
+			return recordVersion(ctx, pipe, r.keys, version)
+		})
+
+		return err
+	}
+
+	err := watch(ctx, r.rdb, txf, r.keys.sizes, r.keys.versions, r.keys.schedules)
+	if err != nil {
+		return model.PackSetSchedule{}, err
+	}
+
+	return schedule, nil
+}
+
+// Stock returns the packs in stock by size
+func (r *RedisPackSizeRepository) Stock(ctx context.Context) (map[int]int, error) {
+	return readStock(ctx, r.rdb, r.keys.stock)
+}
+
+// SetStock sets the stock levels of the given sizes
+func (r *RedisPackSizeRepository) SetStock(ctx context.Context, levels map[int]int) error {
+	if len(levels) == 0 {
+		return nil
+	}
+
+	values := make([]any, 0, 2*len(levels))
+	for size, n := range levels {
+		values = append(values, strconv.Itoa(size), n)
+	}
+
+	return r.rdb.HSet(ctx, r.keys.stock, values...).Err()
+}
+
+// ClearStock drops the stock level of a size
+func (r *RedisPackSizeRepository) ClearStock(ctx context.Context, size int) error {
+	return r.rdb.HDel(ctx, r.keys.stock, strconv.Itoa(size)).Err()
+}
+
+// Costs returns what every pack size and every surplus item costs
+func (r *RedisPackSizeRepository) Costs(ctx context.Context) (model.PackCosts, error) {
+	var costs model.PackCosts
+
+	v, err := r.rdb.Get(ctx, r.keys.costs).Result()
+	if errors.Is(err, redis.Nil) {
+		return costs, nil
+	}
+
+	if err != nil {
+		return model.PackCosts{}, err
+	}
+
+	if err := json.Unmarshal([]byte(v), &costs); err != nil {
+		return model.PackCosts{}, err
+	}
+
+	return costs, nil
+}
+
+// SetCosts replaces the pack costs
+func (r *RedisPackSizeRepository) SetCosts(ctx context.Context, costs model.PackCosts) error {
+	data, err := json.Marshal(costs)
+	if err != nil {
+		return err
+	}
+
+	return r.rdb.Set(ctx, r.keys.costs, data, 0).Err()
+}
+
+// Specs returns the weight and dimensions of every pack size
+func (r *RedisPackSizeRepository) Specs(ctx context.Context) ([]model.PackSpec, error) {
+	var specs []model.PackSpec
+
+	v, err := r.rdb.Get(ctx, r.keys.specs).Result()
+	if errors.Is(err, redis.Nil) {
+		return specs, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(v), &specs); err != nil {
+		return nil, err
+	}
+
+	return specs, nil
+}
+
+// SetSpecs replaces the pack specs
+func (r *RedisPackSizeRepository) SetSpecs(ctx context.Context, specs []model.PackSpec) error {
+	data, err := json.Marshal(specs)
+	if err != nil {
+		return err
+	}
+
+	return r.rdb.Set(ctx, r.keys.specs, data, 0).Err()
+}
+
+// Constraints returns the fewest and most packs of each size a combination may hold
+func (r *RedisPackSizeRepository) Constraints(ctx context.Context) (model.PackConstraints, error) {
+	var constraints model.PackConstraints
+
+	v, err := r.rdb.Get(ctx, r.keys.constraints).Result()
+	if errors.Is(err, redis.Nil) {
+		return constraints, nil
+	}
+
+	if err != nil {
+		return model.PackConstraints{}, err
+	}
+
+	if err := json.Unmarshal([]byte(v), &constraints); err != nil {
+		return model.PackConstraints{}, err
+	}
+
+	return constraints, nil
+}
+
+// SetConstraints replaces the pack count constraints
+func (r *RedisPackSizeRepository) SetConstraints(ctx context.Context, constraints model.PackConstraints) error {
+	data, err := json.Marshal(constraints)
+	if err != nil {
+		return err
+	}
+
+	return r.rdb.Set(ctx, r.keys.constraints, data, 0).Err()
+}
+
+// Customers returns every customer profile ordered by id
+func (r *RedisPackSizeRepository) Customers(ctx context.Context) ([]model.CustomerProfile, error) {
+	vals, err := r.rdb.HVals(ctx, r.keys.customers).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]model.CustomerProfile, 0, len(vals))
+	for _, v := range vals {
+		var profile model.CustomerProfile
+		if err := json.Unmarshal([]byte(v), &profile); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	sortCustomers(profiles)
+
+	return profiles, nil
+}
+
+// Customer returns a single customer profile or ErrCustomerNotFound
+func (r *RedisPackSizeRepository) Customer(ctx context.Context, id string) (model.CustomerProfile, error) {
+	v, err := r.rdb.HGet(ctx, r.keys.customers, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return model.CustomerProfile{}, ErrCustomerNotFound
+	}
+
+	if err != nil {
+		return model.CustomerProfile{}, err
+	}
+
+	var profile model.CustomerProfile
+	if err := json.Unmarshal([]byte(v), &profile); err != nil {
+		return model.CustomerProfile{}, err
+	}
+
+	return profile, nil
+}
+
+// SetCustomer stores a customer profile, replacing the one with the same id
+func (r *RedisPackSizeRepository) SetCustomer(ctx context.Context, profile model.CustomerProfile) error {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+
+	return r.rdb.HSet(ctx, r.keys.customers, profile.ID, data).Err()
+}
+
+// DeleteCustomer removes a customer profile or returns ErrCustomerNotFound
+func (r *RedisPackSizeRepository) DeleteCustomer(ctx context.Context, id string) error {
+	n, err := r.rdb.HDel(ctx, r.keys.customers, id).Result()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrCustomerNotFound
+	}
+
+	return nil
+}
+
+// Packaging returns the cartons and pallet packs ship in
+func (r *RedisPackSizeRepository) Packaging(ctx context.Context) (model.Packaging, error) {
+	var packaging model.Packaging
+
+	v, err := r.rdb.Get(ctx, r.keys.packaging).Result()
+	if errors.Is(err, redis.Nil) {
+		return packaging, nil
+	}
+
+	if err != nil {
+		return model.Packaging{}, err
+	}
+
+	if err := json.Unmarshal([]byte(v), &packaging); err != nil {
+		return model.Packaging{}, err
+	}
+
+	return packaging, nil
+}
+
+// SetPackaging replaces the packaging hierarchy
+func (r *RedisPackSizeRepository) SetPackaging(ctx context.Context, packaging model.Packaging) error {
+	data, err := json.Marshal(packaging)
+	if err != nil {
+		return err
+	}
+
+	return r.rdb.Set(ctx, r.keys.packaging, data, 0).Err()
+}
+
+// Reserve atomically takes packs out of stock and holds them under a new reservation.
+// The stock is watched while it is read, so two reservations cannot both take the last packs.
+func (r *RedisPackSizeRepository) Reserve(ctx context.Context, actor string, packs map[int]int, ttl time.Duration) (model.PackReservation, error) {
+	var reservation model.PackReservation
+
+	txf := func(tx *redis.Tx) error {
+		stock, err := readStock(ctx, tx, r.keys.stock)
+		if err != nil {
+			return err
+		}
+
+		held, err := holdStock(stock, packs)
+		if err != nil {
+			return err
+		}
+
+		reservation = newReservation(r.keys.namespace, actor, held, ttl)
+
+		data, err := json.Marshal(reservation)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for size, n := range held {
+				pipe.HIncrBy(ctx, r.keys.stock, strconv.Itoa(size), -int64(n))
+			}
+			pipe.HSet(ctx, r.keys.reservations, reservation.ID, data)
+			pipe.ZAdd(ctx, r.keys.reservationExpiry, redis.Z{
+				Score:  float64(reservation.ExpiresAt.UnixMilli()),
+				Member: reservation.ID,
+			})
+
+			return nil
+		})
+
+		return err
+	}
+
+	err := watch(ctx, r.rdb, txf, r.keys.stock)
+	if err != nil {
+		return model.PackReservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// ConfirmReservation settles a held reservation, keeping its packs out of stock
+func (r *RedisPackSizeRepository) ConfirmReservation(ctx context.Context, id string) (model.PackReservation, error) {
+	return r.settleReservation(ctx, id, model.ReservationStatusConfirmed, time.Now())
+}
+
+// ReleaseReservation settles a held reservation, returning its packs to stock
+func (r *RedisPackSizeRepository) ReleaseReservation(ctx context.Context, id string) (model.PackReservation, error) {
+	return r.settleReservation(ctx, id, model.ReservationStatusReleased, time.Now())
+}
+
+// ExpireReservations settles every reservation expired at now, returning its packs to stock.
+// Reservations settled concurrently, e.g. by another instance, are skipped.
+func (r *RedisPackSizeRepository) ExpireReservations(ctx context.Context, now time.Time) ([]model.PackReservation, error) {
+	ids, err := r.rdb.ZRangeByScore(ctx, r.keys.reservationExpiry, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []model.PackReservation
+	for _, id := range ids {
+		reservation, err := r.settleReservation(ctx, id, model.ReservationStatusExpired, now)
+		if errors.Is(err, ErrReservationNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return expired, err
+		}
+
+		expired = append(expired, reservation)
+	}
+
+	return expired, nil
+}
+
+// settleReservation moves a held reservation to status and drops it. The reservations and
+// the stock are watched while they are read, so a reservation is settled only once.
+func (r *RedisPackSizeRepository) settleReservation(ctx context.Context, id string, status model.ReservationStatus, now time.Time) (model.PackReservation, error) {
+	var reservation model.PackReservation
+
+	txf := func(tx *redis.Tx) error {
+		var err error
+
+		reservation, err = heldReservation(ctx, tx, r.keys.reservations, id)
+		if err != nil {
+			return err
+		}
+
+		restock, err := settleReservation(&reservation, status, now)
+		if err != nil {
+			return err
+		}
+
+		var returned map[int]int
+		if restock {
+			stock, err := readStock(ctx, tx, r.keys.stock)
+			if err != nil {
+				return err
+			}
+
+			// Packs of a size whose stock stopped being tracked return to unlimited stock
+			returned = trackedPacks(stock, reservation.Packs)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for size, n := range returned {
+				pipe.HIncrBy(ctx, r.keys.stock, strconv.Itoa(size), int64(n))
+			}
+			pipe.HDel(ctx, r.keys.reservations, id)
+			pipe.ZRem(ctx, r.keys.reservationExpiry, id)
+
+			return nil
+		})
+
+		return err
+	}
+
+	err := watch(ctx, r.rdb, txf, r.keys.reservations, r.keys.stock)
+	if err != nil {
+		return model.PackReservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// redisKeys names the Redis keys holding the pack set of a namespace
+type redisKeys struct {
+	namespace         string
+	sizes             string
+	versions          string
+	schedules         string
+	scheduleSeq       string
+	stock             string
+	costs             string
+	specs             string
+	constraints       string
+	packaging         string
+	customers         string
+	reservations      string
+	reservationExpiry string
+}
+
+// newRedisKeys returns the keys of a namespace, or of a SKU nested under the keys of its
+// namespace. The default namespace keeps the keys used before namespaces existed, so its
+// data needs no migration.
+func newRedisKeys(namespace string) redisKeys {
+	parent, sku, _ := strings.Cut(namespace, skuSeparator)
+
+	prefix := ""
+	if parent != DefaultNamespace {
+		prefix = string(constants.RedisKeyNamespacePrefix) + parent + ":"
+	}
+
+	if sku != "" {
+		prefix += string(constants.RedisKeySKUPrefix) + sku + ":"
+	}
+
+	return redisKeys{
+		namespace:         namespace,
+		sizes:             prefix + string(constants.RedisKeyPackSizes),
+		versions:          prefix + string(constants.RedisKeyPackSizeVersions),
+		schedules:         prefix + string(constants.RedisKeyPackSizeSchedules),
+		scheduleSeq:       prefix + string(constants.RedisKeyPackSizeScheduleSeq),
+		stock:             prefix + string(constants.RedisKeyPackSizeStock),
+		costs:             prefix + string(constants.RedisKeyPackSizeCosts),
+		specs:             prefix + string(constants.RedisKeyPackSizeSpecs),
+		constraints:       prefix + string(constants.RedisKeyPackSizeConstraints),
+		packaging:         prefix + string(constants.RedisKeyPackaging),
+		customers:         prefix + string(constants.RedisKeyPackCustomers),
+		reservations:      prefix + string(constants.RedisKeyPackReservations),
+		reservationExpiry: prefix + string(constants.RedisKeyPackReservationExpiry),
+	}
+}
+
+// watch runs txf as an optimistic transaction over keys, retrying it while the watched
+// keys keep changing underneath it
+func watch(ctx context.Context, rdb *redis.Client, txf func(*redis.Tx) error, keys ...string) error {
+	for range maxTxRetries {
+		err := rdb.Watch(ctx, txf, keys...)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+
+		return err
+	}
+
+	return ErrConcurrentUpdate
+}
+
+// recordVersion queues the writes storing version as the live set of a namespace
+func recordVersion(ctx context.Context, pipe redis.Pipeliner, keys redisKeys, version model.PackSetVersion) error {
+	data, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+
+	pipe.Del(ctx, keys.sizes)
+	if len(version.Sizes) > 0 {
+		pipe.ZAdd(ctx, keys.sizes, packSizeMembers(version.Sizes)...)
+	}
+	pipe.RPush(ctx, keys.versions, data)
+	pipe.SAdd(ctx, string(constants.RedisKeyNamespaces), keys.namespace)
+
+	return nil
+}
+
+// pendingSchedule reads a schedule that has not been activated or superseded yet
+func pendingSchedule(ctx context.Context, c redis.Cmdable, key string, id int) (model.PackSetSchedule, error) {
+	v, err := c.HGet(ctx, key, strconv.Itoa(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return model.PackSetSchedule{}, ErrScheduleNotFound
+	}
+
+	if err != nil {
+		return model.PackSetSchedule{}, err
+	}
+
+	var schedule model.PackSetSchedule
+	if err := json.Unmarshal([]byte(v), &schedule); err != nil {
+		return model.PackSetSchedule{}, err
+	}
+
+	if schedule.Status != model.ScheduleStatusPending {
+		return model.PackSetSchedule{}, ErrScheduleNotPending
+	}
+
+	return schedule, nil
+}
+
+// heldReservation reads a reservation that has not been settled yet
+func heldReservation(ctx context.Context, c redis.Cmdable, key, id string) (model.PackReservation, error) {
+	v, err := c.HGet(ctx, key, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return model.PackReservation{}, ErrReservationNotFound
+	}
+
+	if err != nil {
+		return model.PackReservation{}, err
+	}
+
+	var reservation model.PackReservation
+	if err := json.Unmarshal([]byte(v), &reservation); err != nil {
+		return model.PackReservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// readStock reads the stock levels hash
+func readStock(ctx context.Context, c redis.Cmdable, key string) (map[int]int, error) {
+	vals, err := c.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	stock := make(map[int]int, len(vals))
+	for field, v := range vals {
+		size, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+
+		stock[size] = n
+	}
+
+	return stock, nil
+}
+
+// currentPackSet reads the live pack set and the latest recorded version
+func currentPackSet(ctx context.Context, c redis.Cmdable, keys redisKeys) (model.PackSetVersion, error) {
+	sizes, err := listPackSizes(ctx, c, keys.sizes)
+	if err != nil {
+		return model.PackSetVersion{}, err
+	}
+
+	var current model.PackSetVersion
+
+	latest, err := c.LIndex(ctx, keys.versions, -1).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+	case err != nil:
+		return model.PackSetVersion{}, err
+	default:
+		if err := json.Unmarshal([]byte(latest), &current); err != nil {
+			return model.PackSetVersion{}, err
+		}
+	}
+
+	current.Sizes = sizes
+
+	return current, nil
+}
+
+// listPackSizes reads the pack sizes sorted set in descending order
+func listPackSizes(ctx context.Context, c redis.Cmdable, key string) ([]int, error) {
+	vals, err := c.ZRevRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	packSizes := make([]int, 0, len(vals))
+	for _, v := range vals {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		packSizes = append(packSizes, n)
+	}
+
+	return packSizes, nil
+}
+
+// packSizeMembers builds sorted set members scored by size
+func packSizeMembers(sizes []int) []redis.Z {
+	members := make([]redis.Z, 0, len(sizes))
+	for _, size := range sizes {
+		members = append(members, redis.Z{
+			Score:  float64(size),
+			Member: size,
+		})
+	}
+
+	return members
+}
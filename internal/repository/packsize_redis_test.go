@@ -0,0 +1,781 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis starts an in-process Redis server for the test and returns a client to it
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	return mr, rdb
+}
+
+// newTestRedisRepository returns a repository of the default namespace whose live set
+// starts with sizes, without recording a version for them
+func newTestRedisRepository(t *testing.T, sizes ...int) (*RedisPackSizeRepository, *redis.Client) {
+	t.Helper()
+
+	_, rdb := newTestRedis(t)
+	r := NewRedisPackSizeRepository(rdb, DefaultNamespace)
+
+	if len(sizes) > 0 {
+		if err := rdb.ZAdd(context.Background(), r.keys.sizes, packSizeMembers(sizes)...).Err(); err != nil {
+			t.Fatalf("ZAdd() error = %v", err)
+		}
+	}
+
+	return r, rdb
+}
+
+func TestRedisPackSizeRepositoryUpdate(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+
+	previous, version, err := r.Update(ctx, "alice", func(current []int) ([]int, error) {
+		return append(current, 1000), nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(previous, []int{500, 250}) {
+		t.Errorf("Update() previous = %v, want [500 250]", previous)
+	}
+
+	if version.Version != 1 || version.Actor != "alice" || !reflect.DeepEqual(version.Sizes, []int{1000, 500, 250}) {
+		t.Errorf("Update() version = %+v, want version 1 by alice with [1000 500 250]", version)
+	}
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if current.Version != 1 || !reflect.DeepEqual(current.Sizes, []int{1000, 500, 250}) {
+		t.Errorf("Current() = %+v, want version 1 with [1000 500 250]", current)
+	}
+}
+
+func TestRedisPackSizeRepositoryUpdateWithoutChange(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+
+	_, version, err := r.Update(ctx, "alice", func(current []int) ([]int, error) {
+		// Duplicates and order do not make a new set
+		return append(current, 250), nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if version.Version != 0 {
+		t.Errorf("Update() without change recorded version %d, want 0", version.Version)
+	}
+
+	versions, err := r.Versions(ctx)
+	if err != nil {
+		t.Fatalf("Versions() error = %v", err)
+	}
+
+	if len(versions) != 0 {
+		t.Errorf("Versions() = %v, want none", versions)
+	}
+}
+
+func TestRedisPackSizeRepositoryUpdateError(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+	errRejected := errors.New("rejected")
+
+	if _, _, err := r.Update(ctx, "alice", func([]int) ([]int, error) {
+		return nil, errRejected
+	}); !errors.Is(err, errRejected) {
+		t.Fatalf("Update() error = %v, want %v", err, errRejected)
+	}
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(current.Sizes, []int{500, 250}) {
+		t.Errorf("Current() after a rejected update = %v, want [500 250]", current.Sizes)
+	}
+}
+
+func TestRedisPackSizeRepositoryVersions(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t)
+
+	sets := [][]int{{250, 500}, {23, 31, 53}, {250, 500}}
+	for _, set := range sets {
+		if _, _, err := r.Update(ctx, "alice", func([]int) ([]int, error) {
+			return set, nil
+		}); err != nil {
+			t.Fatalf("Update(%v) error = %v", set, err)
+		}
+	}
+
+	versions, err := r.Versions(ctx)
+	if err != nil {
+		t.Fatalf("Versions() error = %v", err)
+	}
+
+	if len(versions) != len(sets) {
+		t.Fatalf("Versions() returned %d versions, want %d", len(versions), len(sets))
+	}
+
+	for i, version := range versions {
+		expected := slices.Clone(sets[i])
+		slices.Sort(expected)
+		slices.Reverse(expected)
+
+		if version.Version != i+1 || !reflect.DeepEqual(version.Sizes, expected) {
+			t.Errorf("Versions()[%d] = %+v, want version %d with %v", i, version, i+1, expected)
+		}
+	}
+
+	version, err := r.Version(ctx, 2)
+	if err != nil {
+		t.Fatalf("Version(2) error = %v", err)
+	}
+
+	if !reflect.DeepEqual(version.Sizes, []int{53, 31, 23}) {
+		t.Errorf("Version(2) = %v, want [53 31 23]", version.Sizes)
+	}
+
+	for _, n := range []int{0, 4} {
+		if _, err := r.Version(ctx, n); !errors.Is(err, ErrVersionNotFound) {
+			t.Errorf("Version(%d) error = %v, want %v", n, err, ErrVersionNotFound)
+		}
+	}
+}
+
+func TestRedisPackSizeRepositoryUpdateConflict(t *testing.T) {
+	ctx := context.Background()
+	r, rdb := newTestRedisRepository(t, 500, 250)
+	other := NewRedisPackSizeRepository(rdb, DefaultNamespace)
+
+	calls := 0
+	_, version, err := r.Update(ctx, "alice", func(current []int) ([]int, error) {
+		calls++
+
+		// Another writer changes the set after it was read, which must retry the update
+		if calls == 1 {
+			if _, _, err := other.Update(ctx, "bob", func(current []int) ([]int, error) {
+				return append(current, 2000), nil
+			}); err != nil {
+				t.Fatalf("Update() by bob error = %v", err)
+			}
+		}
+
+		return append(current, 1000), nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Update() ran its function %d times, want 2", calls)
+	}
+
+	if version.Version != 2 || !reflect.DeepEqual(version.Sizes, []int{2000, 1000, 500, 250}) {
+		t.Errorf("Update() version = %+v, want version 2 with [2000 1000 500 250]", version)
+	}
+
+	versions, err := r.Versions(ctx)
+	if err != nil {
+		t.Fatalf("Versions() error = %v", err)
+	}
+
+	if len(versions) != 2 || versions[0].Actor != "bob" || versions[1].Actor != "alice" {
+		t.Errorf("Versions() = %+v, want bob's version then alice's", versions)
+	}
+}
+
+func TestRedisPackSizeRepositoryUpdateConflictRetriesExhausted(t *testing.T) {
+	ctx := context.Background()
+	r, rdb := newTestRedisRepository(t, 500, 250)
+
+	// Every attempt sees the set change underneath it
+	_, _, err := r.Update(ctx, "alice", func(current []int) ([]int, error) {
+		if err := rdb.ZAdd(ctx, r.keys.sizes, redis.Z{Score: 1, Member: 1}).Err(); err != nil {
+			t.Fatalf("ZAdd() error = %v", err)
+		}
+
+		return append(current, 1000), nil
+	})
+	if !errors.Is(err, ErrConcurrentUpdate) {
+		t.Fatalf("Update() error = %v, want %v", err, ErrConcurrentUpdate)
+	}
+
+	versions, err := r.Versions(ctx)
+	if err != nil {
+		t.Fatalf("Versions() error = %v", err)
+	}
+
+	if len(versions) != 0 {
+		t.Errorf("Versions() = %v, want none recorded", versions)
+	}
+}
+
+func TestRedisPackSizeRepositoryConcurrentUpdate(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		applied int
+	)
+
+	for size := 1; size <= 20; size++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := r.Update(ctx, "alice", func(current []int) ([]int, error) {
+				return append(current, size), nil
+			})
+
+			if err != nil && !errors.Is(err, ErrConcurrentUpdate) {
+				t.Errorf("Update() error = %v", err)
+			}
+
+			if err == nil {
+				mu.Lock()
+				applied++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	// Updates may give up under contention, but none is lost or mixed with another
+	if len(current.Sizes) != applied || current.Version != applied {
+		t.Errorf("Current() = %d sizes at version %d, want %d sizes at version %d", len(current.Sizes), current.Version, applied, applied)
+	}
+}
+
+func TestRedisPackSizeRepositoryActivateSchedule(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+
+	schedule, err := r.Schedule(ctx, "alice", []int{23, 53, 31}, time.Now())
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	activated, err := r.ActivateSchedule(ctx, schedule.ID)
+	if err != nil {
+		t.Fatalf("ActivateSchedule() error = %v", err)
+	}
+
+	if activated.Status != model.ScheduleStatusActivated || activated.ActivatedVersion != 1 {
+		t.Errorf("ActivateSchedule() = %+v, want activated as version 1", activated)
+	}
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if current.Actor != "alice" || !reflect.DeepEqual(current.Sizes, []int{53, 31, 23}) {
+		t.Errorf("Current() = %+v, want [53 31 23] by alice", current)
+	}
+
+	if _, err := r.ActivateSchedule(ctx, schedule.ID); !errors.Is(err, ErrScheduleNotPending) {
+		t.Errorf("ActivateSchedule() again error = %v, want %v", err, ErrScheduleNotPending)
+	}
+
+	if _, err := r.ActivateSchedule(ctx, 99); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("ActivateSchedule() of a missing schedule error = %v, want %v", err, ErrScheduleNotFound)
+	}
+}
+
+func TestRedisPackSizeRepositoryActivateSupersededSchedule(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t)
+
+	schedule, err := r.Schedule(ctx, "alice", []int{23, 53, 31}, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	// A change recorded after the schedule took effect but before it was activated
+	if _, _, err := r.Update(ctx, "bob", func([]int) ([]int, error) {
+		return []int{500, 250}, nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	settled, err := r.ActivateSchedule(ctx, schedule.ID)
+	if err != nil {
+		t.Fatalf("ActivateSchedule() error = %v", err)
+	}
+
+	if settled.Status != model.ScheduleStatusSuperseded {
+		t.Errorf("ActivateSchedule() status = %v, want %v", settled.Status, model.ScheduleStatusSuperseded)
+	}
+
+	current, err := r.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(current.Sizes, []int{500, 250}) {
+		t.Errorf("Current() after a superseded schedule = %v, want [500 250]", current.Sizes)
+	}
+}
+
+func TestRedisPackSizeRepositoryConcurrentActivateSchedule(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+
+	schedule, err := r.Schedule(ctx, "alice", []int{23, 53, 31}, time.Now())
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		activated int
+		cancelled int
+	)
+
+	// Activations and cancellations race for the same schedule
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var err error
+			if i%2 == 0 {
+				_, err = r.ActivateSchedule(ctx, schedule.ID)
+			} else {
+				err = r.CancelSchedule(ctx, schedule.ID)
+			}
+
+			switch {
+			case err == nil:
+				mu.Lock()
+				if i%2 == 0 {
+					activated++
+				} else {
+					cancelled++
+				}
+				mu.Unlock()
+			case !errors.Is(err, ErrScheduleNotPending) && !errors.Is(err, ErrScheduleNotFound) && !errors.Is(err, ErrConcurrentUpdate):
+				t.Errorf("settling the schedule error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if activated+cancelled != 1 {
+		t.Fatalf("schedule activated %d and cancelled %d times, want settled once", activated, cancelled)
+	}
+
+	versions, err := r.Versions(ctx)
+	if err != nil {
+		t.Fatalf("Versions() error = %v", err)
+	}
+
+	if len(versions) != activated {
+		t.Errorf("Versions() recorded %d versions, want %d", len(versions), activated)
+	}
+}
+
+func TestRedisPackSizeRepositorySchedules(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t)
+	now := time.Now()
+
+	for _, offset := range []time.Duration{2 * time.Hour, time.Hour, 3 * time.Hour} {
+		if _, err := r.Schedule(ctx, "alice", []int{250}, now.Add(offset)); err != nil {
+			t.Fatalf("Schedule() error = %v", err)
+		}
+	}
+
+	if err := r.CancelSchedule(ctx, 3); err != nil {
+		t.Fatalf("CancelSchedule() error = %v", err)
+	}
+
+	if err := r.CancelSchedule(ctx, 3); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("CancelSchedule() again error = %v, want %v", err, ErrScheduleNotFound)
+	}
+
+	schedules, err := r.Schedules(ctx)
+	if err != nil {
+		t.Fatalf("Schedules() error = %v", err)
+	}
+
+	ids := make([]int, 0, len(schedules))
+	for _, schedule := range schedules {
+		ids = append(ids, schedule.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []int{2, 1}) {
+		t.Errorf("Schedules() ids = %v, want [2 1] ordered by effective time", ids)
+	}
+}
+
+func TestRedisPackSizeStore(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newTestRedis(t)
+	s := NewRedisPackSizeStore(rdb)
+
+	apparel, err := s.Namespace("apparel")
+	if err != nil {
+		t.Fatalf("Namespace(apparel) error = %v", err)
+	}
+
+	if _, _, err := apparel.Update(ctx, "alice", func([]int) ([]int, error) {
+		return []int{12, 6}, nil
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// Reading a namespace does not make it hold anything
+	if _, err := s.Namespace("unused"); err != nil {
+		t.Fatalf("Namespace(unused) error = %v", err)
+	}
+
+	defaultRepo, err := s.Namespace(DefaultNamespace)
+	if err != nil {
+		t.Fatalf("Namespace(default) error = %v", err)
+	}
+
+	current, err := defaultRepo.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+
+	if len(current.Sizes) != 0 {
+		t.Errorf("Current() of the default namespace = %v, want no sizes", current.Sizes)
+	}
+
+	namespaces, err := s.Namespaces(ctx)
+	if err != nil {
+		t.Fatalf("Namespaces() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(namespaces, []string{"apparel", DefaultNamespace}) {
+		t.Errorf("Namespaces() = %v, want [apparel default]", namespaces)
+	}
+
+	// A namespace keeps its keys under its own prefix
+	if !mr.Exists("namespaces:apparel:pack_sizes") || mr.Exists("pack_sizes") {
+		t.Errorf("keys = %v, want the apparel pack set under its namespace prefix only", mr.Keys())
+	}
+
+	for _, namespace := range []string{"", "Apparel", "a:b", "-apparel", strings.Repeat("a", 65), SKUNamespace("Apparel", "SKU-1")} {
+		if _, err := s.Namespace(namespace); !errors.Is(err, ErrInvalidNamespace) {
+			t.Errorf("Namespace(%q) error = %v, want %v", namespace, err, ErrInvalidNamespace)
+		}
+	}
+}
+
+func TestRedisPackSizeStoreSKU(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newTestRedis(t)
+	s := NewRedisPackSizeStore(rdb)
+
+	for _, name := range []string{SKUNamespace("apparel", "SKU-123"), SKUNamespace("apparel", "widget.blue")} {
+		repo, err := s.Namespace(name)
+		if err != nil {
+			t.Fatalf("Namespace(%s) error = %v", name, err)
+		}
+
+		if _, _, err := repo.Update(ctx, "alice", func([]int) ([]int, error) {
+			return []int{12, 6}, nil
+		}); err != nil {
+			t.Fatalf("Update() in %s error = %v", name, err)
+		}
+	}
+
+	// A SKU's pack set is kept apart from the pack set of its namespace
+	apparel, err := s.Namespace("apparel")
+	if err != nil {
+		t.Fatalf("Namespace(apparel) error = %v", err)
+	}
+
+	current, err := apparel.Current(ctx)
+	if err != nil || len(current.Sizes) != 0 {
+		t.Errorf("Current() of apparel = %v, error = %v, want no sizes", current.Sizes, err)
+	}
+
+	if !mr.Exists("namespaces:apparel:skus:SKU-123:pack_sizes") {
+		t.Errorf("keys = %v, want the SKU-123 pack set under the apparel prefix", mr.Keys())
+	}
+
+	namespaces, err := s.Namespaces(ctx)
+	if err != nil {
+		t.Fatalf("Namespaces() error = %v", err)
+	}
+
+	expected := []string{"apparel/SKU-123", "apparel/widget.blue", DefaultNamespace}
+	if !reflect.DeepEqual(namespaces, expected) {
+		t.Errorf("Namespaces() = %v, want %v", namespaces, expected)
+	}
+
+	for _, sku := range []string{"", "-sku", "a:b", "a/b", "a b", strings.Repeat("a", 129)} {
+		if _, err := s.Namespace(SKUNamespace("apparel", sku)); !errors.Is(err, ErrInvalidSKU) {
+			t.Errorf("Namespace(%q) error = %v, want %v", SKUNamespace("apparel", sku), err, ErrInvalidSKU)
+		}
+	}
+}
+
+func TestRedisPackSizeRepositoryStock(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+
+	if err := r.SetStock(ctx, map[int]int{500: 4, 250: 0}); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	if err := r.SetStock(ctx, map[int]int{500: 2}); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	if err := r.ClearStock(ctx, 250); err != nil {
+		t.Fatalf("ClearStock() error = %v", err)
+	}
+
+	stock, err := r.Stock(ctx)
+	if err != nil {
+		t.Fatalf("Stock() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(stock, map[int]int{500: 2}) {
+		t.Errorf("Stock() = %v, want {500: 2}", stock)
+	}
+}
+
+func TestRedisPackSizeRepositoryCustomers(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+
+	profiles := []model.CustomerProfile{
+		{ID: "dock-b", ExcludedSizes: []int{500}},
+		{ID: "dock-a", AllowedSizes: []int{250}},
+	}
+
+	for _, profile := range profiles {
+		if err := r.SetCustomer(ctx, profile); err != nil {
+			t.Fatalf("SetCustomer() error = %v", err)
+		}
+	}
+
+	got, err := r.Customers(ctx)
+	if err != nil {
+		t.Fatalf("Customers() error = %v", err)
+	}
+
+	if len(got) != 2 || got[0].ID != "dock-a" || !slices.Equal(got[0].AllowedSizes, []int{250}) || got[1].ID != "dock-b" {
+		t.Errorf("Customers() = %+v, want dock-a allowing 250, then dock-b", got)
+	}
+
+	if err := r.DeleteCustomer(ctx, "dock-a"); err != nil {
+		t.Fatalf("DeleteCustomer() error = %v", err)
+	}
+
+	if _, err := r.Customer(ctx, "dock-a"); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("Customer() error = %v, want %v", err, ErrCustomerNotFound)
+	}
+
+	if err := r.DeleteCustomer(ctx, "dock-a"); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("DeleteCustomer() error = %v, want %v", err, ErrCustomerNotFound)
+	}
+}
+
+func TestRedisPackSizeRepositoryReservations(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+
+	if err := r.SetStock(ctx, map[int]int{500: 2}); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	if _, err := r.Reserve(ctx, "alice", map[int]int{500: 3}, time.Minute); !errors.Is(err, ErrStockExhausted) {
+		t.Fatalf("Reserve() beyond the stock error = %v, want %v", err, ErrStockExhausted)
+	}
+
+	held, err := r.Reserve(ctx, "alice", map[int]int{500: 2, 250: 1}, time.Minute)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+
+	// Unlimited sizes are not held
+	if held.Status != model.ReservationStatusHeld || !reflect.DeepEqual(held.Packs, map[int]int{500: 2}) {
+		t.Errorf("Reserve() = %+v, want {500: 2} held", held)
+	}
+
+	expiring, err := r.Reserve(ctx, "bob", map[int]int{250: 4}, 0)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+
+	if _, err := r.ConfirmReservation(ctx, expiring.ID); !errors.Is(err, ErrReservationExpired) {
+		t.Errorf("ConfirmReservation() of an expired reservation error = %v, want %v", err, ErrReservationExpired)
+	}
+
+	expired, err := r.ExpireReservations(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("ExpireReservations() error = %v", err)
+	}
+
+	if len(expired) != 1 || expired[0].ID != expiring.ID || expired[0].Status != model.ReservationStatusExpired {
+		t.Errorf("ExpireReservations() = %+v, want reservation %s expired", expired, expiring.ID)
+	}
+
+	released, err := r.ReleaseReservation(ctx, held.ID)
+	if err != nil {
+		t.Fatalf("ReleaseReservation() error = %v", err)
+	}
+
+	if released.Status != model.ReservationStatusReleased {
+		t.Errorf("ReleaseReservation() status = %v, want %v", released.Status, model.ReservationStatusReleased)
+	}
+
+	if _, err := r.ReleaseReservation(ctx, held.ID); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("ReleaseReservation() again error = %v, want %v", err, ErrReservationNotFound)
+	}
+
+	stock, err := r.Stock(ctx)
+	if err != nil {
+		t.Fatalf("Stock() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(stock, map[int]int{500: 2}) {
+		t.Errorf("Stock() after releasing = %v, want {500: 2}", stock)
+	}
+}
+
+func TestRedisPackSizeRepositoryConcurrentReserve(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+
+	if err := r.SetStock(ctx, map[int]int{500: 5}); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+
+	// More reservations than packs in stock race for the last packs
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := r.Reserve(ctx, "alice", map[int]int{500: 1}, time.Minute)
+			switch {
+			case err == nil:
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			case !errors.Is(err, ErrStockExhausted) && !errors.Is(err, ErrConcurrentUpdate):
+				t.Errorf("Reserve() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stock, err := r.Stock(ctx)
+	if err != nil {
+		t.Fatalf("Stock() error = %v", err)
+	}
+
+	// Every pack is either held once or still in stock
+	if reserved > 5 || stock[500] != 5-reserved {
+		t.Errorf("%d reservations left %d packs in stock, want %d", reserved, stock[500], 5-reserved)
+	}
+}
+
+func TestRedisPackSizeRepositoryConcurrentSettle(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRedisRepository(t, 500, 250)
+
+	if err := r.SetStock(ctx, map[int]int{500: 3}); err != nil {
+		t.Fatalf("SetStock() error = %v", err)
+	}
+
+	held, err := r.Reserve(ctx, "alice", map[int]int{500: 3}, time.Minute)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		released int
+		settled  int
+	)
+
+	// Releases and confirmations race for the same reservation
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var err error
+			if i%2 == 0 {
+				_, err = r.ReleaseReservation(ctx, held.ID)
+			} else {
+				_, err = r.ConfirmReservation(ctx, held.ID)
+			}
+
+			switch {
+			case err == nil:
+				mu.Lock()
+				settled++
+				if i%2 == 0 {
+					released++
+				}
+				mu.Unlock()
+			case !errors.Is(err, ErrReservationNotFound) && !errors.Is(err, ErrConcurrentUpdate):
+				t.Errorf("settling the reservation error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if settled != 1 {
+		t.Fatalf("reservation settled %d times, want once", settled)
+	}
+
+	stock, err := r.Stock(ctx)
+	if err != nil {
+		t.Fatalf("Stock() error = %v", err)
+	}
+
+	// Only a release returns the packs, and only once
+	if stock[500] != 3*released {
+		t.Errorf("Stock() after settling = %v, want %d packs of 500", stock, 3*released)
+	}
+}
@@ -0,0 +1,287 @@
+// Package repository provides storage for pack sizes
+package repository
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// DefaultNamespace is the namespace of requests that do not name one
+const DefaultNamespace = "default"
+
+// namespacePattern restricts namespaces to identifiers that are safe inside storage keys
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// skuPattern restricts SKUs to identifiers that are safe inside storage keys and paths,
+// allowing the upper case letters and dots SKUs are often written with
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// skuSeparator separates the namespace from the SKU in the scoped name of a SKU's pack set
+const skuSeparator = "/"
+
+// maxTxRetries caps how often an optimistic transaction is retried when the data it read changes
+const maxTxRetries = 5
+
+var (
+	// ErrConcurrentUpdate is returned when pack sizes keep changing while a transaction tries to update them
+	ErrConcurrentUpdate = errors.New("pack sizes were changed concurrently")
+	// ErrVersionNotFound is returned when a pack set version does not exist
+	ErrVersionNotFound = errors.New("pack set version not found")
+	// ErrScheduleNotFound is returned when a pack set schedule does not exist
+	ErrScheduleNotFound = errors.New("pack set schedule not found")
+	// ErrScheduleNotPending is returned when a pack set schedule was already activated or superseded
+	ErrScheduleNotPending = errors.New("pack set schedule is no longer pending")
+	// ErrInvalidNamespace is returned when a namespace is not a lowercase identifier of up to 64 characters
+	ErrInvalidNamespace = errors.New("invalid namespace")
+	// ErrInvalidSKU is returned when a SKU is not an identifier of up to 128 letters, digits, '.', '-' or '_'
+	ErrInvalidSKU = errors.New("invalid sku")
+	// ErrReservationNotFound is returned when a pack reservation does not exist or was already settled
+	ErrReservationNotFound = errors.New("pack reservation not found")
+	// ErrReservationExpired is returned when a pack reservation is confirmed after it expired
+	ErrReservationExpired = errors.New("pack reservation expired")
+	// ErrCustomerNotFound is returned when a customer profile does not exist
+	ErrCustomerNotFound = errors.New("customer profile not found")
+	// ErrStockExhausted is returned when a reservation needs more packs of a size than are in stock
+	ErrStockExhausted = errors.New("not enough packs in stock")
+)
+
+// UpdateFunc computes a new pack set from the current one, which it may modify.
+// Returning an error aborts the update.
+type UpdateFunc func(current []int) ([]int, error)
+
+// PackSizeRepository stores the set of available pack sizes and its version history
+type PackSizeRepository interface {
+	// Current returns the live pack set in descending order (largest to smallest) along
+	// with the version it was recorded as. Version is zero while no change has been recorded.
+	Current(ctx context.Context) (model.PackSetVersion, error)
+	// Update atomically applies fn to the current set, stores the result and records it
+	// as a new version by actor. It returns the previous set and the new version; when fn
+	// leaves the set unchanged nothing is recorded and the current version is returned.
+	Update(ctx context.Context, actor string, fn UpdateFunc) ([]int, model.PackSetVersion, error)
+	// Versions returns every recorded version, oldest first
+	Versions(ctx context.Context) ([]model.PackSetVersion, error)
+	// Version returns a single recorded version or ErrVersionNotFound
+	Version(ctx context.Context, version int) (model.PackSetVersion, error)
+	// Schedule stages sizes by actor to become the live set at effectiveFrom
+	Schedule(ctx context.Context, actor string, sizes []int, effectiveFrom time.Time) (model.PackSetSchedule, error)
+	// Schedules returns every schedule ordered by effective time
+	Schedules(ctx context.Context) ([]model.PackSetSchedule, error)
+	// CancelSchedule deletes a pending schedule
+	CancelSchedule(ctx context.Context, id int) error
+	// Stock returns the packs in stock by size. Sizes without a stock level are unlimited.
+	Stock(ctx context.Context) (map[int]int, error)
+	// SetStock sets the stock levels of the given sizes
+	SetStock(ctx context.Context, levels map[int]int) error
+	// ClearStock drops the stock level of a size, making it unlimited again
+	ClearStock(ctx context.Context, size int) error
+	// Costs returns what every pack size and every surplus item costs
+	Costs(ctx context.Context) (model.PackCosts, error)
+	// SetCosts replaces the pack costs
+	SetCosts(ctx context.Context, costs model.PackCosts) error
+	// Specs returns the weight and dimensions of every pack size
+	Specs(ctx context.Context) ([]model.PackSpec, error)
+	// SetSpecs replaces the pack specs
+	SetSpecs(ctx context.Context, specs []model.PackSpec) error
+	// Constraints returns the fewest and most packs of each size a combination may hold
+	Constraints(ctx context.Context) (model.PackConstraints, error)
+	// SetConstraints replaces the pack count constraints
+	SetConstraints(ctx context.Context, constraints model.PackConstraints) error
+	// Customers returns every customer profile ordered by id
+	Customers(ctx context.Context) ([]model.CustomerProfile, error)
+	// Customer returns a single customer profile or ErrCustomerNotFound
+	Customer(ctx context.Context, id string) (model.CustomerProfile, error)
+	// SetCustomer stores a customer profile, replacing the one with the same id
+	SetCustomer(ctx context.Context, profile model.CustomerProfile) error
+	// DeleteCustomer removes a customer profile or returns ErrCustomerNotFound
+	DeleteCustomer(ctx context.Context, id string) error
+	// Packaging returns the cartons and pallet packs ship in
+	Packaging(ctx context.Context) (model.Packaging, error)
+	// SetPackaging replaces the packaging hierarchy
+	SetPackaging(ctx context.Context, packaging model.Packaging) error
+	// Reserve atomically takes packs out of stock and holds them under a new reservation
+	// by actor that expires after ttl. Sizes without a stock level are not held. When a
+	// size has too few packs in stock nothing is taken and ErrStockExhausted is returned.
+	Reserve(ctx context.Context, actor string, packs map[int]int, ttl time.Duration) (model.PackReservation, error)
+	// ConfirmReservation settles a held reservation, keeping its packs out of stock.
+	// An expired reservation cannot be confirmed and returns ErrReservationExpired.
+	ConfirmReservation(ctx context.Context, id string) (model.PackReservation, error)
+	// ReleaseReservation settles a held reservation, returning its packs to stock
+	ReleaseReservation(ctx context.Context, id string) (model.PackReservation, error)
+	// ExpireReservations settles every reservation expired at now, returning its packs
+	// to stock, and returns the reservations it settled
+	ExpireReservations(ctx context.Context, now time.Time) ([]model.PackReservation, error)
+	// ActivateSchedule atomically swaps a pending schedule in as the live set and records it
+	// as a new version. A schedule overridden by a change recorded after its effective time
+	// is marked superseded instead. It returns the schedule in its final state.
+	ActivateSchedule(ctx context.Context, id int) (model.PackSetSchedule, error)
+}
+
+// PackSizeStore hands out the pack size repository of every namespace, so each tenant
+// or product line keeps its own pack set, history and schedules
+type PackSizeStore interface {
+	// Namespace returns the repository of a namespace, or of a SKU within a namespace
+	// when given the scoped name SKUNamespace returns, which need not hold anything yet
+	Namespace(namespace string) (PackSizeRepository, error)
+	// Namespaces returns every namespace holding a pack set or a schedule, the default one
+	// included, and the scoped name of every SKU pack set
+	Namespaces(ctx context.Context) ([]string, error)
+}
+
+// ValidateNamespace checks that a namespace can be used to key a pack set
+func ValidateNamespace(namespace string) error {
+	if !namespacePattern.MatchString(namespace) {
+		return ErrInvalidNamespace
+	}
+
+	return nil
+}
+
+// ValidateSKU checks that a SKU can be used to key a pack set within a namespace
+func ValidateSKU(sku string) error {
+	if !skuPattern.MatchString(sku) {
+		return ErrInvalidSKU
+	}
+
+	return nil
+}
+
+// SKUNamespace returns the scoped name of the pack set of a SKU within a namespace
+func SKUNamespace(namespace, sku string) string {
+	return namespace + skuSeparator + sku
+}
+
+// parseNamespace validates a namespace or the scoped name of a SKU pack set and splits
+// it into the namespace and the SKU, which is empty for a plain namespace
+func parseNamespace(name string) (string, string, error) {
+	namespace, sku, scoped := strings.Cut(name, skuSeparator)
+	if err := ValidateNamespace(namespace); err != nil {
+		return "", "", err
+	}
+
+	if scoped {
+		if err := ValidateSKU(sku); err != nil {
+			return "", "", err
+		}
+	}
+
+	return namespace, sku, nil
+}
+
+// normalizePackSizes returns the distinct sizes in descending order (largest to smallest)
+func normalizePackSizes(sizes []int) []int {
+	out := slices.Clone(sizes)
+	slices.Sort(out)
+	out = slices.Compact(out)
+	slices.Reverse(out)
+
+	return out
+}
+
+// nextVersion builds the version recording next on top of current. It reports false,
+// and returns current, when next leaves the set unchanged.
+func nextVersion(current model.PackSetVersion, next []int, actor string) (model.PackSetVersion, bool) {
+	next = normalizePackSizes(next)
+	if slices.Equal(next, current.Sizes) {
+		return current, false
+	}
+
+	return model.PackSetVersion{
+		Version:   current.Version + 1,
+		Sizes:     next,
+		CreatedAt: time.Now().UTC(),
+		Actor:     actor,
+	}, true
+}
+
+// activateSchedule settles a pending schedule against the live set. It reports true along
+// with the version to record when the schedule changes the live set.
+func activateSchedule(current model.PackSetVersion, schedule *model.PackSetSchedule) (model.PackSetVersion, bool) {
+	// A change recorded after the schedule took effect wins over it
+	if current.CreatedAt.After(schedule.EffectiveFrom) {
+		schedule.Status = model.ScheduleStatusSuperseded
+		return current, false
+	}
+
+	version, changed := nextVersion(current, schedule.Sizes, schedule.Actor)
+
+	schedule.Status = model.ScheduleStatusActivated
+	schedule.ActivatedVersion = version.Version
+
+	return version, changed
+}
+
+// sortSchedules orders schedules by effective time, then by the order they were staged in
+func sortSchedules(schedules []model.PackSetSchedule) {
+	slices.SortFunc(schedules, func(a, b model.PackSetSchedule) int {
+		return cmp.Or(a.EffectiveFrom.Compare(b.EffectiveFrom), cmp.Compare(a.ID, b.ID))
+	})
+}
+
+// sortCustomers orders customer profiles by id
+func sortCustomers(profiles []model.CustomerProfile) {
+	slices.SortFunc(profiles, func(a, b model.CustomerProfile) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+// holdStock returns the packs to take out of stock for a reservation: the given packs of
+// every size with a stock level. It fails with ErrStockExhausted when a size has too few.
+func holdStock(stock, packs map[int]int) (map[int]int, error) {
+	held := trackedPacks(stock, packs)
+	for size, n := range held {
+		if n > stock[size] {
+			return nil, fmt.Errorf("%w: %d packs of %d wanted, %d left", ErrStockExhausted, n, size, stock[size])
+		}
+	}
+
+	return held, nil
+}
+
+// trackedPacks returns the packs of every size with a stock level, leaving out sizes
+// whose stock is unlimited
+func trackedPacks(stock, packs map[int]int) map[int]int {
+	tracked := make(map[int]int)
+	for size, n := range packs {
+		if _, ok := stock[size]; ok && n > 0 {
+			tracked[size] = n
+		}
+	}
+
+	return tracked
+}
+
+// newReservation builds a held reservation of packs by actor that expires after ttl
+func newReservation(namespace, actor string, packs map[int]int, ttl time.Duration) model.PackReservation {
+	now := time.Now().UTC()
+
+	return model.PackReservation{
+		ID:        rand.Text(),
+		Namespace: namespace,
+		Packs:     packs,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		Actor:     actor,
+		Status:    model.ReservationStatusHeld,
+	}
+}
+
+// settleReservation moves a held reservation to status at now. It reports true when the
+// reservation's packs return to stock.
+func settleReservation(reservation *model.PackReservation, status model.ReservationStatus, now time.Time) (bool, error) {
+	if status == model.ReservationStatusConfirmed && !now.Before(reservation.ExpiresAt) {
+		return false, ErrReservationExpired
+	}
+
+	reservation.Status = status
+
+	return status != model.ReservationStatusConfirmed, nil
+}
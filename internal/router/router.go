@@ -44,7 +44,7 @@ func New(
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"}, // or specific frontend domain
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Actor", "X-Pack-Namespace"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
@@ -56,12 +56,20 @@ func New(
 	})
 
 	v1 := r.Group("/api/v1")
+	v1.GET("/namespaces", packHandler.GetNamespaces)
+
+	// ************** Order Routes **************
+	// Orders are packed against the SKU pack sets of the X-Pack-Namespace header, or of the namespace in the path
+	v1.POST("/orders/calculate", api.ResolveNamespace(), packHandler.CalculateOrder)
+	v1.POST("/namespaces/:namespace/orders/calculate", api.ResolveNamespace(), packHandler.CalculateOrder)
+
 	// ************** Pack Routes **************
-	orderRoutes := v1.Group("/packs")
-	orderRoutes.GET("/calculate", packHandler.CalculatePack)
-	orderRoutes.GET("/sizes", packHandler.GetPackSizes)
-	orderRoutes.POST("/sizes", packHandler.AddPackSize)
-	orderRoutes.DELETE("/sizes", packHandler.RemovePackSize)
+	// Pack routes are scoped to the X-Pack-Namespace header, or to the namespace in the path,
+	// and to the pack set of a SKU within it under /skus/:sku
+	registerPackRoutes(v1.Group("/packs", api.ResolveNamespace()), packHandler)
+	registerPackRoutes(v1.Group("/namespaces/:namespace/packs", api.ResolveNamespace()), packHandler)
+	registerPackRoutes(v1.Group("/skus/:sku/packs", api.ResolveNamespace(), api.ResolveSKU()), packHandler)
+	registerPackRoutes(v1.Group("/namespaces/:namespace/skus/:sku/packs", api.ResolveNamespace(), api.ResolveSKU()), packHandler)
 
 	// ************** swagger Route **************
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -69,6 +77,39 @@ func New(
 	return r
 }
 
+// registerPackRoutes registers the pack routes on a group
+func registerPackRoutes(packRoutes *gin.RouterGroup, packHandler *api.PackHandler) {
+	packRoutes.GET("/calculate", packHandler.CalculatePack)
+	packRoutes.POST("/calculate/batch", packHandler.CalculatePackBatch)
+	packRoutes.GET("/sizes", packHandler.GetPackSizes)
+	packRoutes.POST("/sizes", packHandler.AddPackSize)
+	packRoutes.DELETE("/sizes", packHandler.RemovePackSize)
+	packRoutes.PUT("/sizes", packHandler.ReplacePackSizes)
+	packRoutes.GET("/sizes/versions", packHandler.GetPackSetVersions)
+	packRoutes.GET("/sizes/versions/:version", packHandler.GetPackSetVersion)
+	packRoutes.POST("/sizes/versions/:version/rollback", packHandler.RollbackPackSizes)
+	packRoutes.GET("/sizes/schedules", packHandler.GetPackSetSchedules)
+	packRoutes.POST("/sizes/schedules", packHandler.SchedulePackSizes)
+	packRoutes.DELETE("/sizes/schedules/:id", packHandler.CancelPackSetSchedule)
+	packRoutes.GET("/sizes/stock", packHandler.GetPackStock)
+	packRoutes.PUT("/sizes/stock", packHandler.SetPackStock)
+	packRoutes.DELETE("/sizes/stock", packHandler.ClearPackStock)
+	packRoutes.GET("/sizes/costs", packHandler.GetPackCosts)
+	packRoutes.PUT("/sizes/costs", packHandler.SetPackCosts)
+	packRoutes.GET("/sizes/specs", packHandler.GetPackSpecs)
+	packRoutes.PUT("/sizes/specs", packHandler.SetPackSpecs)
+	packRoutes.GET("/sizes/constraints", packHandler.GetPackConstraints)
+	packRoutes.PUT("/sizes/constraints", packHandler.SetPackConstraints)
+	packRoutes.GET("/customers", packHandler.GetCustomers)
+	packRoutes.GET("/customers/:id", packHandler.GetCustomer)
+	packRoutes.PUT("/customers/:id", packHandler.SetCustomer)
+	packRoutes.DELETE("/customers/:id", packHandler.DeleteCustomer)
+	packRoutes.GET("/packaging", packHandler.GetPackaging)
+	packRoutes.PUT("/packaging", packHandler.SetPackaging)
+	packRoutes.POST("/reservations/:id/confirm", packHandler.ConfirmPackReservation)
+	packRoutes.POST("/reservations/:id/release", packHandler.ReleasePackReservation)
+}
+
 // globalRecover provides global panic recovery middleware
 func globalRecover() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,173 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sort"
+)
+
+// maxAlternatives caps how many alternative combinations a single request may ask for
+const maxAlternatives = 10
+
+// findAlternativeCombinations returns up to k runner-up combinations to best for the
+// given order, ranked by the selection's ranking.
+//
+// Alternatives keep the largest packs every optimal combination holds except one,
+// and vary the mix of packs covering the rest of the order. Combinations needing more
+// packs than are in stock, or overshooting more than the selection allows, are left
+// out, a nil stock is unlimited. The search is best effort: once the budget runs out
+// it returns the alternatives found so far.
+func findAlternativeCombinations(
+	ctx context.Context,
+	orderItemQty int,
+	packSizes []int,
+	stock map[int]int,
+	sel selection,
+	best OptimalPacking,
+	k int,
+	budget Budget,
+) ([]PackCombination, error) {
+	if k < 1 {
+		return nil, nil
+	}
+
+	ctx, cancel := withBudgetTimeout(ctx, budget)
+	defer cancel()
+
+	largest := packSizes[0]
+	fixed := largestPackCount(orderItemQty, packSizes)
+	if fixed > 0 {
+		fixed--
+	}
+
+	if available, ok := stock[largest]; ok {
+		fixed = min(fixed, available)
+	}
+
+	bestCombination := newPackCombination(best.Packs)
+	ranked := make([]PackCombination, 0, k+1)
+
+	err := enumerateCombinations(newTracker(ctx, budget), orderItemQty-fixed*largest, packSizes, func(c PackCombination) {
+		if fixed > 0 {
+			c.Packs[largest] += fixed
+			c.Total += fixed * largest
+			c.PackCount += fixed
+		}
+
+		if samePacks(c.Packs, bestCombination.Packs) || !withinStock(c.Packs, stock) || !sel.allows(c, orderItemQty) {
+			return
+		}
+
+		ranked = insertRanked(ranked, c, k, packSizes, sel.ranking)
+	})
+	if err != nil && !errors.Is(err, ErrComputationBudgetExceeded) {
+		return nil, err
+	}
+
+	for i := range ranked {
+		ranked[i].Overshoot = ranked[i].Total - orderItemQty
+	}
+
+	return ranked, nil
+}
+
+// enumerateCombinations visits every combination covering orderQty in which removing
+// any single pack would leave the order uncovered. Larger packs are tried first so
+// good combinations are visited early when the budget cuts the search short.
+func enumerateCombinations(t *tracker, orderQty int, packSizes []int, visit func(PackCombination)) error {
+	current := make(map[int]int)
+
+	var dfs func(index int, total int, count int) error
+	dfs = func(index int, total int, count int) error {
+		if err := t.spend(1); err != nil {
+			return err
+		}
+
+		// Base case: we have enough items
+		if total >= orderQty {
+			visit(PackCombination{Packs: copyPacks(current), Total: total, PackCount: count})
+			return nil
+		}
+
+		// Base case: no more pack sizes to try
+		if index >= len(packSizes) {
+			return nil
+		}
+
+		packSize := packSizes[index]
+
+		// More packs than it takes to cover the order only add removable packs,
+		// and the smallest size must cover whatever is left
+		maxPackCount := (orderQty - total + packSize - 1) / packSize
+		minPackCount := 0
+		if index == len(packSizes)-1 {
+			minPackCount = maxPackCount
+		}
+
+		for i := maxPackCount; i >= minPackCount; i-- {
+			if i > 0 {
+				current[packSize] = i
+			}
+
+			err := dfs(index+1, total+packSize*i, count+i)
+			delete(current, packSize)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return dfs(0, 0, 0)
+}
+
+// insertRanked inserts c into the ranked slice, keeping at most k combinations
+func insertRanked(ranked []PackCombination, c PackCombination, k int, packSizes []int, ranking Ranking) []PackCombination {
+	i := sort.Search(len(ranked), func(i int) bool {
+		return ranking.before(c, ranked[i], packSizes)
+	})
+	if i >= k {
+		return ranked
+	}
+
+	ranked = append(ranked, PackCombination{})
+	copy(ranked[i+1:], ranked[i:])
+	ranked[i] = c
+
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+
+	return ranked
+}
+
+// newPackCombination builds a PackCombination with its totals from a packs map
+func newPackCombination(packs map[int]int) PackCombination {
+	c := PackCombination{Packs: packs}
+	for packSize, count := range packs {
+		c.Total += packSize * count
+		c.PackCount += count
+	}
+
+	return c
+}
+
+// copyPacks returns a copy of packs without zero counts
+func copyPacks(packs map[int]int) map[int]int {
+	out := make(map[int]int, len(packs))
+	for packSize, count := range packs {
+		if count > 0 {
+			out[packSize] = count
+		}
+	}
+
+	return out
+}
+
+// samePacks reports whether a and b hold the same packs, ignoring zero counts
+func samePacks(a, b map[int]int) bool {
+	return maps.Equal(copyPacks(a), copyPacks(b))
+}
@@ -0,0 +1,100 @@
+package pack
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFindAlternativeCombinations(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name                 string
+		orderItemQty         int
+		k                    int
+		expectedAlternatives []PackCombination
+		description          string
+	}{
+		{
+			name:         "Same total with a different mix - 12500",
+			orderItemQty: 12500,
+			k:            2,
+			expectedAlternatives: []PackCombination{
+				{Packs: map[int]int{5000: 2, 2000: 1, 250: 2}, Total: 12500, PackCount: 5},
+				{Packs: map[int]int{5000: 2, 1000: 2, 500: 1}, Total: 12500, PackCount: 5},
+			},
+			description: "Should rank combinations with the same total by pack count, then by larger packs",
+		},
+		{
+			name:         "Overshoot alternatives - 251",
+			orderItemQty: 251,
+			k:            3,
+			expectedAlternatives: []PackCombination{
+				{Packs: map[int]int{250: 2}, Total: 500, PackCount: 2, Overshoot: 249},
+				{Packs: map[int]int{1000: 1}, Total: 1000, PackCount: 1, Overshoot: 749},
+				{Packs: map[int]int{2000: 1}, Total: 2000, PackCount: 1, Overshoot: 1749},
+			},
+			description: "Should report the overshoot of every alternative",
+		},
+		{
+			name:                 "No alternatives requested",
+			orderItemQty:         12500,
+			k:                    0,
+			expectedAlternatives: nil,
+			description:          "Should not search when no alternatives are requested",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best, err := calculatePacks(context.Background(), tt.orderItemQty, packSizes, Budget{})
+			if err != nil {
+				t.Fatalf("calculatePacks() error = %v", err)
+			}
+
+			alternatives, err := findAlternativeCombinations(context.Background(), tt.orderItemQty, packSizes, nil, defaultSelection, best, tt.k, Budget{})
+			if err != nil {
+				t.Fatalf("findAlternativeCombinations() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(alternatives, tt.expectedAlternatives) {
+				t.Errorf("findAlternativeCombinations() = %v, want %v", alternatives, tt.expectedAlternatives)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestFindAlternativeCombinationsLargeOrder(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	best, err := calculatePacks(context.Background(), 1324001, packSizes, Budget{})
+	if err != nil {
+		t.Fatalf("calculatePacks() error = %v", err)
+	}
+
+	alternatives, err := findAlternativeCombinations(context.Background(), 1324001, packSizes, nil, defaultSelection, best, 5, Budget{MaxNodes: 100000})
+	if err != nil {
+		t.Fatalf("findAlternativeCombinations() error = %v", err)
+	}
+
+	if len(alternatives) != 5 {
+		t.Fatalf("findAlternativeCombinations() returned %d alternatives, want 5", len(alternatives))
+	}
+
+	for i, alternative := range alternatives {
+		if alternative.Total < 1324001 {
+			t.Errorf("alternative %d total %d is less than order quantity", i, alternative.Total)
+		}
+
+		if samePacks(alternative.Packs, best.Packs) {
+			t.Errorf("alternative %d repeats the optimal combination %v", i, best.Packs)
+		}
+
+		if i > 0 && defaultRanking.before(alternative, alternatives[i-1], packSizes) {
+			t.Errorf("alternative %d ranks before alternative %d", i, i-1)
+		}
+	}
+}
@@ -1,32 +1,175 @@
 package pack
 
-import "math"
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ctxCheckInterval is how many solver nodes are explored between context checks
+const ctxCheckInterval = 4096
+
+// Budget limits the work a single calculation may do
+type Budget struct {
+	// MaxNodes caps the solver states explored, zero means unlimited
+	MaxNodes int
+	// Timeout caps the wall-clock time of a calculation, zero means unlimited
+	Timeout time.Duration
+	// pool, when set, holds the nodes left to every calculation sharing the budget
+	pool *nodePool
+}
+
+// nodePool counts the nodes left to calculations sharing a budget, run one at a time
+type nodePool struct {
+	left int
+}
+
+// shared returns a budget whose nodes are shared by every calculation run with it,
+// leaving the timeout to the caller's context
+func (b Budget) shared() Budget {
+	if b.MaxNodes <= 0 {
+		return Budget{}
+	}
+
+	return Budget{MaxNodes: b.MaxNodes, pool: &nodePool{left: b.MaxNodes}}
+}
+
+// withBudgetTimeout derives a context that ends when the budget's timeout passes
+func withBudgetTimeout(ctx context.Context, budget Budget) (context.Context, context.CancelFunc) {
+	if budget.Timeout > 0 {
+		return context.WithTimeout(ctx, budget.Timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// tracker counts the solver nodes explored and stops the search once the
+// budget runs out or the context is done
+type tracker struct {
+	ctx       context.Context
+	maxNodes  int
+	pool      *nodePool
+	nodes     int
+	nextCheck int
+}
+
+// newTracker creates a tracker for the given context and budget
+func newTracker(ctx context.Context, budget Budget) *tracker {
+	return &tracker{
+		ctx:       ctx,
+		maxNodes:  budget.MaxNodes,
+		pool:      budget.pool,
+		nextCheck: ctxCheckInterval,
+	}
+}
+
+// fits reports an error when exploring n more nodes would exceed the budget
+func (t *tracker) fits(n int) error {
+	if t.maxNodes > 0 && t.nodes+n > t.maxNodes {
+		return fmt.Errorf("%w: needs %d nodes, budget is %d", ErrComputationBudgetExceeded, t.nodes+n, t.maxNodes)
+	}
+
+	if t.pool != nil && n > t.pool.left {
+		return fmt.Errorf("%w: needs %d more nodes, %d are left of the shared budget", ErrComputationBudgetExceeded, n, t.pool.left)
+	}
+
+	return nil
+}
+
+// spend records n explored nodes and reports an error once the budget runs out
+func (t *tracker) spend(n int) error {
+	if err := t.fits(n); err != nil {
+		return err
+	}
+
+	t.nodes += n
+	if t.pool != nil {
+		t.pool.left -= n
+	}
+
+	if t.nodes >= t.nextCheck {
+		t.nextCheck = t.nodes + ctxCheckInterval
+		if err := t.ctx.Err(); err != nil {
+			return fmt.Errorf("%w: %w", ErrComputationBudgetExceeded, err)
+		}
+	}
+
+	return nil
+}
+
+// Branch identifies the path in calculatePacks that produced a packing
+type Branch string
+
+const (
+	// BranchExactMatch is taken when the order matches a pack size
+	BranchExactMatch Branch = "exact_match"
+	// BranchBelowSmallestPack is taken when the order is smaller than the smallest pack
+	BranchBelowSmallestPack Branch = "below_smallest_pack"
+	// BranchLargestPacksOnly is taken when the largest packs alone cover the order
+	BranchLargestPacksOnly Branch = "largest_packs_only"
+	// BranchOptimisedRemainder is taken when the remainder after the largest packs is optimised
+	BranchOptimisedRemainder Branch = "optimised_remainder"
+	// BranchStockLimited is taken when the optimal packing needs more packs than are in stock
+	BranchStockLimited Branch = "stock_limited"
+	// BranchLowestCost is taken when the cheapest combination is calculated instead of the one with the fewest items
+	BranchLowestCost Branch = "lowest_cost"
+	// BranchRanked is taken when combinations are ranked by a custom ranking
+	BranchRanked Branch = "ranked"
+	// BranchShortfall is taken when shipping short of the order is closer to it than covering it
+	BranchShortfall Branch = "shortfall"
+	// BranchShipmentLimited is taken when the optimal packing is too heavy or takes too many parcels
+	BranchShipmentLimited Branch = "shipment_limited"
+	// BranchConstrained is taken when pack count constraints bound the sizes a combination holds
+	BranchConstrained Branch = "constrained"
+)
+
+// Trace records how calculatePacks produced a packing
+type Trace struct {
+	Branch        Branch `json:"branch"`
+	LargestPacks  int    `json:"largestPacks"`
+	Remainder     int    `json:"remainder"`
+	NodesExplored int    `json:"nodesExplored"`
+	// Lookup is set when the remainder was read from the pack set's lookup table
+	Lookup bool `json:"lookup,omitempty"`
+}
 
 // OptimalPacking represents the optimal pack combination for a given order
 type OptimalPacking struct {
-	Packs map[int]int
+	Packs     map[int]int
+	Total     int
+	PackCount int
+	Trace     Trace
+}
+
+// newOptimalPacking builds an OptimalPacking with its totals from a packs map
+func newOptimalPacking(packs map[int]int, trace Trace) OptimalPacking {
+	c := newPackCombination(packs)
+	return OptimalPacking{Packs: c.Packs, Total: c.Total, PackCount: c.PackCount, Trace: trace}
 }
 
 // PackCombination represents a pack combination with metadata
 type PackCombination struct {
-	Packs     map[int]int
-	Total     int
-	PackCount int
+	Packs     map[int]int `json:"packs"`
+	Total     int         `json:"total"`
+	PackCount int         `json:"packCount"`
+	Overshoot int         `json:"overshoot"`
 }
 
-// calculatePacks finds the optimal pack combination for a given order quantity
-func calculatePacks(orderItemQty int, packSizes []int) OptimalPacking {
-	setPacks := make(map[int]struct{})
+// calculatePacks finds the optimal pack combination for a given order quantity.
+// It stops with ErrComputationBudgetExceeded once the budget runs out or ctx is done.
+func calculatePacks(ctx context.Context, orderItemQty int, packSizes []int, budget Budget) (OptimalPacking, error) {
+	if len(packSizes) == 0 {
+		return OptimalPacking{}, ErrNoPackSizesConfigured
+	}
+
 	packs := make(map[int]int)
 
 	// Check if exact pack size exists for the order
 	for _, v := range packSizes {
 		if orderItemQty == v {
 			packs[v] = 1
-			return OptimalPacking{Packs: packs}
+			return newOptimalPacking(packs, Trace{Branch: BranchExactMatch}), nil
 		}
-
-		setPacks[v] = struct{}{}
 	}
 
 	maxValue := packSizes[0]                // Largest pack size
@@ -35,89 +178,316 @@ func calculatePacks(orderItemQty int, packSizes []int) OptimalPacking {
 	// Case 1: Order is smaller than smallest pack - use smallest pack
 	if orderItemQty < minValue {
 		packs[minValue] = 1
-		return OptimalPacking{Packs: packs}
+		return newOptimalPacking(packs, Trace{Branch: BranchBelowSmallestPack}), nil
 	}
 
-	// Case 2: Order is smaller than largest pack - find optimal combination
-	if orderItemQty < maxValue {
-		best := findBestPackCombination(orderItemQty, packSizes)
-		return OptimalPacking{Packs: best.Packs}
-	}
+	// Case 2: Order is larger than what the smaller packs can optimally hold.
+	// Take the largest packs every optimal combination holds and optimise what is left.
+	count := largestPackCount(orderItemQty, packSizes)
 
-	// Case 3: Order is larger than largest pack
-	// Use as many largest packs as possible, then handle remainder
-	count := orderItemQty / maxValue
-	reminder := orderItemQty % maxValue
 	packs[maxValue] = count
+	reminder := orderItemQty - count*maxValue
 
-	// no remainder
-	if reminder == 0 {
-		return OptimalPacking{Packs: packs}
+	// The largest packs alone cover the order
+	if reminder <= 0 {
+		return newOptimalPacking(packs, Trace{Branch: BranchLargestPacksOnly, LargestPacks: count}), nil
 	}
 
-	// Remainder matches an existing pack size
-	if _, ok := setPacks[reminder]; ok {
-		packs[reminder] = 1
-		return OptimalPacking{Packs: packs}
+	if count == 0 {
+		delete(packs, maxValue)
 	}
 
-	// Remainder is smaller than smallest pack - use smallest pack
-	if reminder < minValue {
-		packs[minValue] = 1
-		return OptimalPacking{Packs: packs}
+	// Case 3: Find optimal combination for remainder
+	ctx, cancel := withBudgetTimeout(ctx, budget)
+	defer cancel()
+
+	t := newTracker(ctx, budget)
+
+	best, err := findBestPackCombination(t, reminder, packSizes)
+	if err != nil {
+		return OptimalPacking{}, err
 	}
 
-	// Find optimal combination for remainder
-	best := findBestPackCombination(reminder, packSizes)
 	for k, v := range best.Packs {
 		packs[k] += v
 	}
 
-	return OptimalPacking{Packs: packs}
+	return newOptimalPacking(packs, Trace{
+		Branch:        BranchOptimisedRemainder,
+		LargestPacks:  count,
+		Remainder:     reminder,
+		NodesExplored: t.nodes,
+	}), nil
 }
 
-// findBestPackCombination uses DFS algorithm to find the optimal pack combination
-func findBestPackCombination(orderQty int, packSizes []int) PackCombination {
-	// packSizes should be sorted in descending order for efficiency
-	best := PackCombination{Total: math.MaxInt64}
+// largestPackCount returns how many largest packs every optimal combination for
+// the order holds. An optimal combination never keeps more than exchangeBound
+// items outside the largest pack, so the rest of the order needs largest packs.
+func largestPackCount(orderItemQty int, packSizes []int) int {
+	bound := exchangeBound(packSizes)
+	if orderItemQty <= bound {
+		return 0
+	}
 
-	var dfs func(index int, current map[int]int, total int, count int)
+	return (orderItemQty - bound + packSizes[0] - 1) / packSizes[0]
+}
 
-	dfs = func(index int, current map[int]int, total int, count int) {
-		// Base case: we have enough items
-		if total >= orderQty {
-			// Update best if this combination is better (fewer total items or same total but fewer packs)
-			if total < best.Total || (total == best.Total && count < best.PackCount) {
-				newMap := make(map[int]int)
-				for k, v := range current {
-					newMap[k] = v
-				}
+// exchangeBound returns the most items an optimal combination can hold in packs
+// other than the largest one.
+//
+// For a smaller size s, largest/gcd(s, largest) packs of s hold exactly as many
+// items as s/gcd(s, largest) largest packs, so swapping them keeps the total and
+// lowers the pack count. An optimal combination therefore holds fewer than
+// largest/gcd(s, largest) packs of every smaller size.
+func exchangeBound(packSizes []int) int {
+	largest := packSizes[0]
+	bound := 0
+
+	for _, size := range packSizes[1:] {
+		bound += size * (largest/gcd(size, largest) - 1)
+	}
+
+	return bound
+}
+
+// findBestPackCombination uses dynamic programming to find the optimal pack combination.
+// It returns the combination with the fewest items that covers orderQty and, among
+// those, the one with the fewest packs. Every item total it evaluates costs one
+// node per pack size against the tracker's budget.
+func findBestPackCombination(t *tracker, orderQty int, packSizes []int) (PackCombination, error) {
+	// packSizes should be sorted in descending order so ties keep the larger packs
+	smallest := packSizes[len(packSizes)-1]
+
+	// Rounding the order up to a multiple of the smallest pack is always possible,
+	// so the best total is below orderQty+smallest.
+	limit := orderQty + smallest
+
+	// Fail before allocating tables the budget could never fill
+	if err := t.fits(limit * len(packSizes)); err != nil {
+		return PackCombination{}, err
+	}
+
+	// counts[t] is the fewest packs holding exactly t items (-1 when impossible),
+	// last[t] is the pack size added last to reach t.
+	counts := make([]int, limit)
+	last := make([]int, limit)
+
+	for total := 1; total < limit; total++ {
+		if err := t.spend(len(packSizes)); err != nil {
+			return PackCombination{}, err
+		}
+
+		counts[total] = -1
 
-				best = PackCombination{Packs: newMap, Total: total, PackCount: count}
+		for _, packSize := range packSizes {
+			if packSize > total || counts[total-packSize] < 0 {
+				continue
+			}
+
+			if counts[total] < 0 || counts[total-packSize]+1 < counts[total] {
+				counts[total] = counts[total-packSize] + 1
+				last[total] = packSize
 			}
-			return
 		}
 
-		// Base case: no more pack sizes to try
-		if index >= len(packSizes) {
-			return
+		// The first reachable total covering the order is the best one
+		if total >= orderQty && counts[total] >= 0 {
+			packs := make(map[int]int)
+			for rest := total; rest > 0; rest -= last[rest] {
+				packs[last[rest]]++
+			}
+
+			return PackCombination{Packs: packs, Total: total, PackCount: counts[total]}, nil
+		}
+	}
+
+	return PackCombination{}, nil
+}
+
+// InsufficientStockError is returned when no combination of the packs in stock covers an order
+type InsufficientStockError struct {
+	OrderItemQuantity int
+	// Capacity is the most items the packs in stock hold
+	Capacity int
+}
+
+// Error implements the error interface
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("%s: order of %d items, packs in stock hold %d", ErrInsufficientStock, e.OrderItemQuantity, e.Capacity)
+}
+
+// Unwrap lets errors.Is match ErrInsufficientStock
+func (e *InsufficientStockError) Unwrap() error {
+	return ErrInsufficientStock
+}
+
+// calculateStockedPacks finds the optimal pack combination for a given order quantity
+// that never uses more packs of a size than are in stock. Sizes missing from stock are
+// unlimited. When the unbounded optimum needs packs that are out of stock it falls back
+// to the best combination the stock allows, and it returns an *InsufficientStockError
+// when the stock cannot cover the order at all.
+func calculateStockedPacks(ctx context.Context, orderItemQty int, packSizes []int, stock map[int]int, budget Budget) (OptimalPacking, error) {
+	packing, err := calculatePacks(ctx, orderItemQty, packSizes, budget)
+	if err != nil || withinStock(packing.Packs, stock) {
+		return packing, err
+	}
+
+	capacity, limited := stockCapacity(packSizes, stock)
+	if limited && capacity < orderItemQty {
+		return OptimalPacking{}, &InsufficientStockError{OrderItemQuantity: orderItemQty, Capacity: capacity}
+	}
+
+	ctx, cancel := withBudgetTimeout(ctx, budget)
+	defer cancel()
+
+	t := newTracker(ctx, budget)
+
+	best, err := findBestStockedPackCombination(t, orderItemQty, packSizes, stock)
+	if err != nil {
+		return OptimalPacking{}, err
+	}
+
+	return newOptimalPacking(best.Packs, Trace{
+		Branch:        BranchStockLimited,
+		Remainder:     orderItemQty,
+		NodesExplored: t.nodes,
+	}), nil
+}
+
+// findBestStockedPackCombination uses bounded dynamic programming to find the combination
+// with the fewest items that covers orderQty, and among those the fewest packs, using at
+// most the stocked packs of every size. Every item total it evaluates costs one node per
+// pack size against the tracker's budget. The order must be coverable by the stock.
+func findBestStockedPackCombination(t *tracker, orderQty int, packSizes []int, stock map[int]int) (PackCombination, error) {
+	// packSizes should be sorted in descending order so ties keep the larger packs
+	var (
+		sizes   []int
+		largest int
+	)
+
+	for _, packSize := range packSizes {
+		if available, ok := stock[packSize]; !ok || available > 0 {
+			sizes = append(sizes, packSize)
+			largest = max(largest, packSize)
+		}
+	}
+
+	// Removing a pack from a combination that still covers the order afterwards only
+	// helps, so the best total is below orderQty+largest.
+	limit := orderQty + largest
+
+	// Fail before allocating tables the budget could never fill
+	if err := t.fits(limit * len(sizes)); err != nil {
+		return PackCombination{}, err
+	}
+
+	// counts[t] is the fewest packs holding exactly t items (-1 when impossible),
+	// used[i][t] is how many packs of sizes[i] the best way to reach t holds.
+	counts := make([]int, limit)
+	for total := 1; total < limit; total++ {
+		counts[total] = -1
+	}
+
+	used := make([][]int32, len(sizes))
+
+	for i, packSize := range sizes {
+		available := limit / packSize
+		if n, ok := stock[packSize]; ok {
+			available = min(available, n)
 		}
 
-		packSize := packSizes[index]
-		// Calculate maximum packs needed for this size (with buffer for optimization)
-		maxPackCount := (orderQty-total)/packSize + 2
+		next := make([]int, limit)
+		used[i] = make([]int32, limit)
 
-		for i := 0; i <= maxPackCount; i++ {
-			if i > 0 {
-				current[packSize] = i
+		// Totals one pack size apart form a chain. Along a chain, holding k more packs
+		// of this size reaches j steps from j-k steps back, so a sliding window
+		// minimum over the last available+1 steps finds the fewest packs.
+		for residue := 0; residue < packSize && residue < limit; residue++ {
+			if err := t.spend((limit - residue + packSize - 1) / packSize); err != nil {
+				return PackCombination{}, err
 			}
-			dfs(index+1, current, total+packSize*i, count+i)
-			if i > 0 {
-				delete(current, packSize)
+
+			// window holds chain steps whose counts minus their step are increasing
+			var window []int
+
+			for j, total := 0, residue; total < limit; j, total = j+1, total+packSize {
+				if counts[total] >= 0 {
+					for len(window) > 0 && counts[residue+window[len(window)-1]*packSize]-window[len(window)-1] >= counts[total]-j {
+						window = window[:len(window)-1]
+					}
+					window = append(window, j)
+				}
+
+				for len(window) > 0 && window[0] < j-available {
+					window = window[1:]
+				}
+
+				if len(window) == 0 {
+					next[total] = -1
+					continue
+				}
+
+				from := window[0]
+				next[total] = counts[residue+from*packSize] + j - from
+				used[i][total] = int32(j - from)
+			}
+		}
+
+		counts = next
+	}
+
+	for total := orderQty; total < limit; total++ {
+		if counts[total] < 0 {
+			continue
+		}
+
+		packs := make(map[int]int)
+		for i, rest := len(sizes)-1, total; i >= 0; i-- {
+			if n := int(used[i][rest]); n > 0 {
+				packs[sizes[i]] = n
+				rest -= n * sizes[i]
 			}
 		}
+
+		return PackCombination{Packs: packs, Total: total, PackCount: counts[total]}, nil
+	}
+
+	return PackCombination{}, &InsufficientStockError{OrderItemQuantity: orderQty}
+}
+
+// stockCapacity returns the most items the stocked packs hold. It reports false when a
+// size is unlimited, so any order can be covered.
+func stockCapacity(packSizes []int, stock map[int]int) (int, bool) {
+	capacity := 0
+	for _, packSize := range packSizes {
+		available, ok := stock[packSize]
+		if !ok {
+			return 0, false
+		}
+
+		capacity += packSize * available
+	}
+
+	return capacity, true
+}
+
+// withinStock reports whether packs uses no more packs of any size than are in stock.
+// Sizes missing from stock are unlimited.
+func withinStock(packs map[int]int, stock map[int]int) bool {
+	for packSize, count := range packs {
+		if available, ok := stock[packSize]; ok && count > available {
+			return false
+		}
+	}
+
+	return true
+}
+
+// gcd returns the greatest common divisor of a and b
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
 	}
 
-	dfs(0, map[int]int{}, 0, 0)
-	return best
+	return a
 }
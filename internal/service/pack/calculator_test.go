@@ -1,7 +1,11 @@
 package pack
 
 import (
+	"context"
+	"errors"
+	"math/rand"
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -206,7 +210,10 @@ func TestCalculatePacks(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := calculatePacks(tt.orderItemQty, packSizes)
+			result, err := calculatePacks(context.Background(), tt.orderItemQty, packSizes, Budget{})
+			if err != nil {
+				t.Fatalf("calculatePacks() error = %v", err)
+			}
 
 			// Check if packs match expected
 			if !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
@@ -224,6 +231,11 @@ func TestCalculatePacks(t *testing.T) {
 				t.Errorf("calculatePacks() total = %v, want %v", total, tt.expectedTotal)
 			}
 
+			// Check if the reported total matches the packs
+			if result.Total != total {
+				t.Errorf("calculatePacks() reported total = %v, want %v", result.Total, total)
+			}
+
 			// Verify that total is >= order quantity (should never be less)
 			if total < tt.orderItemQty {
 				t.Errorf("calculatePacks() total %v is less than order quantity %v", total, tt.orderItemQty)
@@ -319,7 +331,10 @@ func TestCalculatePacksWithLargeNumbers(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := calculatePacks(tt.orderItemQty, packSizes)
+			result, err := calculatePacks(context.Background(), tt.orderItemQty, packSizes, Budget{})
+			if err != nil {
+				t.Fatalf("calculatePacks() error = %v", err)
+			}
 
 			// Check if packs match expected
 			if !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
@@ -357,3 +372,354 @@ func TestCalculatePacksWithLargeNumbers(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculatePacksWithCoprimePackSizes(t *testing.T) {
+	packSizes := []int{53, 31, 23}
+
+	tests := []struct {
+		name          string
+		orderItemQty  int
+		expectedPacks map[int]int
+		expectedTotal int
+		description   string
+	}{
+		{
+			name:         "Between pack sizes - 54",
+			orderItemQty: 54,
+			expectedPacks: map[int]int{
+				31: 1,
+				23: 1,
+			},
+			expectedTotal: 54,
+			description:   "Should not start with the largest pack when smaller packs match the order exactly",
+		},
+		{
+			name:         "Larger than largest pack - 263",
+			orderItemQty: 263,
+			expectedPacks: map[int]int{
+				31: 7,
+				23: 2,
+			},
+			expectedTotal: 263,
+			description:   "Should avoid the largest pack entirely when it cannot reach the exact total",
+		},
+		{
+			name:         "Large number - 1000",
+			orderItemQty: 1000,
+			expectedPacks: map[int]int{
+				53: 18,
+				23: 2,
+			},
+			expectedTotal: 1000,
+			description:   "Should use fewer largest packs than the greedy count to match the order exactly",
+		},
+		{
+			name:         "Very large number - 500000",
+			orderItemQty: 500000,
+			expectedPacks: map[int]int{
+				53: 9429,
+				31: 7,
+				23: 2,
+			},
+			expectedTotal: 500000,
+			description:   "Should only optimise a bounded remainder for very large numbers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calculatePacks(context.Background(), tt.orderItemQty, packSizes, Budget{})
+			if err != nil {
+				t.Fatalf("calculatePacks() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
+				t.Errorf("calculatePacks() packs = %v, want %v", result.Packs, tt.expectedPacks)
+			}
+
+			total := 0
+			for packSize, count := range result.Packs {
+				total += packSize * count
+			}
+
+			if total != tt.expectedTotal {
+				t.Errorf("calculatePacks() total = %v, want %v", total, tt.expectedTotal)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestCalculatePacksMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 3000; i++ {
+		packSizes := randomPackSizes(rng)
+		orderItemQty := 1 + rng.Intn(300)
+
+		result, err := calculatePacks(context.Background(), orderItemQty, packSizes, Budget{})
+		if err != nil {
+			t.Fatalf("calculatePacks(%d, %v) error = %v", orderItemQty, packSizes, err)
+		}
+
+		total, count := 0, 0
+		for packSize, n := range result.Packs {
+			total += packSize * n
+			count += n
+		}
+
+		wantTotal, wantCount := bruteForcePacks(orderItemQty, packSizes)
+		if total != wantTotal || count != wantCount {
+			t.Fatalf("calculatePacks(%d, %v) = %v (total %d, packs %d), brute force gives total %d, packs %d",
+				orderItemQty, packSizes, result.Packs, total, count, wantTotal, wantCount)
+		}
+	}
+}
+
+// randomPackSizes returns between one and four distinct pack sizes in descending order
+func randomPackSizes(rng *rand.Rand) []int {
+	seen := make(map[int]struct{})
+	packSizes := make([]int, 0, 4)
+
+	for n := 1 + rng.Intn(4); len(packSizes) < n; {
+		size := 5 + rng.Intn(56)
+		if _, ok := seen[size]; ok {
+			continue
+		}
+
+		seen[size] = struct{}{}
+		packSizes = append(packSizes, size)
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(packSizes)))
+
+	return packSizes
+}
+
+// bruteForcePacks tries every count of every pack size that can still matter and
+// returns the fewest items, then the fewest packs, covering the order
+func bruteForcePacks(orderItemQty int, packSizes []int) (bestTotal, bestCount int) {
+	return bruteForceStockedPacks(orderItemQty, packSizes, nil)
+}
+
+// bruteForceStockedPacks is bruteForcePacks using at most the stocked packs of every
+// size. It returns a total of -1 when the stock cannot cover the order.
+func bruteForceStockedPacks(orderItemQty int, packSizes []int, stock map[int]int) (bestTotal, bestCount int) {
+	bestTotal = -1
+
+	var try func(index, total, count int)
+	try = func(index, total, count int) {
+		if total >= orderItemQty {
+			if bestTotal < 0 || total < bestTotal || (total == bestTotal && count < bestCount) {
+				bestTotal, bestCount = total, count
+			}
+
+			return
+		}
+
+		if index == len(packSizes) {
+			return
+		}
+
+		available, limited := stock[packSizes[index]]
+		for n := 0; total+n*packSizes[index] < orderItemQty+packSizes[index] && (!limited || n <= available); n++ {
+			try(index+1, total+n*packSizes[index], count+n)
+		}
+	}
+
+	try(0, 0, 0)
+
+	return bestTotal, bestCount
+}
+
+func TestCalculatePacksBudget(t *testing.T) {
+	packSizes := []int{99991, 99989}
+
+	t.Run("Node budget exceeded", func(t *testing.T) {
+		_, err := calculatePacks(context.Background(), 1324001, packSizes, Budget{MaxNodes: 1000})
+		if !errors.Is(err, ErrComputationBudgetExceeded) {
+			t.Fatalf("calculatePacks() error = %v, want %v", err, ErrComputationBudgetExceeded)
+		}
+	})
+
+	t.Run("Context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := calculatePacks(ctx, 1324001, packSizes, Budget{})
+		if !errors.Is(err, ErrComputationBudgetExceeded) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("calculatePacks() error = %v, want %v wrapping %v", err, ErrComputationBudgetExceeded, context.Canceled)
+		}
+	})
+
+	t.Run("Shared budget exceeded", func(t *testing.T) {
+		budget := Budget{MaxNodes: 3_000_000}.shared()
+
+		// Each calculation fits the budget on its own, but not both together
+		if _, err := calculatePacks(context.Background(), 1324001, packSizes, budget); err != nil {
+			t.Fatalf("calculatePacks() error = %v", err)
+		}
+
+		_, err := calculatePacks(context.Background(), 1324001, packSizes, budget)
+		if !errors.Is(err, ErrComputationBudgetExceeded) {
+			t.Fatalf("calculatePacks() error = %v, want %v", err, ErrComputationBudgetExceeded)
+		}
+	})
+
+	t.Run("Within budget", func(t *testing.T) {
+		result, err := calculatePacks(context.Background(), 1324001, []int{5000, 2000, 1000, 500, 250}, Budget{MaxNodes: 200000})
+		if err != nil {
+			t.Fatalf("calculatePacks() error = %v", err)
+		}
+
+		if result.Packs[5000] != 264 {
+			t.Errorf("calculatePacks() packs = %v, want 264 packs of 5000", result.Packs)
+		}
+	})
+}
+
+func TestNewCalculatePackResponse(t *testing.T) {
+	packing := newOptimalPacking(map[int]int{250: 1, 5000: 24, 2000: 2}, Trace{})
+
+	result := newCalculatePackResponse(124001, packing)
+
+	expected := CalculatePackResponse{
+		OrderedQuantity: 124001,
+		ShippedQuantity: 124250,
+		Surplus:         249,
+		PackCount:       27,
+		PackList: []PackLine{
+			{Size: 5000, Count: 24},
+			{Size: 2000, Count: 2},
+			{Size: 250, Count: 1},
+		},
+		Packs: packing.Packs,
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("newCalculatePackResponse() = %+v, want %+v", result, expected)
+	}
+}
+
+func TestCalculatePacksWithoutPackSizes(t *testing.T) {
+	_, err := calculatePacks(context.Background(), 250, []int{}, Budget{})
+	if !errors.Is(err, ErrNoPackSizesConfigured) {
+		t.Fatalf("calculatePacks() error = %v, want %v", err, ErrNoPackSizesConfigured)
+	}
+}
+
+func TestCalculateStockedPacks(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name           string
+		orderItemQty   int
+		stock          map[int]int
+		expectedPacks  map[int]int
+		expectedBranch Branch
+		expectedErr    error
+		description    string
+	}{
+		{
+			name:           "Enough stock",
+			orderItemQty:   12001,
+			stock:          map[int]int{5000: 2, 2000: 1, 250: 1},
+			expectedPacks:  map[int]int{5000: 2, 2000: 1, 250: 1},
+			expectedBranch: BranchOptimisedRemainder,
+			description:    "Should keep the optimal packing when the stock allows it",
+		},
+		{
+			name:           "Largest pack out of stock",
+			orderItemQty:   12001,
+			stock:          map[int]int{5000: 0},
+			expectedPacks:  map[int]int{2000: 6, 250: 1},
+			expectedBranch: BranchStockLimited,
+			description:    "Should fall back to the best combination without the missing pack size",
+		},
+		{
+			name:           "Partially stocked",
+			orderItemQty:   12001,
+			stock:          map[int]int{5000: 1, 2000: 2, 1000: 3},
+			expectedPacks:  map[int]int{5000: 1, 2000: 2, 1000: 3, 250: 1},
+			expectedBranch: BranchStockLimited,
+			description:    "Should use up the scarce sizes and fill up with the unlimited ones",
+		},
+		{
+			name:           "Only more items in stock",
+			orderItemQty:   251,
+			stock:          map[int]int{250: 1, 500: 0},
+			expectedPacks:  map[int]int{1000: 1},
+			expectedBranch: BranchStockLimited,
+			description:    "Should ship more items when the smaller packs run out",
+		},
+		{
+			name:         "Stock cannot cover the order",
+			orderItemQty: 12001,
+			stock:        map[int]int{5000: 1, 2000: 1, 1000: 1, 500: 1, 250: 1},
+			expectedErr:  ErrInsufficientStock,
+			description:  "Should return a typed error when no combination is feasible",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calculateStockedPacks(context.Background(), tt.orderItemQty, packSizes, tt.stock, Budget{})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("calculateStockedPacks() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if tt.expectedErr != nil {
+				var stockErr *InsufficientStockError
+				if !errors.As(err, &stockErr) || stockErr.Capacity != 8750 {
+					t.Errorf("calculateStockedPacks() error = %#v, want an *InsufficientStockError with capacity 8750", err)
+				}
+
+				return
+			}
+
+			if !reflect.DeepEqual(result.Packs, tt.expectedPacks) || result.Trace.Branch != tt.expectedBranch {
+				t.Errorf("calculateStockedPacks() = %v via %s, want %v via %s",
+					result.Packs, result.Trace.Branch, tt.expectedPacks, tt.expectedBranch)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestCalculateStockedPacksMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 3000; i++ {
+		packSizes := randomPackSizes(rng)
+		orderItemQty := 1 + rng.Intn(300)
+
+		stock := make(map[int]int)
+		for _, packSize := range packSizes {
+			if rng.Intn(3) > 0 {
+				stock[packSize] = rng.Intn(6)
+			}
+		}
+
+		wantTotal, wantCount := bruteForceStockedPacks(orderItemQty, packSizes, stock)
+
+		result, err := calculateStockedPacks(context.Background(), orderItemQty, packSizes, stock, Budget{})
+		if wantTotal < 0 {
+			if !errors.Is(err, ErrInsufficientStock) {
+				t.Fatalf("calculateStockedPacks(%d, %v, %v) error = %v, want %v", orderItemQty, packSizes, stock, err, ErrInsufficientStock)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("calculateStockedPacks(%d, %v, %v) error = %v", orderItemQty, packSizes, stock, err)
+		}
+
+		if !withinStock(result.Packs, stock) || result.Total != wantTotal || result.PackCount != wantCount {
+			t.Fatalf("calculateStockedPacks(%d, %v, %v) = %v (total %d, packs %d), brute force gives total %d, packs %d",
+				orderItemQty, packSizes, stock, result.Packs, result.Total, result.PackCount, wantTotal, wantCount)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// validateConstraints checks that constraints only bound sizes of the pack set, are not
+// negative and never ask for more packs of a size than they allow
+func validateConstraints(constraints model.PackConstraints, packSizes []int) error {
+	if constraints.MinOrderQuantity < 0 {
+		return fmt.Errorf("%w: negative minimum order quantity", ErrInvalidPackConstraint)
+	}
+
+	for _, bounds := range []map[int]int{constraints.MinPacks, constraints.MaxPacks} {
+		for packSize, n := range bounds {
+			if n < 0 {
+				return fmt.Errorf("%w: negative pack count for %d", ErrInvalidPackConstraint, packSize)
+			}
+
+			if !slices.Contains(packSizes, packSize) {
+				return fmt.Errorf("%w: %d", ErrNotFoundPackSize, packSize)
+			}
+		}
+	}
+
+	for packSize, minimum := range constraints.MinPacks {
+		if maximum, ok := constraints.MaxPacks[packSize]; ok && minimum > maximum {
+			return fmt.Errorf("%w: at least %d and at most %d packs of %d", ErrInvalidPackConstraint, minimum, maximum, packSize)
+		}
+	}
+
+	return nil
+}
+
+// hasConstraints reports whether constraints bound any pack size
+func hasConstraints(constraints model.PackConstraints) bool {
+	return len(constraints.MinPacks) > 0 || len(constraints.MaxPacks) > 0
+}
+
+// mergeConstraints returns the stored constraints with the requested ones taking their
+// place size by size
+func mergeConstraints(stored, requested model.PackConstraints) model.PackConstraints {
+	merged := model.PackConstraints{
+		MinPacks:         maps.Clone(stored.MinPacks),
+		MaxPacks:         maps.Clone(stored.MaxPacks),
+		MinOrderQuantity: stored.MinOrderQuantity,
+	}
+
+	if merged.MinPacks == nil {
+		merged.MinPacks = make(map[int]int)
+	}
+
+	if merged.MaxPacks == nil {
+		merged.MaxPacks = make(map[int]int)
+	}
+
+	maps.Copy(merged.MinPacks, requested.MinPacks)
+	maps.Copy(merged.MaxPacks, requested.MaxPacks)
+
+	return merged
+}
+
+// constraintsFor returns the constraints bounding sizes of the pack set. Constraints on
+// sizes that were removed since they were stored are left out.
+func constraintsFor(constraints model.PackConstraints, packSizes []int) model.PackConstraints {
+	removed := func(packSize, _ int) bool {
+		return !slices.Contains(packSizes, packSize)
+	}
+
+	constraints.MinPacks = maps.Clone(constraints.MinPacks)
+	constraints.MaxPacks = maps.Clone(constraints.MaxPacks)
+	maps.DeleteFunc(constraints.MinPacks, removed)
+	maps.DeleteFunc(constraints.MaxPacks, removed)
+
+	return constraints
+}
+
+// ConstraintError is returned when no combination the pack constraints allow covers an order
+type ConstraintError struct {
+	OrderItemQuantity int
+	// Capacity is the most items the packs allowed hold
+	Capacity int
+}
+
+// Error implements the error interface
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s: order of %d items, the packs allowed hold at most %d", ErrConstraintsInfeasible, e.OrderItemQuantity, e.Capacity)
+}
+
+// Unwrap lets errors.Is match ErrConstraintsInfeasible
+func (e *ConstraintError) Unwrap() error {
+	return ErrConstraintsInfeasible
+}
+
+// calculateConstrainedPacks finds the combination with the fewest items covering an
+// order, and among those the fewest packs, holding at least the minimum and at most the
+// maximum packs of every constrained size, and no more packs than are in stock unless
+// stock is nil. It returns a *ConstraintError when the packs allowed cannot cover the
+// order, and stops with ErrComputationBudgetExceeded once the budget runs out or ctx is done.
+//
+// The minimum packs are held whatever the order, so only the rest of the order is solved
+// for, with every maximum lowered by the packs already held.
+func calculateConstrainedPacks(ctx context.Context, orderItemQty int, packSizes []int, constraints model.PackConstraints, stock map[int]int, budget Budget) (OptimalPacking, error) {
+	if len(packSizes) == 0 {
+		return OptimalPacking{}, ErrNoPackSizesConfigured
+	}
+
+	fixed := make(map[int]int)
+	bounds := make(map[int]int)
+	fixedTotal := 0
+
+	for _, packSize := range packSizes {
+		minimum := constraints.MinPacks[packSize]
+		if minimum > 0 {
+			fixed[packSize] = minimum
+			fixedTotal += minimum * packSize
+		}
+
+		maximum, bounded := constraints.MaxPacks[packSize]
+		if available, ok := stock[packSize]; ok && (!bounded || available < maximum) {
+			if available < minimum {
+				return OptimalPacking{}, fmt.Errorf("%w: %d packs of %d required, %d in stock", ErrInsufficientStock, minimum, packSize, available)
+			}
+
+			maximum, bounded = available, true
+		}
+
+		if bounded {
+			bounds[packSize] = maximum - minimum
+		}
+	}
+
+	remainder := orderItemQty - fixedTotal
+	if remainder <= 0 {
+		return newOptimalPacking(fixed, Trace{Branch: BranchConstrained}), nil
+	}
+
+	packing, err := calculateStockedPacks(ctx, remainder, packSizes, bounds, budget)
+
+	var stockErr *InsufficientStockError
+	if errors.As(err, &stockErr) {
+		return OptimalPacking{}, &ConstraintError{OrderItemQuantity: orderItemQty, Capacity: fixedTotal + stockErr.Capacity}
+	}
+
+	if err != nil {
+		return OptimalPacking{}, err
+	}
+
+	for packSize, n := range fixed {
+		packing.Packs[packSize] += n
+	}
+
+	trace := packing.Trace
+	trace.Branch = BranchConstrained
+	trace.Remainder = remainder
+
+	return newOptimalPacking(packing.Packs, trace), nil
+}
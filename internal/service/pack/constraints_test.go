@@ -0,0 +1,224 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+func TestCalculateConstrainedPacks(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name          string
+		orderItemQty  int
+		constraints   model.PackConstraints
+		stock         map[int]int
+		expectedPacks map[int]int
+		expectedErr   error
+		description   string
+	}{
+		{
+			name:          "Minimum of the largest pack",
+			orderItemQty:  1200,
+			constraints:   model.PackConstraints{MinPacks: map[int]int{5000: 1}},
+			expectedPacks: map[int]int{5000: 1},
+			description:   "Should hold the required pack even when it alone covers the order",
+		},
+		{
+			name:          "Minimum on top of the order",
+			orderItemQty:  12001,
+			constraints:   model.PackConstraints{MinPacks: map[int]int{500: 1}},
+			expectedPacks: map[int]int{5000: 2, 1000: 1, 500: 2, 250: 1},
+			description:   "Should solve the rest of the order after the required packs",
+		},
+		{
+			name:          "Maximum of the smallest pack",
+			orderItemQty:  12001,
+			constraints:   model.PackConstraints{MaxPacks: map[int]int{250: 0}},
+			expectedPacks: map[int]int{5000: 2, 2000: 1, 500: 1},
+			description:   "Should round up to the next size when the smallest is ruled out",
+		},
+		{
+			name:          "Maximum below the stock",
+			orderItemQty:  1000,
+			constraints:   model.PackConstraints{MaxPacks: map[int]int{1000: 0}},
+			stock:         map[int]int{500: 1},
+			expectedPacks: map[int]int{500: 1, 250: 2},
+			description:   "Should honour both the constraints and the stock",
+		},
+		{
+			name:         "Maximums cannot cover the order",
+			orderItemQty: 1000,
+			constraints:  model.PackConstraints{MaxPacks: map[int]int{5000: 0, 2000: 0, 1000: 0, 500: 1, 250: 1}},
+			expectedErr:  ErrConstraintsInfeasible,
+			description:  "Should fail when the packs allowed hold fewer items than ordered",
+		},
+		{
+			name:         "Minimum above the stock",
+			orderItemQty: 1000,
+			constraints:  model.PackConstraints{MinPacks: map[int]int{5000: 2}},
+			stock:        map[int]int{5000: 1},
+			expectedErr:  ErrInsufficientStock,
+			description:  "Should fail when the stock cannot meet a minimum",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calculateConstrainedPacks(context.Background(), tt.orderItemQty, packSizes, tt.constraints, tt.stock, Budget{})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("calculateConstrainedPacks() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if tt.expectedErr == nil && !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
+				t.Errorf("calculateConstrainedPacks() packs = %v, want %v", result.Packs, tt.expectedPacks)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestConstraintError(t *testing.T) {
+	_, err := calculateConstrainedPacks(context.Background(), 1000, []int{500, 250},
+		model.PackConstraints{MinPacks: map[int]int{250: 1}, MaxPacks: map[int]int{500: 1, 250: 1}}, nil, Budget{})
+
+	var constraintErr *ConstraintError
+	if !errors.As(err, &constraintErr) {
+		t.Fatalf("calculateConstrainedPacks() error = %v, want a *ConstraintError", err)
+	}
+
+	if constraintErr.OrderItemQuantity != 1000 || constraintErr.Capacity != 750 {
+		t.Errorf("calculateConstrainedPacks() error = %+v, want order 1000 and capacity 750", constraintErr)
+	}
+}
+
+func TestValidateConstraints(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name        string
+		constraints model.PackConstraints
+		expectedErr error
+	}{
+		{
+			name:        "Valid",
+			constraints: model.PackConstraints{MinPacks: map[int]int{5000: 1}, MaxPacks: map[int]int{5000: 1, 250: 3}},
+		},
+		{
+			name:        "Negative",
+			constraints: model.PackConstraints{MaxPacks: map[int]int{250: -1}},
+			expectedErr: ErrInvalidPackConstraint,
+		},
+		{
+			name:        "Minimum above maximum",
+			constraints: model.PackConstraints{MinPacks: map[int]int{250: 4}, MaxPacks: map[int]int{250: 3}},
+			expectedErr: ErrInvalidPackConstraint,
+		},
+		{
+			name:        "Unknown size",
+			constraints: model.PackConstraints{MinPacks: map[int]int{300: 1}},
+			expectedErr: ErrNotFoundPackSize,
+		},
+		{
+			name:        "Negative minimum order quantity",
+			constraints: model.PackConstraints{MinOrderQuantity: -1},
+			expectedErr: ErrInvalidPackConstraint,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateConstraints(tt.constraints, packSizes); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("validateConstraints() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+// bruteForceConstrainedPacks tries every count of every pack size the constraints allow
+// up to one pack past the order and returns the fewest items, then the fewest packs,
+// covering it, or -1 when none does
+func bruteForceConstrainedPacks(orderItemQty int, packSizes []int, constraints model.PackConstraints) (int, int) {
+	bestTotal, bestCount := -1, -1
+
+	var try func(index, total, count int)
+	try = func(index, total, count int) {
+		if index == len(packSizes) {
+			if total >= orderItemQty && (bestTotal < 0 || total < bestTotal || (total == bestTotal && count < bestCount)) {
+				bestTotal, bestCount = total, count
+			}
+
+			return
+		}
+
+		packSize := packSizes[index]
+
+		most := constraints.MinPacks[packSize] + orderItemQty/packSize + 1
+		if maximum, ok := constraints.MaxPacks[packSize]; ok {
+			most = min(most, maximum)
+		}
+
+		for n := constraints.MinPacks[packSize]; n <= most; n++ {
+			try(index+1, total+n*packSize, count+n)
+		}
+	}
+
+	try(0, 0, 0)
+
+	return bestTotal, bestCount
+}
+
+func TestCalculateConstrainedPacksMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 3000; i++ {
+		packSizes := randomPackSizes(rng)
+		orderItemQty := 1 + rng.Intn(300)
+
+		constraints := model.PackConstraints{MinPacks: make(map[int]int), MaxPacks: make(map[int]int)}
+		for _, packSize := range packSizes {
+			minimum := 0
+			if rng.Intn(3) == 0 {
+				minimum = rng.Intn(3)
+				constraints.MinPacks[packSize] = minimum
+			}
+
+			if rng.Intn(2) == 0 {
+				constraints.MaxPacks[packSize] = minimum + rng.Intn(6)
+			}
+		}
+
+		wantTotal, wantCount := bruteForceConstrainedPacks(orderItemQty, packSizes, constraints)
+
+		result, err := calculateConstrainedPacks(context.Background(), orderItemQty, packSizes, constraints, nil, Budget{})
+		if wantTotal < 0 {
+			if !errors.Is(err, ErrConstraintsInfeasible) {
+				t.Fatalf("calculateConstrainedPacks(%d, %v, %+v) error = %v, want %v", orderItemQty, packSizes, constraints, err, ErrConstraintsInfeasible)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("calculateConstrainedPacks(%d, %v, %+v) error = %v", orderItemQty, packSizes, constraints, err)
+		}
+
+		for packSize, n := range result.Packs {
+			maximum, bounded := constraints.MaxPacks[packSize]
+			if n < constraints.MinPacks[packSize] || (bounded && n > maximum) {
+				t.Fatalf("calculateConstrainedPacks(%d, %v, %+v) = %v breaks the constraints", orderItemQty, packSizes, constraints, result.Packs)
+			}
+		}
+
+		if result.Total != wantTotal || result.PackCount != wantCount {
+			t.Fatalf("calculateConstrainedPacks(%d, %v, %+v) = %v (total %d, packs %d), brute force gives total %d, packs %d",
+				orderItemQty, packSizes, constraints, result.Packs, result.Total, result.PackCount, wantTotal, wantCount)
+		}
+	}
+}
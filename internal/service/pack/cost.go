@@ -0,0 +1,227 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// Objective selects what the calculator optimises for
+type Objective string
+
+const (
+	// ObjectiveItems ships the fewest items, then uses the fewest packs
+	ObjectiveItems Objective = "items"
+	// ObjectiveCost ships the cheapest combination counting pack and surplus item costs,
+	// then the fewest items, then uses the fewest packs
+	ObjectiveCost Objective = "cost"
+)
+
+// priceList holds what every pack size and every surplus item costs
+type priceList struct {
+	packs   map[int]model.Pack
+	surplus int
+}
+
+// newPriceList builds the price list of a pack set. Every size in the set needs a cost.
+func newPriceList(costs model.PackCosts, packSizes []int) (priceList, error) {
+	prices := priceList{
+		packs:   make(map[int]model.Pack, len(costs.Packs)),
+		surplus: costs.SurplusItemCost,
+	}
+
+	for _, pack := range costs.Packs {
+		prices.packs[pack.Size] = pack
+	}
+
+	for _, packSize := range packSizes {
+		if _, ok := prices.packs[packSize]; !ok {
+			return priceList{}, fmt.Errorf("%w: %d", ErrMissingPackCost, packSize)
+		}
+	}
+
+	return prices, nil
+}
+
+// cost returns what using one pack of a size costs
+func (p priceList) cost(packSize int) int {
+	return p.packs[packSize].Cost()
+}
+
+// breakdown itemises what shipping packs for an order of orderQty items costs
+func (p priceList) breakdown(orderQty int, packs map[int]int) CostBreakdown {
+	breakdown := CostBreakdown{Packs: []PackCostLine{}}
+
+	shipped := 0
+	for _, line := range newPackList(packs) {
+		pack := p.packs[line.Size]
+
+		costLine := PackCostLine{
+			Size:         line.Size,
+			Count:        line.Count,
+			MaterialCost: line.Count * pack.MaterialCost,
+			HandlingCost: line.Count * pack.HandlingCost,
+		}
+		costLine.Total = costLine.MaterialCost + costLine.HandlingCost
+
+		breakdown.Packs = append(breakdown.Packs, costLine)
+		breakdown.PackCost += costLine.Total
+		shipped += line.Size * line.Count
+	}
+
+	breakdown.SurplusItems = max(shipped-orderQty, 0)
+	breakdown.SurplusCost = breakdown.SurplusItems * p.surplus
+	breakdown.Total = breakdown.PackCost + breakdown.SurplusCost
+
+	return breakdown
+}
+
+// calculateCheapestPacks finds the pack combination for a given order quantity that costs
+// least, counting the cost of every pack and of every surplus item. Ties go to the
+// combination with the fewest items, then to the one with the fewest packs.
+// It stops with ErrComputationBudgetExceeded once the budget runs out or ctx is done.
+func calculateCheapestPacks(ctx context.Context, orderItemQty int, packSizes []int, prices priceList, budget Budget) (OptimalPacking, error) {
+	if len(packSizes) == 0 {
+		return OptimalPacking{}, ErrNoPackSizesConfigured
+	}
+
+	// Take the cheapest packs per item every cheapest combination holds and optimise what is left
+	cheapest := cheapestPerItem(packSizes, prices)
+	count := cheapestPackCount(orderItemQty, packSizes, cheapest)
+
+	packs := make(map[int]int)
+	if count > 0 {
+		packs[cheapest] = count
+	}
+
+	remainder := orderItemQty - count*cheapest
+
+	// The cheapest packs alone cover the order
+	if remainder <= 0 {
+		return newOptimalPacking(packs, Trace{Branch: BranchLowestCost, LargestPacks: count}), nil
+	}
+
+	ctx, cancel := withBudgetTimeout(ctx, budget)
+	defer cancel()
+
+	t := newTracker(ctx, budget)
+
+	best, err := findCheapestPackCombination(t, remainder, packSizes, prices)
+	if err != nil {
+		return OptimalPacking{}, err
+	}
+
+	for k, v := range best.Packs {
+		packs[k] += v
+	}
+
+	return newOptimalPacking(packs, Trace{
+		Branch:        BranchLowestCost,
+		LargestPacks:  count,
+		Remainder:     remainder,
+		NodesExplored: t.nodes,
+	}), nil
+}
+
+// cheapestPerItem returns the pack size with the lowest cost per item, the larger one on ties
+func cheapestPerItem(packSizes []int, prices priceList) int {
+	// packSizes should be sorted in descending order so ties keep the larger packs
+	best := packSizes[0]
+	for _, packSize := range packSizes[1:] {
+		if prices.cost(packSize)*best < prices.cost(best)*packSize {
+			best = packSize
+		}
+	}
+
+	return best
+}
+
+// cheapestPackCount returns how many packs of the cheapest size per item some cheapest
+// combination for the order holds.
+//
+// For another size s, cheapest/gcd(s, cheapest) packs of s hold exactly as many items as
+// s/gcd(s, cheapest) cheapest packs, which cost no more and, on a tie, are fewer packs.
+// A cheapest combination therefore holds fewer than cheapest/gcd(s, cheapest) packs of
+// every other size, and the rest of the order needs cheapest packs.
+func cheapestPackCount(orderItemQty int, packSizes []int, cheapest int) int {
+	bound := 0
+	for _, size := range packSizes {
+		if size != cheapest {
+			bound += size * (cheapest/gcd(size, cheapest) - 1)
+		}
+	}
+
+	if orderItemQty <= bound {
+		return 0
+	}
+
+	return (orderItemQty - bound + cheapest - 1) / cheapest
+}
+
+// findCheapestPackCombination uses dynamic programming to find the combination covering
+// orderQty that costs least, then holds the fewest items, then the fewest packs. Every
+// item total it evaluates costs one node per pack size against the tracker's budget.
+func findCheapestPackCombination(t *tracker, orderQty int, packSizes []int, prices priceList) (PackCombination, error) {
+	// packSizes should be sorted in descending order so ties keep the larger packs
+	largest := packSizes[0]
+
+	// Removing a pack from a combination that still covers the order afterwards costs
+	// no more and ships fewer items, so the best total is below orderQty+largest.
+	limit := orderQty + largest
+
+	// Fail before allocating tables the budget could never fill
+	if err := t.fits(limit * len(packSizes)); err != nil {
+		return PackCombination{}, err
+	}
+
+	// costs[t] and counts[t] are the cost and pack count of the cheapest way to hold
+	// exactly t items (count -1 when impossible), last[t] is the pack size added last.
+	costs := make([]int, limit)
+	counts := make([]int, limit)
+	last := make([]int, limit)
+
+	for total := 1; total < limit; total++ {
+		if err := t.spend(len(packSizes)); err != nil {
+			return PackCombination{}, err
+		}
+
+		counts[total] = -1
+
+		for _, packSize := range packSizes {
+			if packSize > total || counts[total-packSize] < 0 {
+				continue
+			}
+
+			cost := costs[total-packSize] + prices.cost(packSize)
+			count := counts[total-packSize] + 1
+
+			if counts[total] < 0 || cost < costs[total] || (cost == costs[total] && count < counts[total]) {
+				costs[total] = cost
+				counts[total] = count
+				last[total] = packSize
+			}
+		}
+	}
+
+	best, bestCost := -1, math.MaxInt
+	for total := orderQty; total < limit; total++ {
+		if counts[total] < 0 {
+			continue
+		}
+
+		// Totals are visited in increasing order, so ties keep the fewest items
+		cost := costs[total] + (total-orderQty)*prices.surplus
+		if cost < bestCost {
+			best, bestCost = total, cost
+		}
+	}
+
+	packs := make(map[int]int)
+	for rest := best; rest > 0; rest -= last[rest] {
+		packs[last[rest]]++
+	}
+
+	return PackCombination{Packs: packs, Total: best, PackCount: counts[best]}, nil
+}
@@ -0,0 +1,173 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// testPrices prices the default pack sizes so that 2000-packs are the cheapest per item
+// and 5000-packs the dearest
+func testPrices(surplusItemCost int) priceList {
+	return priceList{
+		packs: map[int]model.Pack{
+			5000: {Size: 5000, MaterialCost: 900, HandlingCost: 100},
+			2000: {Size: 2000, MaterialCost: 120, HandlingCost: 30},
+			1000: {Size: 1000, MaterialCost: 80, HandlingCost: 20},
+			500:  {Size: 500, MaterialCost: 40, HandlingCost: 20},
+			250:  {Size: 250, MaterialCost: 20, HandlingCost: 20},
+		},
+		surplus: surplusItemCost,
+	}
+}
+
+func TestCalculateCheapestPacks(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name            string
+		orderItemQty    int
+		surplusItemCost int
+		expectedPacks   map[int]int
+		expectedCost    int
+		description     string
+	}{
+		{
+			name:          "Cheaper than the exact pack",
+			orderItemQty:  5000,
+			expectedPacks: map[int]int{2000: 2, 1000: 1},
+			expectedCost:  400,
+			description:   "Should skip the exact 5000-pack when smaller packs holding the same items cost less",
+		},
+		{
+			name:          "Free surplus",
+			orderItemQty:  1750,
+			expectedPacks: map[int]int{2000: 1},
+			expectedCost:  150,
+			description:   "Should overshoot into a cheaper pack when surplus items cost nothing",
+		},
+		{
+			name:            "Priced surplus",
+			orderItemQty:    1750,
+			surplusItemCost: 1,
+			expectedPacks:   map[int]int{1000: 1, 500: 1, 250: 1},
+			expectedCost:    200,
+			description:     "Should avoid the overshoot when surplus items cost more than it saves",
+		},
+		{
+			name:          "Large order",
+			orderItemQty:  12001,
+			expectedPacks: map[int]int{2000: 6, 250: 1},
+			expectedCost:  940,
+			description:   "Should fill a large order with the cheapest packs per item and optimise the rest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices := testPrices(tt.surplusItemCost)
+
+			result, err := calculateCheapestPacks(context.Background(), tt.orderItemQty, packSizes, prices, Budget{})
+			if err != nil {
+				t.Fatalf("calculateCheapestPacks() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
+				t.Errorf("calculateCheapestPacks() packs = %v, want %v", result.Packs, tt.expectedPacks)
+			}
+
+			if cost := prices.breakdown(tt.orderItemQty, result.Packs).Total; cost != tt.expectedCost {
+				t.Errorf("calculateCheapestPacks() cost = %d, want %d", cost, tt.expectedCost)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestPriceListBreakdown(t *testing.T) {
+	breakdown := testPrices(2).breakdown(1750, map[int]int{2000: 1, 250: 0})
+
+	expected := CostBreakdown{
+		Packs:        []PackCostLine{{Size: 2000, Count: 1, MaterialCost: 120, HandlingCost: 30, Total: 150}},
+		PackCost:     150,
+		SurplusItems: 250,
+		SurplusCost:  500,
+		Total:        650,
+	}
+
+	if !reflect.DeepEqual(breakdown, expected) {
+		t.Errorf("breakdown() = %+v, want %+v", breakdown, expected)
+	}
+}
+
+func TestNewPriceListMissingCost(t *testing.T) {
+	costs := model.PackCosts{Packs: []model.Pack{{Size: 500, MaterialCost: 10}}}
+
+	if _, err := newPriceList(costs, []int{500, 250}); !errors.Is(err, ErrMissingPackCost) {
+		t.Errorf("newPriceList() error = %v, want %v", err, ErrMissingPackCost)
+	}
+}
+
+// bruteForceCheapestPacks tries every count of every pack size that can still matter and
+// returns the lowest cost, then the fewest items, then the fewest packs covering the order
+func bruteForceCheapestPacks(orderItemQty int, packSizes []int, prices priceList) (bestCost, bestTotal, bestCount int) {
+	bestTotal = -1
+
+	var try func(index, total, count, cost int)
+	try = func(index, total, count, cost int) {
+		if total >= orderItemQty {
+			cost += (total - orderItemQty) * prices.surplus
+			if bestTotal < 0 || cost < bestCost || (cost == bestCost && total < bestTotal) ||
+				(cost == bestCost && total == bestTotal && count < bestCount) {
+				bestCost, bestTotal, bestCount = cost, total, count
+			}
+
+			return
+		}
+
+		if index == len(packSizes) {
+			return
+		}
+
+		packSize := packSizes[index]
+		for n := 0; total+n*packSize < orderItemQty+packSize; n++ {
+			try(index+1, total+n*packSize, count+n, cost+n*prices.cost(packSize))
+		}
+	}
+
+	try(0, 0, 0, 0)
+
+	return bestCost, bestTotal, bestCount
+}
+
+func TestCalculateCheapestPacksMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 3000; i++ {
+		packSizes := randomPackSizes(rng)
+		orderItemQty := 1 + rng.Intn(300)
+
+		prices := priceList{packs: make(map[int]model.Pack), surplus: rng.Intn(3)}
+		for _, packSize := range packSizes {
+			prices.packs[packSize] = model.Pack{Size: packSize, MaterialCost: rng.Intn(40), HandlingCost: rng.Intn(10)}
+		}
+
+		wantCost, wantTotal, wantCount := bruteForceCheapestPacks(orderItemQty, packSizes, prices)
+
+		result, err := calculateCheapestPacks(context.Background(), orderItemQty, packSizes, prices, Budget{})
+		if err != nil {
+			t.Fatalf("calculateCheapestPacks(%d, %v) error = %v", orderItemQty, packSizes, err)
+		}
+
+		cost := prices.breakdown(orderItemQty, result.Packs).Total
+		if cost != wantCost || result.Total != wantTotal || result.PackCount != wantCount {
+			t.Fatalf("calculateCheapestPacks(%d, %v, %v) = %v (cost %d, total %d, packs %d), brute force gives cost %d, total %d, packs %d",
+				orderItemQty, packSizes, prices.packs, result.Packs, cost, result.Total, result.PackCount, wantCost, wantTotal, wantCount)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package pack
+
+import "fmt"
+
+// Explanation traces which branch of the calculation produced a result and why
+// the chosen combination beat the next-best one
+type Explanation struct {
+	Trace
+	NextBest *PackCombination `json:"nextBest,omitempty"`
+	Reason   string           `json:"reason"`
+}
+
+// explain builds the explanation for a packing given the next-best combination,
+// which is nil when no other combination covers the order, and the ranking that
+// ordered them
+func explain(packing OptimalPacking, nextBest *PackCombination, ranking Ranking) Explanation {
+	return Explanation{
+		Trace:    packing.Trace,
+		NextBest: nextBest,
+		Reason:   explainReason(packing, nextBest, ranking),
+	}
+}
+
+// explainReason describes why the packing ranks before the next-best combination: the
+// first criterion of the ranking the two differ by
+func explainReason(packing OptimalPacking, nextBest *PackCombination, ranking Ranking) string {
+	if nextBest == nil {
+		return "no other combination covers the order"
+	}
+
+	for _, criterion := range ranking.orDefault() {
+		switch criterion {
+		case CriterionItems:
+			if packing.Total != nextBest.Total {
+				return fmt.Sprintf("ships %d fewer items than the next-best combination", nextBest.Total-packing.Total)
+			}
+		case CriterionPacks:
+			if packing.PackCount == nextBest.PackCount {
+				continue
+			}
+
+			if packing.Total == nextBest.Total {
+				return fmt.Sprintf("ships the same %d items in %d fewer packs than the next-best combination",
+					packing.Total, nextBest.PackCount-packing.PackCount)
+			}
+
+			return fmt.Sprintf("ships %d fewer packs than the next-best combination", nextBest.PackCount-packing.PackCount)
+		case CriterionSizes:
+			if sizes, nextSizes := len(copyPacks(packing.Packs)), len(copyPacks(nextBest.Packs)); sizes != nextSizes {
+				return fmt.Sprintf("uses %d fewer pack sizes than the next-best combination", nextSizes-sizes)
+			}
+		case CriterionLargest:
+			return largerPacksReason(packing, nextBest)
+		}
+	}
+
+	return largerPacksReason(packing, nextBest)
+}
+
+// largerPacksReason describes a packing that ranks first only for holding larger packs
+func largerPacksReason(packing OptimalPacking, nextBest *PackCombination) string {
+	if packing.Total == nextBest.Total && packing.PackCount == nextBest.PackCount {
+		return fmt.Sprintf("ships the same %d items in the same %d packs as the next-best combination, using larger packs",
+			packing.Total, packing.PackCount)
+	}
+
+	return "uses larger packs than the next-best combination"
+}
@@ -0,0 +1,144 @@
+package pack
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name           string
+		packSizes      []int
+		orderItemQty   int
+		expectedBranch Branch
+		expectedPacks  int
+		expectedRemain int
+		expectedReason string
+		description    string
+	}{
+		{
+			name:           "Exact match - 2000",
+			packSizes:      packSizes,
+			orderItemQty:   2000,
+			expectedBranch: BranchExactMatch,
+			expectedReason: "ships the same 2000 items in 1 fewer packs than the next-best combination",
+			description:    "Should trace the exact match branch",
+		},
+		{
+			name:           "Below smallest pack - 100",
+			packSizes:      packSizes,
+			orderItemQty:   100,
+			expectedBranch: BranchBelowSmallestPack,
+			expectedReason: "ships 250 fewer items than the next-best combination",
+			description:    "Should trace the smallest pack branch",
+		},
+		{
+			name:           "Largest packs only - 99999",
+			packSizes:      []int{500, 250},
+			orderItemQty:   99999,
+			expectedBranch: BranchLargestPacksOnly,
+			expectedPacks:  200,
+			expectedReason: "ships the same 100000 items in 1 fewer packs than the next-best combination",
+			description:    "Should trace the branch where the largest packs cover the order",
+		},
+		{
+			name:           "Optimised remainder - 1324001",
+			packSizes:      packSizes,
+			orderItemQty:   1324001,
+			expectedBranch: BranchOptimisedRemainder,
+			expectedPacks:  261,
+			expectedRemain: 19001,
+			expectedReason: "ships the same 1324250 items in 1 fewer packs than the next-best combination",
+			description:    "Should trace the remainder optimised after the largest packs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packing, err := calculatePacks(context.Background(), tt.orderItemQty, tt.packSizes, Budget{})
+			if err != nil {
+				t.Fatalf("calculatePacks() error = %v", err)
+			}
+
+			alternatives, err := findAlternativeCombinations(context.Background(), tt.orderItemQty, tt.packSizes, nil, defaultSelection, packing, 1, Budget{})
+			if err != nil {
+				t.Fatalf("findAlternativeCombinations() error = %v", err)
+			}
+
+			explanation := explain(packing, &alternatives[0], defaultRanking)
+
+			if explanation.Branch != tt.expectedBranch {
+				t.Errorf("explain() branch = %v, want %v", explanation.Branch, tt.expectedBranch)
+			}
+
+			if explanation.LargestPacks != tt.expectedPacks || explanation.Remainder != tt.expectedRemain {
+				t.Errorf("explain() largest packs = %d, remainder = %d, want %d, %d",
+					explanation.LargestPacks, explanation.Remainder, tt.expectedPacks, tt.expectedRemain)
+			}
+
+			if tt.expectedBranch == BranchOptimisedRemainder && explanation.NodesExplored == 0 {
+				t.Errorf("explain() nodes explored = 0, want the solver's work")
+			}
+
+			if explanation.Reason != tt.expectedReason {
+				t.Errorf("explain() reason = %q, want %q", explanation.Reason, tt.expectedReason)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestExplainWithoutNextBest(t *testing.T) {
+	packing := newOptimalPacking(map[int]int{250: 1}, Trace{Branch: BranchBelowSmallestPack})
+
+	explanation := explain(packing, nil, defaultRanking)
+	if explanation.Reason != "no other combination covers the order" {
+		t.Errorf("explain() reason = %q", explanation.Reason)
+	}
+}
+
+func TestExplainWithRanking(t *testing.T) {
+	tests := []struct {
+		name           string
+		ranking        Ranking
+		packs          map[int]int
+		nextBest       map[int]int
+		expectedReason string
+	}{
+		{
+			name:           "Fewer packs",
+			ranking:        Ranking{CriterionPacks, CriterionItems},
+			packs:          map[int]int{2000: 1},
+			nextBest:       map[int]int{1000: 1, 250: 1},
+			expectedReason: "ships 1 fewer packs than the next-best combination",
+		},
+		{
+			name:           "Fewer sizes",
+			ranking:        Ranking{CriterionSizes, CriterionItems},
+			packs:          map[int]int{250: 3},
+			nextBest:       map[int]int{500: 1, 250: 1},
+			expectedReason: "uses 1 fewer pack sizes than the next-best combination",
+		},
+		{
+			name:           "Larger packs",
+			ranking:        Ranking{CriterionLargest},
+			packs:          map[int]int{5000: 1},
+			nextBest:       map[int]int{2000: 1},
+			expectedReason: "uses larger packs than the next-best combination",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextBest := newPackCombination(tt.nextBest)
+
+			explanation := explain(newOptimalPacking(tt.packs, Trace{Branch: BranchRanked}), &nextBest, tt.ranking)
+			if explanation.Reason != tt.expectedReason {
+				t.Errorf("explain() reason = %q, want %q", explanation.Reason, tt.expectedReason)
+			}
+		})
+	}
+}
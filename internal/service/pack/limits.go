@@ -0,0 +1,507 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// specList holds the weight and dimensions of pack sizes by size
+type specList map[int]model.PackSpec
+
+// newSpecList indexes pack specs by size
+func newSpecList(specs []model.PackSpec) specList {
+	list := make(specList, len(specs))
+	for _, spec := range specs {
+		list[spec.Size] = spec
+	}
+
+	return list
+}
+
+// covers returns an error wrapping ErrMissingPackSpec when a pack size has no spec
+func (l specList) covers(packSizes []int) error {
+	for _, packSize := range packSizes {
+		if _, ok := l[packSize]; !ok {
+			return fmt.Errorf("%w: %d", ErrMissingPackSpec, packSize)
+		}
+	}
+
+	return nil
+}
+
+// measure returns the gross weight and volume of packs, reporting false when a size
+// among them has no spec
+func (l specList) measure(packs map[int]int) (weight, volume int, ok bool) {
+	for packSize, count := range packs {
+		spec, found := l[packSize]
+		if !found {
+			return 0, 0, false
+		}
+
+		weight += count * spec.GrossWeight
+		volume += count * spec.Volume()
+	}
+
+	return weight, volume, true
+}
+
+// shipmentLimits caps the gross weight of a shipment and the parcels it takes, every
+// pack shipping as one parcel
+type shipmentLimits struct {
+	// maxWeight caps the gross weight in grams, negative means unlimited
+	maxWeight int
+	// maxParcels caps the packs, negative means unlimited
+	maxParcels int
+}
+
+// allows reports whether packs stay within the limits
+func (l shipmentLimits) allows(packs map[int]int, specs specList) bool {
+	c := newPackCombination(packs)
+	if l.maxParcels >= 0 && c.PackCount > l.maxParcels {
+		return false
+	}
+
+	weight, _, _ := specs.measure(packs)
+
+	return l.maxWeight < 0 || weight <= l.maxWeight
+}
+
+// ShipmentLimitError is returned when no combination covering an order stays within the
+// shipment limits
+type ShipmentLimitError struct {
+	OrderItemQuantity int
+	// MaxWeight is the gross weight cap in grams, negative when unlimited
+	MaxWeight int
+	// MaxParcels is the parcel cap, negative when unlimited
+	MaxParcels int
+}
+
+// Error implements the error interface
+func (e *ShipmentLimitError) Error() string {
+	var caps []string
+	if e.MaxWeight >= 0 {
+		caps = append(caps, fmt.Sprintf("%d g", e.MaxWeight))
+	}
+
+	if e.MaxParcels >= 0 {
+		caps = append(caps, fmt.Sprintf("%d parcels", e.MaxParcels))
+	}
+
+	return fmt.Sprintf("%s: no combination for %d items fits %s", ErrShipmentLimitExceeded, e.OrderItemQuantity, strings.Join(caps, " in "))
+}
+
+// Unwrap lets errors.Is match ErrShipmentLimitExceeded
+func (e *ShipmentLimitError) Unwrap() error {
+	return ErrShipmentLimitExceeded
+}
+
+// maxLimitedTableEntries caps the item totals times pack counts the shipment limited
+// search tabulates, whatever the computation budget
+const maxLimitedTableEntries = 1 << 21
+
+// calculateLimitedPacks finds the combination with the fewest items covering an order,
+// and among those the fewest packs, that stays within the shipment limits. Every size
+// needs a spec. It returns a *ShipmentLimitError when no combination fits, and stops
+// with ErrComputationBudgetExceeded once the budget runs out or ctx is done.
+func calculateLimitedPacks(ctx context.Context, orderItemQty int, packSizes []int, specs specList, limits shipmentLimits, budget Budget) (OptimalPacking, error) {
+	packing, err := calculatePacks(ctx, orderItemQty, packSizes, budget)
+	if err != nil || limits.allows(packing.Packs, specs) {
+		return packing, err
+	}
+
+	exceeded := &ShipmentLimitError{
+		OrderItemQuantity: orderItemQty,
+		MaxWeight:         limits.maxWeight,
+		MaxParcels:        limits.maxParcels,
+	}
+
+	ctx, cancel := withBudgetTimeout(ctx, budget)
+	defer cancel()
+
+	t := newTracker(ctx, budget)
+
+	search, err := newLimitedSearch(t, orderItemQty, packSizes, specs, limits)
+	if err != nil {
+		return OptimalPacking{}, err
+	}
+
+	if err := search.visit(0, 0, 0, 0); err != nil {
+		return OptimalPacking{}, err
+	}
+
+	if !search.found {
+		return OptimalPacking{}, exceeded
+	}
+
+	trace := Trace{Branch: BranchShipmentLimited, Remainder: orderItemQty, NodesExplored: t.nodes}
+	if len(search.outer) == 0 {
+		trace.LargestPacks = search.fixed
+		trace.Remainder -= search.fixed * packSizes[0]
+	}
+
+	return newOptimalPacking(search.best.Packs, trace), nil
+}
+
+// limitedSearch searches for the best combination within shipment limits around a pivot
+// size: the lightest pack per item, the largest among equals, or the largest pack when
+// weight is unlimited.
+//
+// Swapping smaller packs for pivot packs holding the same items saves packs without
+// adding weight, so as in calculatePacks the best combination holds the pivot packs
+// largestPackCount asks for, and the rest is read from a table. The packs of the sizes
+// above the pivot are heavier per item and are searched for size by size, only as many
+// as the weight still allows.
+type limitedSearch struct {
+	t        *tracker
+	orderQty int
+	// fewest is the fewest items any combination covering the order holds, the order
+	// rounded up to a multiple of the pack sizes' greatest common divisor
+	fewest int
+	specs  specList
+	limits shipmentLimits
+	// outer holds the sizes above the pivot, inner the pivot and the sizes below it
+	outer []int
+	inner []int
+	// most caps the packs of an outer size any best combination holds, -1 when unbounded
+	most  map[int]int
+	table *limitedTable
+	// packs holds the outer packs of the branch being searched
+	packs map[int]int
+	best  PackCombination
+	// fixed is the pivot packs largestPackCount asked for in best
+	fixed int
+	found bool
+}
+
+// newLimitedSearch splits the pack sizes around the pivot and tabulates the remainders
+// of the inner sizes
+func newLimitedSearch(t *tracker, orderQty int, packSizes []int, specs specList, limits shipmentLimits) (*limitedSearch, error) {
+	pivot := 0
+	if limits.maxWeight >= 0 {
+		for i, packSize := range packSizes {
+			if specs[packSize].GrossWeight*packSizes[pivot] < specs[packSizes[pivot]].GrossWeight*packSize {
+				pivot = i
+			}
+		}
+	}
+
+	s := &limitedSearch{
+		t:        t,
+		orderQty: orderQty,
+		fewest:   orderQty,
+		specs:    specs,
+		limits:   limits,
+		outer:    packSizes[:pivot],
+		inner:    packSizes[pivot:],
+		most:     make(map[int]int, pivot),
+		packs:    make(map[int]int, pivot),
+	}
+
+	divisor := 0
+	for _, packSize := range packSizes {
+		divisor = gcd(divisor, packSize)
+	}
+
+	if r := orderQty % divisor; r > 0 {
+		s.fewest += divisor - r
+	}
+
+	// A pack is never worth holding a/gcd(a, s) times when a larger pack a is at most as
+	// heavy per item, as swapping them holds the same items in fewer packs and no more weight
+	for i, packSize := range s.outer {
+		s.most[packSize] = -1
+		for _, larger := range packSizes[:i] {
+			if specs[larger].GrossWeight*packSize <= specs[packSize].GrossWeight*larger {
+				most := larger/gcd(larger, packSize) - 1
+				if s.most[packSize] < 0 || most < s.most[packSize] {
+					s.most[packSize] = most
+				}
+			}
+		}
+	}
+
+	// Without outer sizes the remainder is known, otherwise it is at most the exchange bound
+	remainder := exchangeBound(s.inner)
+	if len(s.outer) == 0 {
+		remainder = orderQty - largestPackCount(orderQty, s.inner)*s.inner[0]
+	}
+
+	table, err := newLimitedTable(t, max(remainder, 0)+s.inner[0], s.inner, specs, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	s.table = table
+
+	return s, nil
+}
+
+// visit searches the packs of the outer size at index and the sizes after it, given the
+// items, weight and packs of the outer packs chosen so far. Larger counts are tried
+// first, so among equally good combinations the one holding more of the larger packs wins.
+func (s *limitedSearch) visit(index, items, weight, count int) error {
+	if err := s.t.spend(1); err != nil {
+		return err
+	}
+
+	if index == len(s.outer) {
+		return s.complete(items, weight, count)
+	}
+
+	packSize := s.outer[index]
+	rest := max(s.orderQty-items, 0)
+
+	// Once a combination holds the fewest items possible only fewer packs can beat it, and
+	// the rest needs at least rest/packSize more packs
+	if s.found && s.best.Total == s.fewest && count+(rest+packSize-1)/packSize >= s.best.PackCount {
+		return nil
+	}
+
+	// The sizes after this one hold at most next items per pack
+	next := s.inner[0]
+	if index+1 < len(s.outer) {
+		next = s.outer[index+1]
+	}
+
+	for n := s.mostPacks(packSize, rest, weight, count); n >= 0; n-- {
+		// Every pack fewer of this size takes more than one pack of the sizes after it, so
+		// once the packs needed at least exceed the parcels or match the best they only grow
+		least := count + n + (max(rest-n*packSize, 0)+next-1)/next
+		if (s.limits.maxParcels >= 0 && least > s.limits.maxParcels) ||
+			(s.found && s.best.Total == s.fewest && least >= s.best.PackCount) {
+			break
+		}
+
+		s.packs[packSize] = n
+		if err := s.visit(index+1, items+n*packSize, weight+n*s.specs[packSize].GrossWeight, count+n); err != nil {
+			return err
+		}
+	}
+
+	delete(s.packs, packSize)
+
+	return nil
+}
+
+// mostPacks returns the most packs of an outer size worth trying, or -1 when even none
+// leaves room for the rest of the order within the limits. More packs than cover the
+// rest only add items, and past the weight the rest needs at least in pivot packs none fits.
+func (s *limitedSearch) mostPacks(packSize, rest, weight, count int) int {
+	hi := (rest + packSize - 1) / packSize
+	if most := s.most[packSize]; most >= 0 {
+		hi = min(hi, most)
+	}
+
+	if s.limits.maxParcels >= 0 {
+		hi = min(hi, s.limits.maxParcels-count)
+	}
+
+	// The weight the rest needs grows with every pack of a size heavier per item than the pivot
+	fits := func(n int) bool {
+		return s.lightEnough(weight+n*s.specs[packSize].GrossWeight, max(rest-n*packSize, 0))
+	}
+
+	if hi < 0 || !fits(0) {
+		return -1
+	}
+
+	lo := 0
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if fits(mid) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return lo
+}
+
+// lightEnough reports whether packs of the given weight leave room within the weight
+// limit for rest more items, no pack holding them lighter per item than the pivot
+func (s *limitedSearch) lightEnough(weight, rest int) bool {
+	if s.limits.maxWeight < 0 {
+		return true
+	}
+
+	pivot := s.inner[0]
+
+	return weight*pivot+rest*s.specs[pivot].GrossWeight <= s.limits.maxWeight*pivot
+}
+
+// complete covers the rest of the order after the outer packs chosen with the pivot
+// packs largestPackCount asks for and the best remainder in the table, keeping the
+// result when it beats the best combination found so far
+func (s *limitedSearch) complete(items, weight, count int) error {
+	pivot := s.inner[0]
+
+	rest := s.orderQty - items
+	fixed := 0
+	if rest > 0 {
+		fixed = largestPackCount(rest, s.inner)
+	}
+
+	weight += fixed * s.specs[pivot].GrossWeight
+	count += fixed
+
+	limits := s.limits
+	if limits.maxWeight >= 0 {
+		limits.maxWeight -= weight
+	}
+
+	if limits.maxParcels >= 0 {
+		limits.maxParcels -= count
+	}
+
+	if (s.limits.maxWeight >= 0 && limits.maxWeight < 0) || (s.limits.maxParcels >= 0 && limits.maxParcels < 0) {
+		return nil
+	}
+
+	remainder, found, err := s.table.cover(s.t, rest-fixed*pivot, limits)
+	if err != nil || !found {
+		return err
+	}
+
+	total := items + fixed*pivot + remainder.Total
+	packCount := count + remainder.PackCount
+	if s.found && (total > s.best.Total || (total == s.best.Total && packCount >= s.best.PackCount)) {
+		return nil
+	}
+
+	packs := remainder.Packs
+	for packSize, n := range s.packs {
+		if n > 0 {
+			packs[packSize] += n
+		}
+	}
+
+	if fixed > 0 {
+		packs[pivot] += fixed
+	}
+
+	s.best, s.fixed, s.found = newPackCombination(packs), fixed, true
+
+	return nil
+}
+
+// limitedTable holds the lightest way to hold every item total below limit in every
+// pack count below width
+type limitedTable struct {
+	limit int
+	width int
+	// weights[total*width+count] is the lightest way to hold exactly total items in count
+	// packs (-1 when impossible), last holds the pack size added last to reach it.
+	weights []int
+	last    []int
+}
+
+// newLimitedTable tabulates item totals below limit with dynamic programming. No more
+// packs than the limits allow are counted, and every state it evaluates costs one node
+// per pack size against the tracker's budget.
+func newLimitedTable(t *tracker, limit int, packSizes []int, specs specList, limits shipmentLimits) (*limitedTable, error) {
+	smallest := packSizes[len(packSizes)-1]
+
+	maxCount := (limit - 1) / smallest
+	if limits.maxParcels >= 0 {
+		maxCount = min(maxCount, limits.maxParcels)
+	}
+
+	// Every pack weighs at least as much as the lightest one
+	lightest := specs[smallest].GrossWeight
+	for _, packSize := range packSizes {
+		lightest = min(lightest, specs[packSize].GrossWeight)
+	}
+
+	if limits.maxWeight >= 0 && lightest > 0 {
+		maxCount = min(maxCount, limits.maxWeight/lightest)
+	}
+
+	width := maxCount + 1
+
+	if entries := limit * width; entries > maxLimitedTableEntries {
+		return nil, fmt.Errorf("%w: needs %d table entries, at most %d are allowed", ErrComputationBudgetExceeded, entries, maxLimitedTableEntries)
+	}
+
+	// Fail before allocating tables the budget could never fill
+	if err := t.fits(limit * width * len(packSizes)); err != nil {
+		return nil, err
+	}
+
+	table := &limitedTable{
+		limit:   limit,
+		width:   width,
+		weights: make([]int, limit*width),
+		last:    make([]int, limit*width),
+	}
+
+	for i := 1; i < len(table.weights); i++ {
+		table.weights[i] = -1
+	}
+
+	for total := 1; total < limit; total++ {
+		if err := t.spend(width * len(packSizes)); err != nil {
+			return nil, err
+		}
+
+		for count := 1; count < width; count++ {
+			at := total*width + count
+
+			for _, packSize := range packSizes {
+				if packSize > total {
+					continue
+				}
+
+				from := table.weights[(total-packSize)*width+count-1]
+				if from < 0 {
+					continue
+				}
+
+				weight := from + specs[packSize].GrossWeight
+				if table.weights[at] < 0 || weight < table.weights[at] {
+					table.weights[at] = weight
+					table.last[at] = packSize
+				}
+			}
+		}
+	}
+
+	return table, nil
+}
+
+// cover returns the combination in the table with the fewest items covering orderQty,
+// and among those the fewest packs, that stays within the limits. Every total it reads
+// costs one node per pack count against the tracker's budget.
+func (tb *limitedTable) cover(t *tracker, orderQty int, limits shipmentLimits) (PackCombination, bool, error) {
+	// Removing a pack from a combination that still covers the order afterwards only
+	// helps, so the first total covering the order within the limits is the best one,
+	// and its fewest packs within the limits are found first
+	for total := max(orderQty, 0); total < tb.limit; total++ {
+		if err := t.spend(tb.width); err != nil {
+			return PackCombination{}, false, err
+		}
+
+		for count := 0; count < tb.width; count++ {
+			if limits.maxParcels >= 0 && count > limits.maxParcels {
+				break
+			}
+
+			weight := tb.weights[total*tb.width+count]
+			if weight < 0 || (limits.maxWeight >= 0 && weight > limits.maxWeight) {
+				continue
+			}
+
+			packs := make(map[int]int)
+			for rest, n := total, count; n > 0; rest, n = rest-tb.last[rest*tb.width+n], n-1 {
+				packs[tb.last[rest*tb.width+n]]++
+			}
+
+			return PackCombination{Packs: packs, Total: total, PackCount: count}, true, nil
+		}
+	}
+
+	return PackCombination{}, false, nil
+}
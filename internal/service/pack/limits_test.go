@@ -0,0 +1,234 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// testSpecs weighs the default pack sizes at 1 g per item plus a heavier box for larger
+// packs, so 5000-packs are the heaviest per item
+func testSpecs() specList {
+	return specList{
+		5000: {Size: 5000, GrossWeight: 5600, Length: 60, Width: 40, Height: 40},
+		2000: {Size: 2000, GrossWeight: 2100, Length: 40, Width: 30, Height: 30},
+		1000: {Size: 1000, GrossWeight: 1050, Length: 30, Width: 30, Height: 20},
+		500:  {Size: 500, GrossWeight: 520, Length: 30, Width: 20, Height: 15},
+		250:  {Size: 250, GrossWeight: 260, Length: 20, Width: 15, Height: 15},
+	}
+}
+
+func TestCalculateLimitedPacks(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name          string
+		orderItemQty  int
+		limits        shipmentLimits
+		expectedPacks map[int]int
+		expectedErr   error
+		description   string
+	}{
+		{
+			name:          "Optimal packing fits",
+			orderItemQty:  12001,
+			limits:        shipmentLimits{maxWeight: -1, maxParcels: 4},
+			expectedPacks: map[int]int{5000: 2, 2000: 1, 250: 1},
+			description:   "Should keep the optimal packing when it fits the limits",
+		},
+		{
+			name:          "Lighter packs",
+			orderItemQty:  5000,
+			limits:        shipmentLimits{maxWeight: 5300, maxParcels: -1},
+			expectedPacks: map[int]int{2000: 2, 1000: 1},
+			description:   "Should swap a heavy pack for lighter ones holding the same items",
+		},
+		{
+			name:          "More items in fewer parcels",
+			orderItemQty:  1750,
+			limits:        shipmentLimits{maxWeight: -1, maxParcels: 1},
+			expectedPacks: map[int]int{2000: 1},
+			description:   "Should ship more items when the parcel cap rules out the optimal packing",
+		},
+		{
+			name:         "Nothing fits",
+			orderItemQty: 5000,
+			limits:       shipmentLimits{maxWeight: 5000, maxParcels: -1},
+			expectedErr:  ErrShipmentLimitExceeded,
+			description:  "Should fail when every combination is too heavy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calculateLimitedPacks(context.Background(), tt.orderItemQty, packSizes, testSpecs(), tt.limits, Budget{})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("calculateLimitedPacks() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if tt.expectedErr == nil && !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
+				t.Errorf("calculateLimitedPacks() packs = %v, want %v", result.Packs, tt.expectedPacks)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestShipmentLimitError(t *testing.T) {
+	_, err := calculateLimitedPacks(context.Background(), 5000, []int{5000, 2000, 1000, 500, 250}, testSpecs(),
+		shipmentLimits{maxWeight: 5000, maxParcels: 2}, Budget{})
+
+	var limitErr *ShipmentLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("calculateLimitedPacks() error = %v, want a *ShipmentLimitError", err)
+	}
+
+	if want := "shipment limit exceeded: no combination for 5000 items fits 5000 g in 2 parcels"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestCalculateLimitedPacksLargeOrders(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name          string
+		orderItemQty  int
+		limits        shipmentLimits
+		expectedPacks map[int]int
+		description   string
+	}{
+		{
+			name:          "Weight allows heavier packs",
+			orderItemQty:  10_000_000,
+			limits:        shipmentLimits{maxWeight: 10_500_000, maxParcels: -1},
+			expectedPacks: map[int]int{2000: 5000},
+			description:   "Should search the packs heavier per item than 500-packs within the weight limit",
+		},
+		{
+			name:          "Weight allows some heavier packs",
+			orderItemQty:  10_000_000,
+			limits:        shipmentLimits{maxWeight: 10_450_000, maxParcels: -1},
+			expectedPacks: map[int]int{2000: 2500, 500: 10000},
+			description:   "Should fill the rest with the lightest packs per item",
+		},
+		{
+			name:          "Weight and parcels",
+			orderItemQty:  1_000_001,
+			limits:        shipmentLimits{maxWeight: 1_100_000, maxParcels: 400},
+			expectedPacks: map[int]int{5000: 142, 2000: 145, 250: 1},
+			description:   "Should respect both limits on a large order",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calculateLimitedPacks(context.Background(), tt.orderItemQty, packSizes, testSpecs(), tt.limits, Budget{MaxNodes: 5_000_000})
+			if err != nil {
+				t.Fatalf("calculateLimitedPacks() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
+				t.Errorf("calculateLimitedPacks() packs = %v, want %v", result.Packs, tt.expectedPacks)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestCalculateLimitedPacksTableCap(t *testing.T) {
+	specs := specList{
+		1000: {Size: 1000, GrossWeight: 5000},
+		997:  {Size: 997, GrossWeight: 997},
+		991:  {Size: 991, GrossWeight: 991},
+	}
+
+	// 997-packs and 991-packs leave remainders up to about a million items, too many to
+	// tabulate even without a node budget
+	_, err := calculateLimitedPacks(context.Background(), 1000, []int{1000, 997, 991}, specs,
+		shipmentLimits{maxWeight: 4000, maxParcels: -1}, Budget{})
+	if !errors.Is(err, ErrComputationBudgetExceeded) {
+		t.Errorf("calculateLimitedPacks() error = %v, want %v", err, ErrComputationBudgetExceeded)
+	}
+}
+
+// bruteForceLimitedPacks tries every count of every pack size that can still matter and
+// returns the fewest items, then the fewest packs, covering the order within the limits,
+// or a total of -1 when nothing fits
+func bruteForceLimitedPacks(orderItemQty int, packSizes []int, specs specList, limits shipmentLimits) (bestTotal, bestCount int) {
+	bestTotal = -1
+
+	var try func(index, total, count, weight int)
+	try = func(index, total, count, weight int) {
+		if total >= orderItemQty {
+			fits := (limits.maxWeight < 0 || weight <= limits.maxWeight) && (limits.maxParcels < 0 || count <= limits.maxParcels)
+			if fits && (bestTotal < 0 || total < bestTotal || (total == bestTotal && count < bestCount)) {
+				bestTotal, bestCount = total, count
+			}
+
+			return
+		}
+
+		if index == len(packSizes) {
+			return
+		}
+
+		packSize := packSizes[index]
+		for n := 0; total+n*packSize < orderItemQty+packSize; n++ {
+			try(index+1, total+n*packSize, count+n, weight+n*specs[packSize].GrossWeight)
+		}
+	}
+
+	try(0, 0, 0, 0)
+
+	return bestTotal, bestCount
+}
+
+func TestCalculateLimitedPacksMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 3000; i++ {
+		packSizes := randomPackSizes(rng)
+		orderItemQty := 1 + rng.Intn(300)
+
+		specs := make(specList, len(packSizes))
+		for _, packSize := range packSizes {
+			specs[packSize] = model.PackSpec{Size: packSize, GrossWeight: packSize + rng.Intn(2*packSize)}
+		}
+
+		limits := shipmentLimits{maxWeight: -1, maxParcels: -1}
+		if rng.Intn(2) == 0 {
+			limits.maxWeight = orderItemQty + rng.Intn(2*orderItemQty)
+		}
+
+		if rng.Intn(2) == 0 {
+			limits.maxParcels = rng.Intn(10)
+		}
+
+		wantTotal, wantCount := bruteForceLimitedPacks(orderItemQty, packSizes, specs, limits)
+
+		result, err := calculateLimitedPacks(context.Background(), orderItemQty, packSizes, specs, limits, Budget{})
+		if wantTotal < 0 {
+			if !errors.Is(err, ErrShipmentLimitExceeded) {
+				t.Fatalf("calculateLimitedPacks(%d, %v, %+v) error = %v, want %v", orderItemQty, packSizes, limits, err, ErrShipmentLimitExceeded)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("calculateLimitedPacks(%d, %v, %+v) error = %v", orderItemQty, packSizes, limits, err)
+		}
+
+		if result.Total != wantTotal || result.PackCount != wantCount || !limits.allows(result.Packs, specs) {
+			t.Fatalf("calculateLimitedPacks(%d, %v, %v, %+v) = %v (total %d, packs %d), brute force gives total %d, packs %d",
+				orderItemQty, packSizes, specs, limits, result.Packs, result.Total, result.PackCount, wantTotal, wantCount)
+		}
+	}
+}
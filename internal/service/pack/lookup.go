@@ -0,0 +1,206 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+const (
+	// maxLookupEntries caps the orders a lookup table answers, pack sets with a larger
+	// exchange bound are solved per calculation instead
+	maxLookupEntries = 1 << 18
+	// maxLookupTables caps how many pack sets keep a lookup table at once
+	maxLookupTables = 16
+)
+
+// lookupTable holds the optimal combination of a pack set for every order up to its
+// exchange bound. Larger orders add the largest packs largestPackCount asks for, leaving
+// a remainder within the bound, so every order is answered without a search.
+type lookupTable struct {
+	packSizes []int
+	// cover[q] is the total of the optimal combination for an order of q items
+	cover []int32
+	// last[t] is the pack size added last to hold exactly t items in the fewest packs
+	last []int32
+}
+
+// newLookupTable builds the lookup table of a pack set sorted in descending order. It
+// reports false when the pack set's exchange bound holds more than maxLookupEntries orders.
+func newLookupTable(packSizes []int) (*lookupTable, bool) {
+	bound := exchangeBound(packSizes)
+	if bound > maxLookupEntries {
+		return nil, false
+	}
+
+	// As in findBestPackCombination, rounding an order up to a multiple of the smallest
+	// pack is always possible, so the best total is below bound+smallest.
+	limit := bound + packSizes[len(packSizes)-1]
+
+	counts := make([]int, limit)
+	last := make([]int32, limit)
+
+	for total := 1; total < limit; total++ {
+		counts[total] = -1
+
+		for _, packSize := range packSizes {
+			if packSize > total || counts[total-packSize] < 0 {
+				continue
+			}
+
+			if counts[total] < 0 || counts[total-packSize]+1 < counts[total] {
+				counts[total] = counts[total-packSize] + 1
+				last[total] = int32(packSize)
+			}
+		}
+	}
+
+	// The best total for an order is the first reachable total covering it
+	cover := make([]int32, bound+1)
+	next := limit - 1
+	for q := bound; q >= 0; q-- {
+		if counts[q] >= 0 {
+			next = q
+		}
+
+		cover[q] = int32(next)
+	}
+
+	return &lookupTable{packSizes: slices.Clone(packSizes), cover: cover, last: last}, true
+}
+
+// combination returns the optimal combination for an order of orderQty items, reporting
+// false when the order is beyond the table
+func (t *lookupTable) combination(orderQty int) (PackCombination, bool) {
+	if orderQty < 0 || orderQty >= len(t.cover) {
+		return PackCombination{}, false
+	}
+
+	total := int(t.cover[orderQty])
+
+	packs := make(map[int]int)
+	for rest := total; rest > 0; rest -= int(t.last[rest]) {
+		packs[int(t.last[rest])]++
+	}
+
+	return newPackCombination(packs), true
+}
+
+// lookupPacks finds the optimal pack combination like calculatePacks, reading the
+// remainder after the largest packs from table instead of searching for it. It falls
+// back to calculatePacks when table is nil or was built for another pack set.
+func lookupPacks(ctx context.Context, orderItemQty int, packSizes []int, table *lookupTable, budget Budget) (OptimalPacking, error) {
+	if table == nil || !slices.Equal(table.packSizes, packSizes) {
+		return calculatePacks(ctx, orderItemQty, packSizes, budget)
+	}
+
+	// Exact matches, orders below the smallest pack and orders the largest packs cover
+	// alone take no search
+	if orderItemQty < packSizes[len(packSizes)-1] || slices.Contains(packSizes, orderItemQty) {
+		return calculatePacks(ctx, orderItemQty, packSizes, budget)
+	}
+
+	count := largestPackCount(orderItemQty, packSizes)
+	remainder := orderItemQty - count*packSizes[0]
+	if remainder <= 0 {
+		return calculatePacks(ctx, orderItemQty, packSizes, budget)
+	}
+
+	best, ok := table.combination(remainder)
+	if !ok {
+		return calculatePacks(ctx, orderItemQty, packSizes, budget)
+	}
+
+	packs := best.Packs
+	if count > 0 {
+		packs[packSizes[0]] += count
+	}
+
+	return newOptimalPacking(packs, Trace{
+		Branch:       BranchOptimisedRemainder,
+		LargestPacks: count,
+		Remainder:    remainder,
+		Lookup:       true,
+	}), nil
+}
+
+// lookupTables caches the lookup tables of recently used pack sets by their sizes, so a
+// table never outlives the pack set it was built for. It is safe for concurrent use.
+type lookupTables struct {
+	mu     sync.Mutex
+	tables map[string]*lookupTable
+	// keys orders the cached pack sets from the least recently used
+	keys []string
+}
+
+// newLookupTables creates an empty lookup table cache
+func newLookupTables() *lookupTables {
+	return &lookupTables{tables: make(map[string]*lookupTable)}
+}
+
+// get returns the lookup table of a pack set, building it when it is not cached yet,
+// and marks it the most recently used. It returns nil when the pack set is too large
+// for a table.
+func (c *lookupTables) get(packSizes []int) *lookupTable {
+	key := fmt.Sprint(packSizes)
+
+	c.mu.Lock()
+	table, ok := c.tables[key]
+	if ok {
+		c.touch(key)
+	}
+	c.mu.Unlock()
+
+	if ok {
+		return table
+	}
+
+	return c.build(packSizes)
+}
+
+// build builds and caches the lookup table of a pack set, evicting the least recently
+// used one once maxLookupTables are cached. It returns nil when the pack set is empty
+// or too large for a table.
+func (c *lookupTables) build(packSizes []int) *lookupTable {
+	if len(packSizes) == 0 {
+		return nil
+	}
+
+	key := fmt.Sprint(packSizes)
+
+	// Building outside the lock keeps other calculations going, a pack set built twice
+	// at once just replaces an identical table
+	table, ok := newLookupTable(packSizes)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, cached := c.tables[key]; cached {
+		c.touch(key)
+	} else {
+		if len(c.keys) == maxLookupTables {
+			delete(c.tables, c.keys[0])
+			c.keys = c.keys[1:]
+		}
+
+		c.keys = append(c.keys, key)
+	}
+
+	// Pack sets too large for a table are cached as nil, so they are not retried
+	if !ok {
+		table = nil
+	}
+
+	c.tables[key] = table
+
+	return table
+}
+
+// touch moves a cached pack set to the most recently used end of keys. The caller must
+// hold the lock.
+func (c *lookupTables) touch(key string) {
+	if i := slices.Index(c.keys, key); i >= 0 {
+		c.keys = append(slices.Delete(c.keys, i, i+1), key)
+	}
+}
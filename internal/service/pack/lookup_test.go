@@ -0,0 +1,147 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestLookupPacksMatchesCalculatePacks(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 3000; i++ {
+		packSizes := randomPackSizes(rng)
+		orderItemQty := 1 + rng.Intn(20000)
+
+		table, ok := newLookupTable(packSizes)
+		if !ok {
+			t.Fatalf("newLookupTable(%v) found the set too large", packSizes)
+		}
+
+		want, err := calculatePacks(context.Background(), orderItemQty, packSizes, Budget{})
+		if err != nil {
+			t.Fatalf("calculatePacks(%d, %v) error = %v", orderItemQty, packSizes, err)
+		}
+
+		got, err := lookupPacks(context.Background(), orderItemQty, packSizes, table, Budget{})
+		if err != nil {
+			t.Fatalf("lookupPacks(%d, %v) error = %v", orderItemQty, packSizes, err)
+		}
+
+		if !reflect.DeepEqual(got.Packs, want.Packs) || got.Trace.Branch != want.Trace.Branch {
+			t.Fatalf("lookupPacks(%d, %v) = %v (%s), calculatePacks gives %v (%s)",
+				orderItemQty, packSizes, got.Packs, got.Trace.Branch, want.Packs, want.Trace.Branch)
+		}
+
+		if got.Trace.Branch == BranchOptimisedRemainder && (!got.Trace.Lookup || got.Trace.NodesExplored != 0) {
+			t.Fatalf("lookupPacks(%d, %v) trace = %+v, want a lookup without a search", orderItemQty, packSizes, got.Trace)
+		}
+	}
+}
+
+func TestLookupPacksFallsBack(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name        string
+		table       *lookupTable
+		description string
+	}{
+		{
+			name:        "No table",
+			description: "Should search when the pack set has no table",
+		},
+		{
+			name:        "Table of another pack set",
+			table:       mustLookupTable(t, []int{5000, 2000, 1000, 500}),
+			description: "Should search when the table was built for another pack set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := lookupPacks(context.Background(), 12001, packSizes, tt.table, Budget{})
+			if err != nil {
+				t.Fatalf("lookupPacks() error = %v", err)
+			}
+
+			if result.Trace.Lookup || result.Trace.NodesExplored == 0 {
+				t.Errorf("lookupPacks() trace = %+v, want a search", result.Trace)
+			}
+
+			expected := map[int]int{5000: 2, 2000: 1, 250: 1}
+			if !reflect.DeepEqual(result.Packs, expected) {
+				t.Errorf("lookupPacks() packs = %v, want %v", result.Packs, expected)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestNewLookupTableTooLarge(t *testing.T) {
+	if _, ok := newLookupTable([]int{999983, 999979, 999961}); ok {
+		t.Error("newLookupTable() built a table past maxLookupEntries")
+	}
+}
+
+func TestLookupTablesEviction(t *testing.T) {
+	tables := newLookupTables()
+
+	first := tables.get([]int{60, 25})
+	if first == nil {
+		t.Fatal("get() = nil, want a table")
+	}
+
+	if tables.get([]int{60, 25}) != first {
+		t.Error("get() rebuilt a cached table")
+	}
+
+	for size := 1; size <= maxLookupTables; size++ {
+		tables.build([]int{100 + size, size})
+	}
+
+	if len(tables.tables) != maxLookupTables || len(tables.keys) != maxLookupTables {
+		t.Fatalf("cache holds %d tables and %d keys, want %d", len(tables.tables), len(tables.keys), maxLookupTables)
+	}
+
+	if _, ok := tables.tables[fmt.Sprint([]int{60, 25})]; ok {
+		t.Error("build() kept the oldest table past maxLookupTables")
+	}
+}
+
+func TestLookupTablesKeepRecentlyUsed(t *testing.T) {
+	tables := newLookupTables()
+	hot := []int{60, 25}
+
+	tables.get(hot)
+	for size := 1; size < maxLookupTables; size++ {
+		tables.get([]int{100 + size, size})
+	}
+
+	// Using the oldest table keeps it over the ones built after it
+	tables.get(hot)
+	tables.get([]int{200, 7})
+
+	if _, ok := tables.tables[fmt.Sprint(hot)]; !ok {
+		t.Error("get() evicted a recently used table")
+	}
+
+	if _, ok := tables.tables[fmt.Sprint([]int{101, 1})]; ok {
+		t.Error("get() kept the least recently used table past maxLookupTables")
+	}
+}
+
+// mustLookupTable builds the lookup table of a pack set, failing the test when it is too large
+func mustLookupTable(t *testing.T, packSizes []int) *lookupTable {
+	t.Helper()
+
+	table, ok := newLookupTable(packSizes)
+	if !ok {
+		t.Fatalf("newLookupTable(%v) found the set too large", packSizes)
+	}
+
+	return table
+}
@@ -0,0 +1,266 @@
+package pack
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// validatePackaging checks that every carton and the pallet are named and hold something,
+// and that cartons only take sizes of the pack set
+func validatePackaging(packaging model.Packaging, packSizes []int) error {
+	if len(packaging.Cartons) == 0 {
+		return fmt.Errorf("%w: at least one carton type is needed", ErrInvalidPackaging)
+	}
+
+	names := make(map[string]struct{}, len(packaging.Cartons))
+	for _, carton := range packaging.Cartons {
+		if carton.Name == "" {
+			return fmt.Errorf("%w: every carton type needs a name", ErrInvalidPackaging)
+		}
+
+		if _, ok := names[carton.Name]; ok {
+			return fmt.Errorf("%w: carton type %q appears twice", ErrInvalidPackaging, carton.Name)
+		}
+
+		if carton.Capacity < 1 || len(carton.PackSizes) == 0 {
+			return fmt.Errorf("%w: carton type %q must hold at least one pack", ErrInvalidPackaging, carton.Name)
+		}
+
+		for i, packSize := range carton.PackSizes {
+			if slices.Contains(carton.PackSizes[:i], packSize) {
+				return fmt.Errorf("%w: carton type %q lists %d twice", ErrInvalidPackaging, carton.Name, packSize)
+			}
+
+			if !slices.Contains(packSizes, packSize) {
+				return fmt.Errorf("%w: %d", ErrNotFoundPackSize, packSize)
+			}
+		}
+
+		names[carton.Name] = struct{}{}
+	}
+
+	if pallet := packaging.Pallet; pallet != nil && (pallet.Name == "" || pallet.Capacity < 1) {
+		return fmt.Errorf("%w: the pallet type needs a name and must hold at least one carton", ErrInvalidPackaging)
+	}
+
+	return nil
+}
+
+// buildShipment puts packs into cartons and cartons onto pallets. Packs no carton type
+// takes ship loose, as do cartons when there is no pallet type.
+func buildShipment(packs map[int]int, packaging model.Packaging) Shipment {
+	cartons, loose := fillCartons(packs, packaging.Cartons)
+
+	shipment := Shipment{
+		Pallets: []PalletLoad{},
+		Cartons: []CartonLoad{},
+		Packs:   newPackList(loose),
+	}
+
+	for _, carton := range cartons {
+		shipment.CartonCount += carton.Count
+	}
+
+	if packaging.Pallet == nil {
+		shipment.Cartons = cartons
+		return shipment
+	}
+
+	shipment.Pallets = loadPallets(cartons, *packaging.Pallet)
+	for _, pallet := range shipment.Pallets {
+		shipment.PalletCount += pallet.Count
+	}
+
+	return shipment
+}
+
+// fillCartons fills cartons starting with the largest pack left. Its carton is the
+// smallest type taking every pack left that it accepts, or the largest type accepting it
+// when none does. A carton takes the largest packs it accepts first. Runs of identical
+// cartons are filled at once and merged, and packs no carton type takes are returned.
+func fillCartons(packs map[int]int, cartons []model.CartonType) ([]CartonLoad, map[int]int) {
+	remaining := copyPacks(packs)
+	loose := make(map[int]int)
+	filled := []CartonLoad{}
+
+	for _, line := range newPackList(packs) {
+		for remaining[line.Size] > 0 {
+			carton, ok := chooseCarton(line.Size, remaining, cartons)
+			if !ok {
+				loose[line.Size] = remaining[line.Size]
+				delete(remaining, line.Size)
+
+				break
+			}
+
+			load := CartonLoad{Carton: carton.Name, Count: 1, Packs: []PackLine{}}
+
+			// Cartons holding a full carton of the largest pack alone repeat until the
+			// choice of type changes, so a run of them is filled at once
+			if run := fullCartons(line.Size, carton, remaining, cartons); run > 0 {
+				load.Count = run
+				load.Packs = append(load.Packs, PackLine{Size: line.Size, Count: carton.Capacity})
+				remaining[line.Size] -= run * carton.Capacity
+			} else {
+				space := carton.Capacity
+				for _, rest := range newPackList(remaining) {
+					if space == 0 {
+						break
+					}
+
+					if !slices.Contains(carton.PackSizes, rest.Size) {
+						continue
+					}
+
+					n := min(rest.Count, space)
+					load.Packs = append(load.Packs, PackLine{Size: rest.Size, Count: n})
+					remaining[rest.Size] -= n
+					space -= n
+				}
+			}
+
+			if last := len(filled) - 1; last >= 0 && sameCartonLoad(filled[last], load) {
+				filled[last].Count += load.Count
+				continue
+			}
+
+			filled = append(filled, load)
+		}
+	}
+
+	return filled, loose
+}
+
+// fullCartons returns how many cartons in a row of the type chosen for a pack of
+// packSize are filled with that size alone, or 0 when the next carton is not. Each one
+// takes a full carton of packSize, and the type stays the choice until the packs left
+// run short of a full carton or fit into some type accepting packSize.
+func fullCartons(packSize int, carton model.CartonType, remaining map[int]int, cartons []model.CartonType) int {
+	run := remaining[packSize] / carton.Capacity
+
+	for _, other := range cartons {
+		if run == 0 {
+			break
+		}
+
+		if !slices.Contains(other.PackSizes, packSize) {
+			continue
+		}
+
+		accepted := 0
+		for _, size := range other.PackSizes {
+			accepted += remaining[size]
+		}
+
+		// A type already fitting is the choice for this carton only
+		over := accepted - other.Capacity
+		if over <= 0 {
+			run = min(run, 1)
+			continue
+		}
+
+		run = min(run, (over+carton.Capacity-1)/carton.Capacity)
+	}
+
+	return run
+}
+
+// chooseCarton returns the carton type for a carton starting with a pack of packSize
+func chooseCarton(packSize int, remaining map[int]int, cartons []model.CartonType) (model.CartonType, bool) {
+	var (
+		largest, fitting       model.CartonType
+		foundLargest, foundFit bool
+	)
+
+	for _, carton := range cartons {
+		if !slices.Contains(carton.PackSizes, packSize) {
+			continue
+		}
+
+		if !foundLargest || carton.Capacity > largest.Capacity {
+			largest, foundLargest = carton, true
+		}
+
+		accepted := 0
+		for _, size := range carton.PackSizes {
+			accepted += remaining[size]
+		}
+
+		if accepted <= carton.Capacity && (!foundFit || carton.Capacity < fitting.Capacity) {
+			fitting, foundFit = carton, true
+		}
+	}
+
+	if foundFit {
+		return fitting, true
+	}
+
+	return largest, foundLargest
+}
+
+// loadPallets stacks cartons onto pallets in order, each pallet holding up to the pallet
+// type's capacity. Runs of identical pallets are merged.
+func loadPallets(cartons []CartonLoad, pallet model.PalletType) []PalletLoad {
+	pallets := []PalletLoad{}
+
+	var (
+		current []CartonLoad
+		space   = pallet.Capacity
+	)
+
+	push := func(load PalletLoad) {
+		if last := len(pallets) - 1; last >= 0 && slices.EqualFunc(pallets[last].Cartons, load.Cartons, sameCartons) {
+			pallets[last].Count += load.Count
+			return
+		}
+
+		pallets = append(pallets, load)
+	}
+
+	for _, group := range cartons {
+		for n := group.Count; n > 0; {
+			// Cartons filling whole pallets on their own are stacked at once
+			if space == pallet.Capacity && n >= pallet.Capacity {
+				full := n / pallet.Capacity
+				push(PalletLoad{Pallet: pallet.Name, Count: full, Cartons: []CartonLoad{withCount(group, pallet.Capacity)}})
+				n -= full * pallet.Capacity
+
+				continue
+			}
+
+			take := min(n, space)
+			current = append(current, withCount(group, take))
+			space -= take
+			n -= take
+
+			if space == 0 {
+				push(PalletLoad{Pallet: pallet.Name, Count: 1, Cartons: current})
+				current, space = nil, pallet.Capacity
+			}
+		}
+	}
+
+	if len(current) > 0 {
+		push(PalletLoad{Pallet: pallet.Name, Count: 1, Cartons: current})
+	}
+
+	return pallets
+}
+
+// withCount returns a copy of a carton load standing for count cartons
+func withCount(load CartonLoad, count int) CartonLoad {
+	load.Count = count
+	return load
+}
+
+// sameCartonLoad reports whether a and b are cartons of the same type holding the same packs
+func sameCartonLoad(a, b CartonLoad) bool {
+	return a.Carton == b.Carton && slices.Equal(a.Packs, b.Packs)
+}
+
+// sameCartons reports whether a and b stand for the same number of identical cartons
+func sameCartons(a, b CartonLoad) bool {
+	return a.Count == b.Count && sameCartonLoad(a, b)
+}
@@ -0,0 +1,176 @@
+package pack
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// testPackaging takes small packs four to a carton and large ones two to a carton, leaving
+// 5000-packs loose
+func testPackaging(palletCapacity int) model.Packaging {
+	packaging := model.Packaging{
+		Cartons: []model.CartonType{
+			{Name: "small", PackSizes: []int{500, 250}, Capacity: 4},
+			{Name: "large", PackSizes: []int{2000, 1000}, Capacity: 2},
+		},
+	}
+
+	if palletCapacity > 0 {
+		packaging.Pallet = &model.PalletType{Name: "euro", Capacity: palletCapacity}
+	}
+
+	return packaging
+}
+
+func TestBuildShipment(t *testing.T) {
+	tests := []struct {
+		name             string
+		packs            map[int]int
+		packaging        model.Packaging
+		expectedShipment Shipment
+		description      string
+	}{
+		{
+			name:      "Mixed cartons on pallets",
+			packs:     map[int]int{5000: 1, 2000: 3, 1000: 2, 500: 5, 250: 1},
+			packaging: testPackaging(3),
+			expectedShipment: Shipment{
+				PalletCount: 2,
+				CartonCount: 5,
+				Pallets: []PalletLoad{
+					{Pallet: "euro", Count: 1, Cartons: []CartonLoad{
+						{Carton: "large", Count: 1, Packs: []PackLine{{Size: 2000, Count: 2}}},
+						{Carton: "large", Count: 1, Packs: []PackLine{{Size: 2000, Count: 1}, {Size: 1000, Count: 1}}},
+						{Carton: "large", Count: 1, Packs: []PackLine{{Size: 1000, Count: 1}}},
+					}},
+					{Pallet: "euro", Count: 1, Cartons: []CartonLoad{
+						{Carton: "small", Count: 1, Packs: []PackLine{{Size: 500, Count: 4}}},
+						{Carton: "small", Count: 1, Packs: []PackLine{{Size: 500, Count: 1}, {Size: 250, Count: 1}}},
+					}},
+				},
+				Cartons: []CartonLoad{},
+				Packs:   []PackLine{{Size: 5000, Count: 1}},
+			},
+			description: "Should fill cartons largest packs first, stack them in order and leave untaken packs loose",
+		},
+		{
+			name:      "Identical pallets merged",
+			packs:     map[int]int{250: 9},
+			packaging: testPackaging(1),
+			expectedShipment: Shipment{
+				PalletCount: 3,
+				CartonCount: 3,
+				Pallets: []PalletLoad{
+					{Pallet: "euro", Count: 2, Cartons: []CartonLoad{{Carton: "small", Count: 1, Packs: []PackLine{{Size: 250, Count: 4}}}}},
+					{Pallet: "euro", Count: 1, Cartons: []CartonLoad{{Carton: "small", Count: 1, Packs: []PackLine{{Size: 250, Count: 1}}}}},
+				},
+				Cartons: []CartonLoad{},
+				Packs:   []PackLine{},
+			},
+			description: "Should report runs of identical pallets once with their count",
+		},
+		{
+			name:      "Without a pallet type",
+			packs:     map[int]int{250: 9},
+			packaging: testPackaging(0),
+			expectedShipment: Shipment{
+				CartonCount: 3,
+				Pallets:     []PalletLoad{},
+				Cartons: []CartonLoad{
+					{Carton: "small", Count: 2, Packs: []PackLine{{Size: 250, Count: 4}}},
+					{Carton: "small", Count: 1, Packs: []PackLine{{Size: 250, Count: 1}}},
+				},
+				Packs: []PackLine{},
+			},
+			description: "Should ship cartons on their own when there is no pallet type",
+		},
+		{
+			name:      "Many identical cartons",
+			packs:     map[int]int{250: 4_000_000_001},
+			packaging: testPackaging(0),
+			expectedShipment: Shipment{
+				CartonCount: 1_000_000_001,
+				Pallets:     []PalletLoad{},
+				Cartons: []CartonLoad{
+					{Carton: "small", Count: 1_000_000_000, Packs: []PackLine{{Size: 250, Count: 4}}},
+					{Carton: "small", Count: 1, Packs: []PackLine{{Size: 250, Count: 1}}},
+				},
+				Packs: []PackLine{},
+			},
+			description: "Should fill a run of identical cartons at once however long it is",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shipment := buildShipment(tt.packs, tt.packaging)
+			if !reflect.DeepEqual(shipment, tt.expectedShipment) {
+				t.Errorf("buildShipment() = %+v, want %+v", shipment, tt.expectedShipment)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestValidatePackaging(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name        string
+		packaging   model.Packaging
+		expectedErr error
+	}{
+		{
+			name:      "Valid",
+			packaging: testPackaging(3),
+		},
+		{
+			name:        "No cartons",
+			packaging:   model.Packaging{},
+			expectedErr: ErrInvalidPackaging,
+		},
+		{
+			name:        "Unnamed carton",
+			packaging:   model.Packaging{Cartons: []model.CartonType{{PackSizes: []int{250}, Capacity: 1}}},
+			expectedErr: ErrInvalidPackaging,
+		},
+		{
+			name: "Repeated carton",
+			packaging: model.Packaging{Cartons: []model.CartonType{
+				{Name: "box", PackSizes: []int{250}, Capacity: 1},
+				{Name: "box", PackSizes: []int{500}, Capacity: 1},
+			}},
+			expectedErr: ErrInvalidPackaging,
+		},
+		{
+			name:        "Empty carton",
+			packaging:   model.Packaging{Cartons: []model.CartonType{{Name: "box", PackSizes: []int{250}}}},
+			expectedErr: ErrInvalidPackaging,
+		},
+		{
+			name:        "Unknown size",
+			packaging:   model.Packaging{Cartons: []model.CartonType{{Name: "box", PackSizes: []int{750}, Capacity: 1}}},
+			expectedErr: ErrNotFoundPackSize,
+		},
+		{
+			name: "Empty pallet",
+			packaging: model.Packaging{
+				Cartons: []model.CartonType{{Name: "box", PackSizes: []int{250}, Capacity: 1}},
+				Pallet:  &model.PalletType{Name: "euro"},
+			},
+			expectedErr: ErrInvalidPackaging,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePackaging(tt.packaging, packSizes); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("validatePackaging() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// Criterion is one way of comparing two pack combinations
+type Criterion string
+
+const (
+	// CriterionItems prefers the combination shipping the fewest items
+	CriterionItems Criterion = "items"
+	// CriterionPacks prefers the combination using the fewest packs
+	CriterionPacks Criterion = "packs"
+	// CriterionSizes prefers the combination using the fewest distinct pack sizes
+	CriterionSizes Criterion = "sizes"
+	// CriterionLargest prefers the combination holding more of the larger pack sizes
+	CriterionLargest Criterion = "largest"
+)
+
+// Ranking orders pack combinations by each of its criteria in turn. Combinations tied
+// on every criterion are ordered by the largest criterion, so rankings are total.
+type Ranking []Criterion
+
+// defaultRanking ranks combinations by fewest items, then fewest packs, then larger packs
+var defaultRanking = Ranking{CriterionItems, CriterionPacks, CriterionLargest}
+
+// validate checks that every criterion is known and appears once
+func (r Ranking) validate() error {
+	seen := make(map[Criterion]bool, len(r))
+	for _, criterion := range r {
+		switch criterion {
+		case CriterionItems, CriterionPacks, CriterionSizes, CriterionLargest:
+		default:
+			return fmt.Errorf("%w: unknown criterion %q", ErrInvalidRanking, criterion)
+		}
+
+		if seen[criterion] {
+			return fmt.Errorf("%w: %q appears twice", ErrInvalidRanking, criterion)
+		}
+
+		seen[criterion] = true
+	}
+
+	return nil
+}
+
+// orDefault returns the ranking, or the default one when it is empty
+func (r Ranking) orDefault() Ranking {
+	if len(r) == 0 {
+		return defaultRanking
+	}
+
+	return r
+}
+
+// isDefault reports whether the ranking orders combinations the way the default one does
+func (r Ranking) isDefault() bool {
+	r = r.orDefault()
+	if r[len(r)-1] != CriterionLargest {
+		r = append(slices.Clone(r), CriterionLargest)
+	}
+
+	return slices.Equal(r, defaultRanking)
+}
+
+// before reports whether a ranks before b
+func (r Ranking) before(a, b PackCombination, packSizes []int) bool {
+	for _, criterion := range r.orDefault() {
+		if c := compareBy(criterion, a, b, packSizes); c != 0 {
+			return c < 0
+		}
+	}
+
+	return compareBy(CriterionLargest, a, b, packSizes) < 0
+}
+
+// compareBy compares a and b by a single criterion, returning a negative number when a
+// ranks before b, a positive one when b ranks before a and zero on a tie
+func compareBy(criterion Criterion, a, b PackCombination, packSizes []int) int {
+	switch criterion {
+	case CriterionItems:
+		return a.Total - b.Total
+	case CriterionPacks:
+		return a.PackCount - b.PackCount
+	case CriterionSizes:
+		return len(copyPacks(a.Packs)) - len(copyPacks(b.Packs))
+	case CriterionLargest:
+		for _, packSize := range packSizes {
+			if a.Packs[packSize] != b.Packs[packSize] {
+				return b.Packs[packSize] - a.Packs[packSize]
+			}
+		}
+	}
+
+	return 0
+}
+
+// selection picks the combination a calculation returns: the first by ranking among
+// those overshooting the order by at most maxOvershoot items, or the closest to the
+// order among those falling at most maxShortfall items short of it too
+type selection struct {
+	ranking Ranking
+	// maxOvershoot caps the surplus items, negative means unlimited
+	maxOvershoot int
+	// maxShortfall caps the items missing from the order, zero means none may be
+	maxShortfall int
+	// constraints bounds the packs of each size, none bounding nothing
+	constraints model.PackConstraints
+}
+
+// defaultSelection ranks combinations by default without capping the overshoot
+var defaultSelection = selection{ranking: defaultRanking, maxOvershoot: -1}
+
+// allows reports whether c overshoots an order of orderQty items by no more than allowed
+func (s selection) allows(c PackCombination, orderQty int) bool {
+	return s.maxOvershoot < 0 || c.Total-orderQty <= s.maxOvershoot
+}
+
+// checkOvershoot returns an error wrapping ErrOvershootExceeded when the packing
+// overshoots an order of orderQty items by more than allowed
+func (s selection) checkOvershoot(packing OptimalPacking, orderQty int) error {
+	if s.allows(newPackCombination(packing.Packs), orderQty) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: the best combination ships %d surplus items, at most %d are allowed",
+		ErrOvershootExceeded, packing.Total-orderQty, s.maxOvershoot)
+}
+
+// maxSizesRankedPackSizes caps the pack sizes a ranking may compare by the sizes
+// criterion after another one, as every subset of them is searched
+const maxSizesRankedPackSizes = 10
+
+// findRankedPacks finds the combination covering an order that ranks first by a custom
+// ranking. Only combinations in which no pack could be removed with the order still
+// covered are ranked. It stops with ErrComputationBudgetExceeded once the budget runs
+// out or ctx is done, and returns an error wrapping ErrOvershootExceeded when every
+// combination overshoots the order by more than allowed.
+//
+// Swapping packs of a smaller size for the same items in largest packs saves packs and
+// holds more of the larger sizes, so as in calculatePacks the first ranked combination
+// holds the largest packs largestPackCount asks for. Only the sizes criterion can prefer
+// the combination before the swap, so with it every subset of the sizes is searched on
+// its own, each with its own largest pack. Subsets are searched from the smallest, so
+// when sizes is the first criterion the search stops after the subsets as large as the
+// sizes the best combination so far uses. Otherwise every subset is searched, and pack
+// sets larger than maxSizesRankedPackSizes fail with ErrInvalidRanking.
+func findRankedPacks(ctx context.Context, orderItemQty int, packSizes []int, sel selection, budget Budget) (OptimalPacking, error) {
+	if len(packSizes) == 0 {
+		return OptimalPacking{}, ErrNoPackSizesConfigured
+	}
+
+	bySizes := slices.Contains(sel.ranking, CriterionSizes)
+	leading := bySizes && sel.ranking[0] == CriterionSizes
+	if bySizes && !leading && len(packSizes) > maxSizesRankedPackSizes {
+		return OptimalPacking{}, fmt.Errorf("%w: ranking by %q after another criterion allows at most %d pack sizes, the set has %d",
+			ErrInvalidRanking, CriterionSizes, maxSizesRankedPackSizes, len(packSizes))
+	}
+
+	ctx, cancel := withBudgetTimeout(ctx, budget)
+	defer cancel()
+
+	t := newTracker(ctx, budget)
+
+	var (
+		best  PackCombination
+		found bool
+	)
+
+	// Without the sizes criterion only the set of all sizes is searched
+	all := 1<<len(packSizes) - 1
+	smallest := len(packSizes)
+	if bySizes {
+		smallest = 1
+	}
+
+	for k := smallest; k <= len(packSizes); k++ {
+		// Larger subsets only add combinations using more sizes than the best one
+		if leading && found && k > len(copyPacks(best.Packs)) {
+			break
+		}
+
+		for mask := 1<<k - 1; mask <= all; mask = nextSubset(mask) {
+			sizes := subsetOf(packSizes, mask)
+			largest := sizes[0]
+			fixed := largestPackCount(orderItemQty, sizes)
+
+			err := enumerateCombinations(t, orderItemQty-fixed*largest, sizes, func(c PackCombination) {
+				if fixed > 0 {
+					c.Packs[largest] += fixed
+					c.Total += fixed * largest
+					c.PackCount += fixed
+				}
+
+				if !sel.allows(c, orderItemQty) {
+					return
+				}
+
+				if !found || sel.ranking.before(c, best, packSizes) {
+					best, found = c, true
+				}
+			})
+			if err != nil {
+				return OptimalPacking{}, err
+			}
+		}
+	}
+
+	if !found {
+		return OptimalPacking{}, fmt.Errorf("%w: every combination ships more than %d surplus items", ErrOvershootExceeded, sel.maxOvershoot)
+	}
+
+	return newOptimalPacking(best.Packs, Trace{
+		Branch:        BranchRanked,
+		LargestPacks:  best.Packs[packSizes[0]],
+		NodesExplored: t.nodes,
+	}), nil
+}
+
+// subsetOf returns the pack sizes whose bit is set in mask, keeping their order
+func subsetOf(packSizes []int, mask int) []int {
+	var subset []int
+	for i, packSize := range packSizes {
+		if mask&(1<<i) != 0 {
+			subset = append(subset, packSize)
+		}
+	}
+
+	return subset
+}
+
+// nextSubset returns the next larger mask with as many bits set as mask
+func nextSubset(mask int) int {
+	low := mask & -mask
+	ripple := mask + low
+
+	return ((ripple^mask)>>2)/low | ripple
+}
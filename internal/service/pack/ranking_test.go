@@ -0,0 +1,210 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestFindRankedPacks(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	// Twenty sizes from 5000 down to 250 in steps of 250
+	manySizes := make([]int, 0, 20)
+	for packSize := 5000; packSize > 0; packSize -= 250 {
+		manySizes = append(manySizes, packSize)
+	}
+
+	tests := []struct {
+		name          string
+		packSizes     []int
+		orderItemQty  int
+		sel           selection
+		expectedPacks map[int]int
+		expectedErr   error
+		description   string
+	}{
+		{
+			name:          "Fewer packs before fewer items",
+			packSizes:     packSizes,
+			orderItemQty:  1250,
+			sel:           selection{ranking: Ranking{CriterionPacks, CriterionItems}, maxOvershoot: -1},
+			expectedPacks: map[int]int{2000: 1},
+			description:   "Should overshoot into a single pack when packs rank before items",
+		},
+		{
+			name:          "Fewer sizes before fewer items",
+			packSizes:     packSizes,
+			orderItemQty:  750,
+			sel:           selection{ranking: Ranking{CriterionSizes, CriterionItems}, maxOvershoot: -1},
+			expectedPacks: map[int]int{250: 3},
+			description:   "Should pick from a single size, then ship the fewest items",
+		},
+		{
+			name:          "Largest packs first",
+			packSizes:     packSizes,
+			orderItemQty:  251,
+			sel:           selection{ranking: Ranking{CriterionLargest}, maxOvershoot: -1},
+			expectedPacks: map[int]int{5000: 1},
+			description:   "Should prefer the largest pack whatever it overshoots",
+		},
+		{
+			name:          "Overshoot cap",
+			packSizes:     packSizes,
+			orderItemQty:  1250,
+			sel:           selection{ranking: Ranking{CriterionPacks}, maxOvershoot: 250},
+			expectedPacks: map[int]int{1000: 1, 500: 1},
+			description:   "Should skip combinations overshooting the cap and break ties by larger packs",
+		},
+		{
+			name:          "Fewer sizes first with many sizes",
+			packSizes:     manySizes,
+			orderItemQty:  12345,
+			sel:           selection{ranking: Ranking{CriterionSizes, CriterionItems}, maxOvershoot: -1},
+			expectedPacks: map[int]int{2500: 5},
+			description:   "Should stop after the single sizes rather than search every subset",
+		},
+		{
+			name:         "Fewer sizes later with many sizes",
+			packSizes:    manySizes,
+			orderItemQty: 12345,
+			sel:          selection{ranking: Ranking{CriterionItems, CriterionSizes}, maxOvershoot: -1},
+			expectedErr:  ErrInvalidRanking,
+			description:  "Should reject ranking by sizes after another criterion over too many sizes",
+		},
+		{
+			name:         "Overshoot cap exceeded",
+			packSizes:    []int{500},
+			orderItemQty: 251,
+			sel:          selection{ranking: Ranking{CriterionPacks}, maxOvershoot: 0},
+			expectedErr:  ErrOvershootExceeded,
+			description:  "Should fail when every combination overshoots the cap",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := findRankedPacks(context.Background(), tt.orderItemQty, tt.packSizes, tt.sel, Budget{MaxNodes: 5_000_000})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("findRankedPacks() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if tt.expectedErr == nil && !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
+				t.Errorf("findRankedPacks() packs = %v, want %v", result.Packs, tt.expectedPacks)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestRankingIsDefault(t *testing.T) {
+	tests := []struct {
+		ranking  Ranking
+		expected bool
+	}{
+		{ranking: nil, expected: true},
+		{ranking: Ranking{CriterionItems, CriterionPacks}, expected: true},
+		{ranking: Ranking{CriterionItems, CriterionPacks, CriterionLargest}, expected: true},
+		{ranking: Ranking{CriterionItems}, expected: false},
+		{ranking: Ranking{CriterionPacks, CriterionItems}, expected: false},
+		{ranking: Ranking{CriterionItems, CriterionPacks, CriterionSizes}, expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.ranking.isDefault(); got != tt.expected {
+			t.Errorf("%v.isDefault() = %v, want %v", tt.ranking, got, tt.expected)
+		}
+	}
+}
+
+// bruteForceRankedPacks tries every count of every pack size that can still matter and
+// returns the combination ranking first among those no pack could be removed from,
+// reporting false when none stays within the overshoot cap
+func bruteForceRankedPacks(orderItemQty int, packSizes []int, sel selection) (PackCombination, bool) {
+	var (
+		best  PackCombination
+		found bool
+	)
+
+	packs := make(map[int]int)
+
+	var try func(index, total int)
+	try = func(index, total int) {
+		if index == len(packSizes) {
+			if total < orderItemQty {
+				return
+			}
+
+			for packSize, n := range packs {
+				if n > 0 && total-packSize >= orderItemQty {
+					return
+				}
+			}
+
+			c := newPackCombination(copyPacks(packs))
+			if sel.allows(c, orderItemQty) && (!found || sel.ranking.before(c, best, packSizes)) {
+				best, found = c, true
+			}
+
+			return
+		}
+
+		packSize := packSizes[index]
+		for n := 0; n == 0 || total+n*packSize < orderItemQty+packSize; n++ {
+			packs[packSize] = n
+			try(index+1, total+n*packSize)
+		}
+
+		delete(packs, packSize)
+	}
+
+	try(0, 0)
+
+	return best, found
+}
+
+// randomRanking returns between one and four distinct criteria in random order
+func randomRanking(rng *rand.Rand) Ranking {
+	criteria := []Criterion{CriterionItems, CriterionPacks, CriterionSizes, CriterionLargest}
+	rng.Shuffle(len(criteria), func(i, j int) {
+		criteria[i], criteria[j] = criteria[j], criteria[i]
+	})
+
+	return Ranking(criteria[:1+rng.Intn(len(criteria))])
+}
+
+func TestFindRankedPacksMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 3000; i++ {
+		packSizes := randomPackSizes(rng)
+		orderItemQty := 1 + rng.Intn(200)
+
+		sel := selection{ranking: randomRanking(rng), maxOvershoot: -1}
+		if rng.Intn(2) == 0 {
+			sel.maxOvershoot = rng.Intn(30)
+		}
+
+		want, ok := bruteForceRankedPacks(orderItemQty, packSizes, sel)
+
+		result, err := findRankedPacks(context.Background(), orderItemQty, packSizes, sel, Budget{})
+		if !ok {
+			if !errors.Is(err, ErrOvershootExceeded) {
+				t.Fatalf("findRankedPacks(%d, %v, %+v) error = %v, want %v", orderItemQty, packSizes, sel, err, ErrOvershootExceeded)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("findRankedPacks(%d, %v, %+v) error = %v", orderItemQty, packSizes, sel, err)
+		}
+
+		if !reflect.DeepEqual(result.Packs, want.Packs) {
+			t.Fatalf("findRankedPacks(%d, %v, %+v) = %v, brute force gives %v", orderItemQty, packSizes, sel, result.Packs, want.Packs)
+		}
+	}
+}
@@ -1,16 +1,199 @@
 package pack
 
+import (
+	"time"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
 // CalculatePackRequest represents a request to calculate optimal packing
 type CalculatePackRequest struct {
-	OrderItemQuantity int `form:"orderItemQuantity"`
+	OrderItemQuantity   int         `form:"orderItemQuantity"`
+	Alternatives        int         `form:"alternatives"`
+	Explain             bool        `form:"explain"`
+	PackSetVersion      int         `form:"packSetVersion"`
+	AsOf                time.Time   `form:"asOf"`
+	RespectStock        bool        `form:"respectStock"`
+	Reserve             bool        `form:"reserve"`
+	Objective           Objective   `form:"objective"`
+	Ranking             Ranking     `form:"rank"`
+	MaxOvershoot        *int        `form:"maxOvershoot"`
+	MaxOvershootPercent *float64    `form:"maxOvershootPercent"`
+	MaxShortfall        *int        `form:"maxShortfall"`
+	MaxShortfallPercent *float64    `form:"maxShortfallPercent"`
+	MaxShipmentWeight   *int        `form:"maxShipmentWeight"`
+	MaxParcels          *int        `form:"maxParcels"`
+	SplitMaxPacks       *int        `form:"splitMaxPacks"`
+	SplitMaxItems       *int        `form:"splitMaxItems"`
+	SplitMaxWeight      *int        `form:"splitMaxWeight"`
+	Customer            string      `form:"customer"`
+	AllowedSizes        []int       `form:"allowedSizes"`
+	ExcludedSizes       []int       `form:"excludedSizes"`
+	MinPacks            map[int]int `form:"minPacks"`
+	MaxPacks            map[int]int `form:"maxPacks"`
+	Packaging           bool        `form:"packaging"`
+	Actor               string      `form:"-"`
+	Namespace           string      `form:"-"`
+}
+
+// allowsShortfall reports whether the request accepts shipping short of the order
+func (r CalculatePackRequest) allowsShortfall() bool {
+	return r.MaxShortfall != nil || r.MaxShortfallPercent != nil
+}
+
+// hasShipmentLimits reports whether the request caps the shipment weight or parcel count
+func (r CalculatePackRequest) hasShipmentLimits() bool {
+	return r.MaxShipmentWeight != nil || r.MaxParcels != nil
+}
+
+// restrictsSizes reports whether the request narrows the pack set to a customer's or the given sizes
+func (r CalculatePackRequest) restrictsSizes() bool {
+	return r.Customer != "" || len(r.AllowedSizes) > 0 || len(r.ExcludedSizes) > 0
+}
+
+// constraints returns the pack count constraints of the request
+func (r CalculatePackRequest) constraints() model.PackConstraints {
+	return model.PackConstraints{MinPacks: r.MinPacks, MaxPacks: r.MaxPacks}
+}
+
+// splits reports whether the request splits the order into shipments with per-shipment limits
+func (r CalculatePackRequest) splits() bool {
+	return r.SplitMaxPacks != nil || r.SplitMaxItems != nil || r.SplitMaxWeight != nil
+}
+
+// weighs reports whether the request limits or splits shipments, the features reading pack specs
+func (r CalculatePackRequest) weighs() bool {
+	return r.hasShipmentLimits() || r.splits()
+}
+
+// CalculatePackBatchRequest represents a request to calculate optimal packing for many order lines
+type CalculatePackBatchRequest struct {
+	Lines          []CalculatePackBatchLine `json:"lines" binding:"required"`
+	PackSetVersion int                      `json:"packSetVersion,omitempty"`
+	AsOf           time.Time                `json:"asOf"`
+	Namespace      string                   `json:"-"`
+}
+
+// CalculatePackBatchLine represents a single order line of a batch calculation
+type CalculatePackBatchLine struct {
+	ID                string `json:"id,omitempty"`
+	OrderItemQuantity int    `json:"orderItemQuantity"`
+}
+
+// CalculateOrderRequest represents a request to pack an order whose lines are for different SKUs
+type CalculateOrderRequest struct {
+	Lines     []OrderLine `json:"lines" binding:"required"`
+	AsOf      time.Time   `json:"asOf"`
+	Namespace string      `json:"-"`
+}
+
+// OrderLine represents a quantity of a single SKU within an order. The SKU keys its own
+// pack set within the namespace of the order.
+type OrderLine struct {
+	SKU      string `json:"sku" binding:"required"`
+	Quantity int    `json:"quantity"`
 }
 
 // AddPackSizeRequest represents a request to add a new pack size
 type AddPackSizeRequest struct {
-	Size int `json:"size" binding:"required"`
+	Size      int    `json:"size" binding:"required"`
+	Actor     string `json:"-"`
+	Namespace string `json:"-"`
 }
 
 // RemovePackSizeRequest represents a request to remove a pack size
 type RemovePackSizeRequest struct {
-	Size int `json:"size" binding:"required"`
+	Size      int    `json:"size" binding:"required"`
+	Actor     string `json:"-"`
+	Namespace string `json:"-"`
+}
+
+// ReplacePackSizesRequest represents a request to replace the whole pack set
+type ReplacePackSizesRequest struct {
+	Sizes     []int  `json:"sizes" binding:"required"`
+	Actor     string `json:"-"`
+	Namespace string `json:"-"`
+}
+
+// GetPackSetVersionRequest represents a request to fetch a recorded pack set version
+type GetPackSetVersionRequest struct {
+	Version   int    `uri:"version" binding:"required"`
+	Namespace string `json:"-"`
+}
+
+// RollbackPackSizesRequest represents a request to swap a recorded version back in
+type RollbackPackSizesRequest struct {
+	Version   int    `uri:"version" binding:"required"`
+	Actor     string `json:"-"`
+	Namespace string `json:"-"`
+}
+
+// SchedulePackSizesRequest represents a request to stage a pack set that becomes live at a future time
+type SchedulePackSizesRequest struct {
+	Sizes         []int     `json:"sizes" binding:"required"`
+	EffectiveFrom time.Time `json:"effectiveFrom" binding:"required"`
+	Actor         string    `json:"-"`
+	Namespace     string    `json:"-"`
+}
+
+// CancelPackSetScheduleRequest represents a request to cancel a pending pack set schedule
+type CancelPackSetScheduleRequest struct {
+	ID        int    `uri:"id" binding:"required"`
+	Namespace string `json:"-"`
+}
+
+// SetPackStockRequest represents a request to set the stock levels of pack sizes
+type SetPackStockRequest struct {
+	Levels    map[int]int `json:"levels" binding:"required"`
+	Namespace string      `json:"-"`
+}
+
+// SetPackCostsRequest represents a request to replace what pack sizes and surplus items cost
+type SetPackCostsRequest struct {
+	model.PackCosts
+	Namespace string `json:"-"`
+}
+
+// SetPackSpecsRequest represents a request to replace the weight and dimensions of the pack sizes
+type SetPackSpecsRequest struct {
+	Specs     []model.PackSpec `json:"specs" binding:"required"`
+	Namespace string           `json:"-"`
+}
+
+// SetPackConstraintsRequest represents a request to replace the pack count constraints
+type SetPackConstraintsRequest struct {
+	model.PackConstraints
+	Namespace string `json:"-"`
+}
+
+// SetCustomerRequest represents a request to store the pack sizes a customer's orders ship in
+type SetCustomerRequest struct {
+	ID            string `uri:"id" json:"-" binding:"required"`
+	AllowedSizes  []int  `json:"allowedSizes"`
+	ExcludedSizes []int  `json:"excludedSizes"`
+	Namespace     string `json:"-"`
+}
+
+// CustomerRequest represents a request to fetch or delete a customer profile
+type CustomerRequest struct {
+	ID        string `uri:"id" binding:"required"`
+	Namespace string `json:"-"`
+}
+
+// SetPackagingRequest represents a request to replace the packaging hierarchy
+type SetPackagingRequest struct {
+	model.Packaging
+	Namespace string `json:"-"`
+}
+
+// ClearPackStockRequest represents a request to stop tracking the stock of a pack size
+type ClearPackStockRequest struct {
+	Size      int    `json:"size" binding:"required"`
+	Namespace string `json:"-"`
+}
+
+// PackReservationRequest represents a request to confirm or release a pack reservation
+type PackReservationRequest struct {
+	ID        string `uri:"id" binding:"required"`
+	Namespace string `json:"-"`
 }
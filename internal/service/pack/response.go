@@ -1,11 +1,253 @@
 package pack
 
+import (
+	"cmp"
+	"slices"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
 // CalculatePackResponse represents the response for pack calculation
 type CalculatePackResponse struct {
-	Packs map[int]int `json:"packs"`
+	OrderedQuantity   int                    `json:"orderedQuantity"`
+	ShippedQuantity   int                    `json:"shippedQuantity"`
+	Surplus           int                    `json:"surplus"`
+	Shortfall         int                    `json:"shortfall,omitempty"`
+	ShortfallAccepted bool                   `json:"shortfallAccepted,omitempty"`
+	PackCount         int                    `json:"packCount"`
+	TotalWeight       int                    `json:"totalWeight,omitempty"`
+	TotalVolume       int                    `json:"totalVolume,omitempty"`
+	PackList          []PackLine             `json:"packList"`
+	Packs             map[int]int            `json:"packs"`
+	Alternatives      []PackCombination      `json:"alternatives,omitempty"`
+	Explanation       *Explanation           `json:"explanation,omitempty"`
+	PackSetVersion    int                    `json:"packSetVersion,omitempty"`
+	PackSetScheduleID int                    `json:"packSetScheduleId,omitempty"`
+	Cost              *CostBreakdown         `json:"cost,omitempty"`
+	Reservation       *model.PackReservation `json:"reservation,omitempty"`
+	Restriction       *SizeRestriction       `json:"restriction,omitempty"`
+	Shipments         []SplitShipment        `json:"shipments,omitempty"`
+	Shipment          *Shipment              `json:"shipment,omitempty"`
+}
+
+// SizeRestriction represents how a customer's or the requested sizes narrowed the pack
+// set. OvershootIncrease is how many more surplus items the fewest the allowed sizes can
+// ship are than the fewest the whole set can.
+type SizeRestriction struct {
+	Customer           string `json:"customer,omitempty"`
+	ExcludedSizes      []int  `json:"excludedSizes"`
+	OvershootIncreased bool   `json:"overshootIncreased"`
+	OvershootIncrease  int    `json:"overshootIncrease"`
+}
+
+// SplitShipment represents one of the shipments a split order ships in, with its own totals
+type SplitShipment struct {
+	Number          int        `json:"number"`
+	ShippedQuantity int        `json:"shippedQuantity"`
+	PackCount       int        `json:"packCount"`
+	TotalWeight     int        `json:"totalWeight,omitempty"`
+	PackList        []PackLine `json:"packList"`
+}
+
+// Shipment represents how the packs of a combination ship: in cartons stacked on pallets,
+// in cartons on their own when there is no pallet type, or loose when no carton takes them
+type Shipment struct {
+	PalletCount int          `json:"palletCount"`
+	CartonCount int          `json:"cartonCount"`
+	Pallets     []PalletLoad `json:"pallets"`
+	Cartons     []CartonLoad `json:"cartons"`
+	Packs       []PackLine   `json:"packs"`
+}
+
+// PalletLoad represents Count identical pallets and the cartons each one holds
+type PalletLoad struct {
+	Pallet  string       `json:"pallet"`
+	Count   int          `json:"count"`
+	Cartons []CartonLoad `json:"cartons"`
+}
+
+// CartonLoad represents Count identical cartons and the packs each one holds
+type CartonLoad struct {
+	Carton string     `json:"carton"`
+	Count  int        `json:"count"`
+	Packs  []PackLine `json:"packs"`
+}
+
+// CostBreakdown itemises what shipping a combination costs
+type CostBreakdown struct {
+	Packs        []PackCostLine `json:"packs"`
+	PackCost     int            `json:"packCost"`
+	SurplusItems int            `json:"surplusItems"`
+	SurplusCost  int            `json:"surplusCost"`
+	Total        int            `json:"total"`
+}
+
+// PackCostLine represents what the packs of one size in a combination cost
+type PackCostLine struct {
+	Size         int `json:"size"`
+	Count        int `json:"count"`
+	MaterialCost int `json:"materialCost"`
+	HandlingCost int `json:"handlingCost"`
+	Total        int `json:"total"`
+}
+
+// PackLine represents the number of packs of one size in a combination
+type PackLine struct {
+	Size  int `json:"size"`
+	Count int `json:"count"`
+}
+
+// CalculatePackBatchResponse represents the response for a batch pack calculation
+type CalculatePackBatchResponse struct {
+	PackSetVersion    int                            `json:"packSetVersion,omitempty"`
+	PackSetScheduleID int                            `json:"packSetScheduleId,omitempty"`
+	Lines             []CalculatePackBatchLineResult `json:"lines"`
+}
+
+// CalculatePackBatchLineResult represents the result or the error of a single batch line
+type CalculatePackBatchLineResult struct {
+	ID     string                 `json:"id,omitempty"`
+	Result *CalculatePackResponse `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// CalculateOrderResponse represents the packing of a whole order
+type CalculateOrderResponse struct {
+	Lines   []CalculateOrderLineResult `json:"lines"`
+	Summary ShipmentSummary            `json:"summary"`
+}
+
+// CalculateOrderLineResult represents the result or the error of a single order line
+type CalculateOrderLineResult struct {
+	SKU    string                 `json:"sku"`
+	Result *CalculatePackResponse `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// ShipmentSummary represents the order-level totals of the lines that could be packed
+type ShipmentSummary struct {
+	LineCount       int           `json:"lineCount"`
+	FailedLines     int           `json:"failedLines"`
+	OrderedQuantity int           `json:"orderedQuantity"`
+	ShippedQuantity int           `json:"shippedQuantity"`
+	Surplus         int           `json:"surplus"`
+	PackCount       int           `json:"packCount"`
+	PackList        []SKUPackLine `json:"packList"`
+}
+
+// SKUPackLine represents the number of packs of one size of a SKU in a shipment
+type SKUPackLine struct {
+	SKU   string `json:"sku"`
+	Size  int    `json:"size"`
+	Count int    `json:"count"`
+}
+
+// GetNamespacesResponse represents the response for listing namespaces
+type GetNamespacesResponse struct {
+	Namespaces []string `json:"namespaces"`
 }
 
 // GetPackSizesResponse represents the response for getting pack sizes
 type GetPackSizesResponse struct {
-	Sizes []int `json:"sizes"`
+	Sizes   []int `json:"sizes"`
+	Version int   `json:"version,omitempty"`
+}
+
+// GetPackStockResponse represents the packs in stock by size, sizes without a level are unlimited
+type GetPackStockResponse struct {
+	Stock map[int]int `json:"stock"`
+}
+
+// GetCustomersResponse represents every customer profile
+type GetCustomersResponse struct {
+	Customers []model.CustomerProfile `json:"customers"`
+}
+
+// GetPackSpecsResponse represents the weight and dimensions of the pack sizes
+type GetPackSpecsResponse struct {
+	Specs []model.PackSpec `json:"specs"`
+}
+
+// PackSetChangeResponse represents the response for replacing or rolling back the whole pack set
+type PackSetChangeResponse struct {
+	Previous []int `json:"previous"`
+	Current  []int `json:"current"`
+	Version  int   `json:"version"`
+}
+
+// GetPackSetVersionsResponse represents the response for listing pack set versions
+type GetPackSetVersionsResponse struct {
+	Versions []model.PackSetVersion `json:"versions"`
+}
+
+// GetPackSetSchedulesResponse represents the response for listing pack set schedules
+type GetPackSetSchedulesResponse struct {
+	Schedules []model.PackSetSchedule `json:"schedules"`
+}
+
+// newCalculatePackResponse builds the calculation response for an order from its optimal packing
+func newCalculatePackResponse(orderItemQty int, packing OptimalPacking) CalculatePackResponse {
+	return CalculatePackResponse{
+		OrderedQuantity:   orderItemQty,
+		ShippedQuantity:   packing.Total,
+		Surplus:           max(packing.Total-orderItemQty, 0),
+		Shortfall:         max(orderItemQty-packing.Total, 0),
+		ShortfallAccepted: packing.Total < orderItemQty,
+		PackCount:         packing.PackCount,
+		PackList:          newPackList(packing.Packs),
+		Packs:             packing.Packs,
+	}
+}
+
+// newShipmentSummary totals the packed lines of an order, merging packs of the same SKU
+// and size across lines
+func newShipmentSummary(lines []CalculateOrderLineResult) ShipmentSummary {
+	summary := ShipmentSummary{
+		LineCount: len(lines),
+		PackList:  []SKUPackLine{},
+	}
+
+	counts := make(map[SKUPackLine]int)
+	for _, line := range lines {
+		if line.Result == nil {
+			summary.FailedLines++
+			continue
+		}
+
+		summary.OrderedQuantity += line.Result.OrderedQuantity
+		summary.ShippedQuantity += line.Result.ShippedQuantity
+		summary.Surplus += line.Result.Surplus
+		summary.PackCount += line.Result.PackCount
+
+		for _, pack := range line.Result.PackList {
+			counts[SKUPackLine{SKU: line.SKU, Size: pack.Size}] += pack.Count
+		}
+	}
+
+	for key, count := range counts {
+		key.Count = count
+		summary.PackList = append(summary.PackList, key)
+	}
+
+	slices.SortFunc(summary.PackList, func(a, b SKUPackLine) int {
+		return cmp.Or(cmp.Compare(a.SKU, b.SKU), cmp.Compare(b.Size, a.Size))
+	})
+
+	return summary
+}
+
+// newPackList returns the packs as a list sorted by size, largest first
+func newPackList(packs map[int]int) []PackLine {
+	list := make([]PackLine, 0, len(packs))
+	for size, count := range packs {
+		if count > 0 {
+			list = append(list, PackLine{Size: size, Count: count})
+		}
+	}
+
+	slices.SortFunc(list, func(a, b PackLine) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+
+	return list
 }
@@ -0,0 +1,57 @@
+package pack
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// validateCustomerProfile checks that a profile lists positive sizes, each once, and
+// never both allows and excludes a size
+func validateCustomerProfile(profile model.CustomerProfile) error {
+	for _, sizes := range [][]int{profile.AllowedSizes, profile.ExcludedSizes} {
+		for i, packSize := range sizes {
+			if packSize < 1 {
+				return fmt.Errorf("%w: pack size %d", ErrInvalidCustomerProfile, packSize)
+			}
+
+			if slices.Contains(sizes[:i], packSize) {
+				return fmt.Errorf("%w: %d is listed twice", ErrInvalidCustomerProfile, packSize)
+			}
+		}
+	}
+
+	for _, packSize := range profile.AllowedSizes {
+		if slices.Contains(profile.ExcludedSizes, packSize) {
+			return fmt.Errorf("%w: %d is both allowed and excluded", ErrInvalidCustomerProfile, packSize)
+		}
+	}
+
+	return nil
+}
+
+// narrowSizes returns the sizes of packSizes that allowed holds, every size when it is
+// empty, and excluded does not, keeping their order
+func narrowSizes(packSizes, allowed, excluded []int) []int {
+	narrowed := make([]int, 0, len(packSizes))
+	for _, packSize := range packSizes {
+		if (len(allowed) == 0 || slices.Contains(allowed, packSize)) && !slices.Contains(excluded, packSize) {
+			narrowed = append(narrowed, packSize)
+		}
+	}
+
+	return narrowed
+}
+
+// excludedSizes returns the sizes of packSizes missing from narrowed, keeping their order
+func excludedSizes(packSizes, narrowed []int) []int {
+	excluded := []int{}
+	for _, packSize := range packSizes {
+		if !slices.Contains(narrowed, packSize) {
+			excluded = append(excluded, packSize)
+		}
+	}
+
+	return excluded
+}
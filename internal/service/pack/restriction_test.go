@@ -0,0 +1,92 @@
+package pack
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+func TestNarrowSizes(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name          string
+		allowed       []int
+		excluded      []int
+		expectedSizes []int
+		description   string
+	}{
+		{
+			name:          "No filter",
+			expectedSizes: packSizes,
+			description:   "Should keep every size",
+		},
+		{
+			name:          "Allowed sizes",
+			allowed:       []int{250, 1000},
+			expectedSizes: []int{1000, 250},
+			description:   "Should keep only the allowed sizes in the set's order",
+		},
+		{
+			name:          "Excluded sizes",
+			excluded:      []int{5000},
+			expectedSizes: []int{2000, 1000, 500, 250},
+			description:   "Should drop the excluded sizes",
+		},
+		{
+			name:          "Allowed and excluded",
+			allowed:       []int{5000, 2000},
+			excluded:      []int{5000},
+			expectedSizes: []int{2000},
+			description:   "Should drop excluded sizes from the allowed ones",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := narrowSizes(packSizes, tt.allowed, tt.excluded); !slices.Equal(got, tt.expectedSizes) {
+				t.Errorf("narrowSizes() = %v, want %v", got, tt.expectedSizes)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestValidateCustomerProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		profile     model.CustomerProfile
+		expectedErr error
+	}{
+		{
+			name:    "Valid",
+			profile: model.CustomerProfile{ID: "dock", AllowedSizes: []int{250, 500}, ExcludedSizes: []int{5000}},
+		},
+		{
+			name:        "Not positive",
+			profile:     model.CustomerProfile{ID: "dock", ExcludedSizes: []int{0}},
+			expectedErr: ErrInvalidCustomerProfile,
+		},
+		{
+			name:        "Listed twice",
+			profile:     model.CustomerProfile{ID: "dock", AllowedSizes: []int{250, 250}},
+			expectedErr: ErrInvalidCustomerProfile,
+		},
+		{
+			name:        "Allowed and excluded",
+			profile:     model.CustomerProfile{ID: "dock", AllowedSizes: []int{250}, ExcludedSizes: []int{250}},
+			expectedErr: ErrInvalidCustomerProfile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCustomerProfile(tt.profile); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("validateCustomerProfile() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
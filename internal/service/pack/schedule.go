@@ -0,0 +1,62 @@
+package pack
+
+import (
+	"time"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+// activePackSet is the pack set a calculation runs against. ScheduleID names the
+// schedule that staged it when the set took effect through a schedule, and Constraints
+// holds the stored pack count constraints on its sizes.
+type activePackSet struct {
+	model.PackSetVersion
+	ScheduleID  int
+	Constraints model.PackConstraints
+}
+
+// resolvePackSet returns the pack set active at the given time: whichever recorded
+// version or schedule took effect last by then. Versions must be ordered oldest first
+// and schedules by effective time. It reports false when no set was active yet.
+func resolvePackSet(versions []model.PackSetVersion, schedules []model.PackSetSchedule, at time.Time) (activePackSet, bool) {
+	var (
+		active activePackSet
+		since  time.Time
+		found  bool
+	)
+
+	for _, version := range versions {
+		if version.CreatedAt.After(at) {
+			break
+		}
+
+		active = activePackSet{PackSetVersion: version}
+		since = version.CreatedAt
+		found = true
+	}
+
+	for _, schedule := range schedules {
+		if schedule.EffectiveFrom.After(at) {
+			break
+		}
+
+		// A change recorded no earlier than the schedule took effect overrides it
+		if found && !schedule.EffectiveFrom.After(since) {
+			continue
+		}
+
+		active = activePackSet{
+			PackSetVersion: model.PackSetVersion{
+				Version:   schedule.ActivatedVersion,
+				Sizes:     schedule.Sizes,
+				CreatedAt: schedule.EffectiveFrom,
+				Actor:     schedule.Actor,
+			},
+			ScheduleID: schedule.ID,
+		}
+		since = schedule.EffectiveFrom
+		found = true
+	}
+
+	return active, found
+}
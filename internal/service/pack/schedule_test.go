@@ -0,0 +1,87 @@
+package pack
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Amir-Sadati/order-packing/internal/model"
+)
+
+func TestResolvePackSet(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days int) time.Time {
+		return start.AddDate(0, 0, days)
+	}
+
+	versions := []model.PackSetVersion{
+		{Version: 1, Sizes: []int{500, 250}, CreatedAt: at(0)},
+		{Version: 2, Sizes: []int{1000, 500, 250}, CreatedAt: at(10)},
+	}
+	schedules := []model.PackSetSchedule{
+		{ID: 1, Sizes: []int{53, 31, 23}, EffectiveFrom: at(5), Status: model.ScheduleStatusSuperseded},
+		{ID: 2, Sizes: []int{2000, 1000}, EffectiveFrom: at(20), Status: model.ScheduleStatusPending},
+	}
+
+	tests := []struct {
+		name           string
+		at             time.Time
+		expectedSizes  []int
+		expectedID     int
+		expectedActive bool
+		description    string
+	}{
+		{
+			name:           "Before any change",
+			at:             at(-1),
+			expectedActive: false,
+			description:    "Should report no active set before the first version",
+		},
+		{
+			name:           "First version",
+			at:             at(3),
+			expectedSizes:  []int{500, 250},
+			expectedActive: true,
+			description:    "Should return the latest version recorded by then",
+		},
+		{
+			name:           "Schedule took effect",
+			at:             at(5),
+			expectedSizes:  []int{53, 31, 23},
+			expectedID:     1,
+			expectedActive: true,
+			description:    "Should return a schedule from its effective time on",
+		},
+		{
+			name:           "Later change overrides schedule",
+			at:             at(15),
+			expectedSizes:  []int{1000, 500, 250},
+			expectedActive: true,
+			description:    "Should return a version recorded after the schedule took effect",
+		},
+		{
+			name:           "Pending schedule in the future",
+			at:             at(25),
+			expectedSizes:  []int{2000, 1000},
+			expectedID:     2,
+			expectedActive: true,
+			description:    "Should return a pending schedule once its effective time has come",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := resolvePackSet(versions, schedules, tt.at)
+			if ok != tt.expectedActive {
+				t.Fatalf("resolvePackSet() at %v active = %v, want %v", tt.at, ok, tt.expectedActive)
+			}
+
+			if !reflect.DeepEqual(result.Sizes, tt.expectedSizes) || result.ScheduleID != tt.expectedID {
+				t.Errorf("resolvePackSet() at %v = %v from schedule %d, want %v from schedule %d",
+					tt.at, result.Sizes, result.ScheduleID, tt.expectedSizes, tt.expectedID)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
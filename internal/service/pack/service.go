@@ -3,10 +3,15 @@ package pack
 import (
 	"context"
 	"errors"
-	"strconv"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
 
-	"github.com/Amir-Sadati/order-packing/internal/constants"
-	"github.com/redis/go-redis/v9"
+	"github.com/Amir-Sadati/order-packing/internal/config"
+	"github.com/Amir-Sadati/order-packing/internal/model"
+	"github.com/Amir-Sadati/order-packing/internal/repository"
 )
 
 var (
@@ -14,83 +19,1456 @@ var (
 	ErrInvalidOrderItemQuantity = errors.New("invalid order-item-quantity")
 	// ErrNotFoundPackSize is returned when a pack size is not found
 	ErrNotFoundPackSize = errors.New("pack size not found")
+	// ErrInvalidAlternatives is returned when the requested number of alternatives is out of range
+	ErrInvalidAlternatives = errors.New("invalid alternatives")
+	// ErrInvalidBatchSize is returned when a batch has no lines or too many lines
+	ErrInvalidBatchSize = errors.New("invalid batch size")
+	// ErrInvalidOrderLines is returned when an order has no lines or too many lines
+	ErrInvalidOrderLines = errors.New("invalid order lines")
+	// ErrNoPackSizesConfigured is returned when a calculation runs against an empty pack set
+	ErrNoPackSizesConfigured = errors.New("no pack sizes configured")
+	// ErrLastPackSize is returned when removing a pack size would leave the set empty
+	ErrLastPackSize = errors.New("cannot remove the last pack size")
+	// ErrEmptyPackSet is returned when a pack set without any size is submitted
+	ErrEmptyPackSet = errors.New("pack set must hold at least one size")
+	// ErrInvalidPackSize is returned when a pack size is outside the configured limits
+	ErrInvalidPackSize = errors.New("invalid pack size")
+	// ErrPackSizeExists is returned when a pack size is already in the set
+	ErrPackSizeExists = errors.New("pack size already exists")
+	// ErrTooManyPackSizes is returned when a pack set would hold more sizes than allowed
+	ErrTooManyPackSizes = errors.New("too many pack sizes")
+	// ErrComputationBudgetExceeded is returned when a calculation runs out of its compute budget
+	ErrComputationBudgetExceeded = errors.New("computation budget exceeded")
+	// ErrInsufficientStock is returned, wrapped in an *InsufficientStockError, when the packs in stock cannot cover an order
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrInvalidStockLevel is returned when a stock level is negative
+	ErrInvalidStockLevel = errors.New("invalid stock level")
+	// ErrInvalidObjective is returned when a calculation names an unknown objective
+	ErrInvalidObjective = errors.New("invalid objective")
+	// ErrUnsupportedCostOption is returned when the cost objective is combined with an option it does not support
+	ErrUnsupportedCostOption = errors.New("objective=cost cannot be combined with alternatives, explain, respectStock, reserve, rank, a tolerance or a shipment limit")
+	// ErrMissingPackCost is returned when the cost objective runs against a pack size without a cost
+	ErrMissingPackCost = errors.New("pack size has no cost")
+	// ErrInvalidPackCost is returned when a cost is negative or a pack size is priced twice
+	ErrInvalidPackCost = errors.New("invalid pack cost")
+	// ErrInvalidRanking is returned when a ranking names an unknown criterion or one criterion twice,
+	// or compares too many pack sizes by the sizes criterion after another one
+	ErrInvalidRanking = errors.New("invalid ranking")
+	// ErrUnsupportedRanking is returned when a custom ranking is combined with an option it does not support
+	ErrUnsupportedRanking = errors.New("rank cannot be combined with respectStock or reserve")
+	// ErrInvalidTolerance is returned when a tolerance is negative or given both in items and as a percentage
+	ErrInvalidTolerance = errors.New("invalid tolerance")
+	// ErrUnsupportedShortfall is returned when a shortfall tolerance is combined with an option it does not support
+	ErrUnsupportedShortfall = errors.New("maxShortfall cannot be combined with alternatives, explain, rank, respectStock or reserve")
+	// ErrOvershootExceeded is returned when no combination covers an order within the allowed overshoot
+	ErrOvershootExceeded = errors.New("overshoot exceeded")
+	// ErrInvalidPackSpec is returned when a weight or dimension is negative or a pack size is specified twice
+	ErrInvalidPackSpec = errors.New("invalid pack spec")
+	// ErrMissingPackSpec is returned when a shipment limit applies to a pack size without a spec
+	ErrMissingPackSpec = errors.New("pack size has no spec")
+	// ErrInvalidShipmentLimit is returned when a shipment limit is negative
+	ErrInvalidShipmentLimit = errors.New("invalid shipment limit")
+	// ErrUnsupportedShipmentLimit is returned when a shipment limit is combined with an option it does not support
+	ErrUnsupportedShipmentLimit = errors.New("maxShipmentWeight and maxParcels cannot be combined with alternatives, explain, rank, respectStock, reserve or maxShortfall")
+	// ErrShipmentLimitExceeded is returned, wrapped in a *ShipmentLimitError, when no combination fits the shipment limits
+	ErrShipmentLimitExceeded = errors.New("shipment limit exceeded")
+	// ErrInvalidSplitLimit is returned when a per-shipment limit of a split order is below one
+	ErrInvalidSplitLimit = errors.New("invalid split limit")
+	// ErrUnsplittablePack is returned when a single pack exceeds the per-shipment limits of a split order
+	ErrUnsplittablePack = errors.New("pack exceeds the shipment limits")
+	// ErrTooManyShipments is returned when an order would be split into more shipments than allowed
+	ErrTooManyShipments = errors.New("too many shipments")
+	// ErrInvalidPackConstraint is returned when a pack count constraint is negative or its minimum exceeds its maximum
+	ErrInvalidPackConstraint = errors.New("invalid pack constraint")
+	// ErrUnsupportedConstraint is returned when pack count constraints are combined with an option they do not support
+	ErrUnsupportedConstraint = errors.New("pack count constraints cannot be combined with alternatives, explain, rank, maxShortfall, objective=cost or a shipment limit")
+	// ErrConstraintsInfeasible is returned, wrapped in a *ConstraintError, when no combination within the pack constraints covers an order
+	ErrConstraintsInfeasible = errors.New("pack constraints cannot be met")
+	// ErrInvalidCustomerProfile is returned when a customer profile lists a size that is not positive, twice, or both allowed and excluded
+	ErrInvalidCustomerProfile = errors.New("invalid customer profile")
+	// ErrAllPackSizesExcluded is returned when a customer's or the requested sizes leave none of the pack set
+	ErrAllPackSizesExcluded = errors.New("every pack size is excluded")
+	// ErrInvalidPackaging is returned when a packaging hierarchy has an unnamed, empty or repeated carton or pallet type
+	ErrInvalidPackaging = errors.New("invalid packaging")
+	// ErrNoPackagingConfigured is returned when a calculation asks for a shipment before any packaging is set
+	ErrNoPackagingConfigured = errors.New("no packaging configured")
+	// ErrAmbiguousPackSet is returned when a calculation selects a pack set both by version and by time
+	ErrAmbiguousPackSet = errors.New("packSetVersion and asOf cannot be combined")
+	// ErrInvalidEffectiveFrom is returned when a pack set is scheduled to take effect in the past
+	ErrInvalidEffectiveFrom = errors.New("effectiveFrom must be in the future")
 )
 
+// maxBatchLines caps how many lines a single batch calculation may hold
+const maxBatchLines = 10000
+
+// maxReserveAttempts caps how often a reserving calculation is rerun when concurrent
+// reservations take the stock it read before its packs could be held
+const maxReserveAttempts = 3
+
 // Service provides pack-related business logic operations
 type Service struct {
-	rdb *redis.Client
+	store  repository.PackSizeStore
+	cfg    *config.PackConfig
+	budget Budget
+	now    func() time.Time
+	// tables holds the lookup tables of the pack sets calculations ran against
+	tables *lookupTables
 }
 
 // NewService creates and returns a new Service instance
-func NewService(redisClinet *redis.Client) *Service {
+func NewService(store repository.PackSizeStore, cfg *config.PackConfig) *Service {
 	return &Service{
-		rdb: redisClinet,
+		store: store,
+		cfg:   cfg,
+		budget: Budget{
+			MaxNodes: cfg.MaxComputeNodes,
+			Timeout:  cfg.ComputeTimeout,
+		},
+		now:    time.Now,
+		tables: newLookupTables(),
 	}
 }
 
-// CalculatePack calculates the optimal pack combination for a given order quantity
-func (s *Service) CalculatePack(context context.Context, req CalculatePackRequest) (CalculatePackResponse, error) {
-	if req.OrderItemQuantity < 1 {
-		return CalculatePackResponse{}, ErrInvalidOrderItemQuantity
+// CalculatePack calculates the optimal pack combination for a given order quantity.
+// With RespectStock it never uses more packs of a size than are in stock, and with
+// Reserve it also holds the chosen packs until the reservation is confirmed, released
+// or expires.
+func (s *Service) CalculatePack(ctx context.Context, req CalculatePackRequest) (CalculatePackResponse, error) {
+	if err := validateCalculateRequest(req); err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	packSet, err := readPackSet(ctx, repo, req.PackSetVersion, req.AsOf, s.now())
+	if err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	var restriction *SizeRestriction
+	if req.restrictsSizes() {
+		packSet, restriction, err = s.restrict(ctx, repo, req, packSet)
+		if err != nil {
+			return CalculatePackResponse{}, err
+		}
+	}
+
+	req, err = withConstraints(req, packSet)
+	if err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	// Only shipment limits and splits need specs, so other calculations skip reading them
+	var specs specList
+	if req.weighs() {
+		stored, err := repo.Specs(ctx)
+		if err != nil {
+			return CalculatePackResponse{}, err
+		}
+
+		specs = newSpecList(stored)
+	}
+
+	var result CalculatePackResponse
+	switch {
+	case req.Objective == ObjectiveCost:
+		result, err = s.calculateCheapest(ctx, repo, req, packSet.Sizes)
+	case req.hasShipmentLimits():
+		result, err = s.calculateWithinLimits(ctx, req, packSet.Sizes, specs)
+	case req.Reserve:
+		result, err = s.reservePacks(ctx, repo, req, packSet.Sizes)
+	case req.RespectStock:
+		result, err = s.calculateInStock(ctx, repo, req, packSet.Sizes)
+	default:
+		result, err = s.calculate(ctx, req, packSet.Sizes, nil)
 	}
 
-	packVals, err := s.rdb.ZRevRange(
-		context,
-		string(constants.RedisKeyPackSizes),
-		0, -1,
-	).Result()
 	if err != nil {
 		return CalculatePackResponse{}, err
 	}
 
-	packSizez := make([]int, len(packVals))
-	for i, v := range packVals {
-		packSize, err := strconv.Atoi(v)
+	if weight, volume, ok := specs.measure(result.Packs); ok {
+		result.TotalWeight, result.TotalVolume = weight, volume
+	}
+
+	if req.splits() {
+		shipments, err := s.split(ctx, req, result.Packs, specs)
+		if err != nil {
+			return CalculatePackResponse{}, err
+		}
+
+		result.Shipments = shipments
+	}
+
+	if req.Packaging {
+		shipment, err := s.shipment(ctx, repo, result.Packs)
 		if err != nil {
 			return CalculatePackResponse{}, err
 		}
-		packSizez[i] = packSize
+
+		result.Shipment = &shipment
+	}
+
+	result.Restriction = restriction
+	result.PackSetVersion = packSet.Version
+	result.PackSetScheduleID = packSet.ScheduleID
+
+	return result, nil
+}
+
+// restrict narrows a pack set to the sizes the request's customer profile and then its
+// own allowed and excluded sizes leave, and reports how the overshoot changed. Sizes the
+// request names must be in the set, while a profile may name sizes the set lacks.
+func (s *Service) restrict(ctx context.Context, repo repository.PackSizeRepository, req CalculatePackRequest, packSet activePackSet) (activePackSet, *SizeRestriction, error) {
+	for _, packSize := range slices.Concat(req.AllowedSizes, req.ExcludedSizes) {
+		if !slices.Contains(packSet.Sizes, packSize) {
+			return activePackSet{}, nil, fmt.Errorf("%w: %d", ErrNotFoundPackSize, packSize)
+		}
+	}
+
+	sizes := packSet.Sizes
+	if req.Customer != "" {
+		profile, err := repo.Customer(ctx, req.Customer)
+		if err != nil {
+			return activePackSet{}, nil, err
+		}
+
+		sizes = narrowSizes(sizes, profile.AllowedSizes, profile.ExcludedSizes)
+	}
+
+	sizes = narrowSizes(sizes, req.AllowedSizes, req.ExcludedSizes)
+	if len(sizes) == 0 {
+		return activePackSet{}, nil, ErrAllPackSizesExcluded
+	}
+
+	restriction := &SizeRestriction{
+		Customer:      req.Customer,
+		ExcludedSizes: excludedSizes(packSet.Sizes, sizes),
+	}
+
+	if len(restriction.ExcludedSizes) > 0 {
+		full, err := s.optimalPacks(ctx, req.OrderItemQuantity, packSet.Sizes)
+		if err != nil {
+			return activePackSet{}, nil, err
+		}
+
+		narrowed, err := s.optimalPacks(ctx, req.OrderItemQuantity, sizes)
+		if err != nil {
+			return activePackSet{}, nil, err
+		}
+
+		restriction.OvershootIncrease = narrowed.Total - full.Total
+		restriction.OvershootIncreased = restriction.OvershootIncrease > 0
+	}
+
+	packSet.Sizes = sizes
+	packSet.Constraints = constraintsFor(packSet.Constraints, sizes)
+
+	return packSet, restriction, nil
+}
+
+// calculateWithinLimits runs a calculation request for the best combination within its
+// shipment limits
+func (s *Service) calculateWithinLimits(ctx context.Context, req CalculatePackRequest, packSizes []int, specs specList) (CalculatePackResponse, error) {
+	if err := specs.covers(packSizes); err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	packing, err := calculateLimitedPacks(ctx, req.OrderItemQuantity, packSizes, specs, newShipmentLimits(req), s.budget)
+	if err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	if err := newSelection(req).checkOvershoot(packing, req.OrderItemQuantity); err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	return newCalculatePackResponse(req.OrderItemQuantity, packing), nil
+}
+
+// split spreads the packs of a calculation across shipments within the request's
+// per-shipment limits. Limiting the weight needs a spec for every size used.
+func (s *Service) split(ctx context.Context, req CalculatePackRequest, packs map[int]int, specs specList) ([]SplitShipment, error) {
+	limits := splitLimits{
+		maxPacks:  orUnlimited(req.SplitMaxPacks),
+		maxItems:  orUnlimited(req.SplitMaxItems),
+		maxWeight: orUnlimited(req.SplitMaxWeight),
+	}
+
+	if limits.maxWeight >= 0 {
+		if err := specs.covers(slices.Collect(maps.Keys(copyPacks(packs)))); err != nil {
+			return nil, err
+		}
 	}
 
-	resp := calculatePacks(req.OrderItemQuantity, packSizez)
-	return CalculatePackResponse(resp), nil
+	return splitShipments(ctx, packs, specs, limits, s.budget)
 }
 
-// GetPackSizes returns all pack sizes in descending order (largest to smallest)
-func (s *Service) GetPackSizes(ctx context.Context) (GetPackSizesResponse, error) {
-	vals, err := s.rdb.ZRevRange(ctx, string(constants.RedisKeyPackSizes), 0, -1).Result()
+// shipment puts the packs of a calculation into the cartons and onto the pallets of the
+// namespace's packaging hierarchy
+func (s *Service) shipment(ctx context.Context, repo repository.PackSizeRepository, packs map[int]int) (Shipment, error) {
+	packaging, err := repo.Packaging(ctx)
 	if err != nil {
-		return GetPackSizesResponse{}, err
+		return Shipment{}, err
+	}
+
+	if len(packaging.Cartons) == 0 {
+		return Shipment{}, ErrNoPackagingConfigured
+	}
+
+	return buildShipment(packs, packaging), nil
+}
+
+// calculateCheapest runs a calculation request for the cheapest combination and itemises its cost
+func (s *Service) calculateCheapest(ctx context.Context, repo repository.PackSizeRepository, req CalculatePackRequest, packSizes []int) (CalculatePackResponse, error) {
+	costs, err := repo.Costs(ctx)
+	if err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	prices, err := newPriceList(costs, packSizes)
+	if err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	packing, err := calculateCheapestPacks(ctx, req.OrderItemQuantity, packSizes, prices, s.budget)
+	if err != nil {
+		return CalculatePackResponse{}, err
 	}
 
-	packSizes := make([]int, 0, len(vals))
-	for _, v := range vals {
-		n, err := strconv.Atoi(v)
+	result := newCalculatePackResponse(req.OrderItemQuantity, packing)
+
+	breakdown := prices.breakdown(req.OrderItemQuantity, packing.Packs)
+	result.Cost = &breakdown
+
+	return result, nil
+}
+
+// reservePacks calculates against the packs in stock and holds the chosen packs. When
+// concurrent reservations take the stock read before the packs are held, the calculation
+// is rerun against the stock left.
+func (s *Service) reservePacks(ctx context.Context, repo repository.PackSizeRepository, req CalculatePackRequest, packSizes []int) (CalculatePackResponse, error) {
+	for range maxReserveAttempts {
+		result, err := s.calculateInStock(ctx, repo, req, packSizes)
+		if err != nil {
+			return CalculatePackResponse{}, err
+		}
+
+		reservation, err := repo.Reserve(ctx, req.Actor, result.Packs, s.cfg.ReservationTTL)
+		if errors.Is(err, repository.ErrStockExhausted) {
+			continue
+		}
+
 		if err != nil {
-			return GetPackSizesResponse{}, err
+			return CalculatePackResponse{}, err
 		}
-		packSizes = append(packSizes, n)
+
+		result.Reservation = &reservation
+
+		return result, nil
 	}
 
-	return GetPackSizesResponse{Sizes: packSizes}, nil
+	return CalculatePackResponse{}, repository.ErrConcurrentUpdate
 }
 
-// AddPackSize adds a new pack size to the Redis sorted set
-func (s *Service) AddPackSize(ctx context.Context, req AddPackSizeRequest) error {
-	return s.rdb.ZAdd(ctx, string(constants.RedisKeyPackSizes), redis.Z{
-		Score:  float64(req.Size),
-		Member: req.Size,
-	}).Err()
+// calculateInStock runs a calculation request using at most the packs currently in stock
+func (s *Service) calculateInStock(ctx context.Context, repo repository.PackSizeRepository, req CalculatePackRequest, packSizes []int) (CalculatePackResponse, error) {
+	stock, err := repo.Stock(ctx)
+	if err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	return s.calculate(ctx, req, packSizes, stock)
 }
 
-// RemovePackSize removes a pack size from the Redis sorted set
-func (s *Service) RemovePackSize(ctx context.Context, req RemovePackSizeRequest) error {
-	removedCount, err := s.rdb.ZRem(ctx, string(constants.RedisKeyPackSizes), req.Size).Result()
+// CalculatePackBatch calculates the optimal pack combination for every line of a batch.
+// The pack sizes are read once, and a line that fails reports its own error without
+// failing the rest of the batch. The lines share the deadline and node budget of a
+// single calculation, so lines past them fail with ErrComputationBudgetExceeded.
+func (s *Service) CalculatePackBatch(ctx context.Context, req CalculatePackBatchRequest) (CalculatePackBatchResponse, error) {
+	if len(req.Lines) == 0 || len(req.Lines) > maxBatchLines {
+		return CalculatePackBatchResponse{}, ErrInvalidBatchSize
+	}
+
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return CalculatePackBatchResponse{}, err
+	}
+
+	packSet, err := readPackSet(ctx, repo, req.PackSetVersion, req.AsOf, s.now())
+	if err != nil {
+		return CalculatePackBatchResponse{}, err
+	}
+
+	// The lines share one deadline and node budget, so a batch costs no more than a
+	// single calculation may
+	lineCtx, cancel := withBudgetTimeout(ctx, s.budget)
+	defer cancel()
+
+	batch := *s
+	batch.budget = s.budget.shared()
+
+	lines := make([]CalculatePackBatchLineResult, len(req.Lines))
+	for i, line := range req.Lines {
+		if err := ctx.Err(); err != nil {
+			return CalculatePackBatchResponse{}, err
+		}
+
+		lines[i].ID = line.ID
+
+		lineReq := CalculatePackRequest{OrderItemQuantity: line.OrderItemQuantity}
+		if err := validateCalculateRequest(lineReq); err != nil {
+			lines[i].Error = err.Error()
+			continue
+		}
+
+		lineReq, err := withConstraints(lineReq, packSet)
+		if err != nil {
+			lines[i].Error = err.Error()
+			continue
+		}
+
+		result, err := batch.calculate(lineCtx, lineReq, packSet.Sizes, nil)
+		if err != nil {
+			lines[i].Error = err.Error()
+			continue
+		}
+
+		lines[i].Result = &result
+	}
+
+	return CalculatePackBatchResponse{
+		PackSetVersion:    packSet.Version,
+		PackSetScheduleID: packSet.ScheduleID,
+		Lines:             lines,
+	}, nil
+}
+
+// CalculateOrder calculates the optimal pack combination for every line of an order
+// against the pack set of the line's SKU within the order's namespace, and totals the
+// lines into a shipment summary.
+// Each SKU's pack set is read once, and a line that fails reports its own error without
+// failing the rest of the order. The lines share the deadline and node budget of a single
+// calculation, so lines past them fail with ErrComputationBudgetExceeded.
+func (s *Service) CalculateOrder(ctx context.Context, req CalculateOrderRequest) (CalculateOrderResponse, error) {
+	if len(req.Lines) == 0 || len(req.Lines) > maxBatchLines {
+		return CalculateOrderResponse{}, ErrInvalidOrderLines
+	}
+
+	now := s.now()
+	packSets := make(map[string]activePackSet)
+	packSetErrs := make(map[string]error)
+
+	// The lines share one deadline and node budget, as they do for a batch
+	lineCtx, cancel := withBudgetTimeout(ctx, s.budget)
+	defer cancel()
+
+	order := *s
+	order.budget = s.budget.shared()
+
+	lines := make([]CalculateOrderLineResult, len(req.Lines))
+	for i, line := range req.Lines {
+		if err := ctx.Err(); err != nil {
+			return CalculateOrderResponse{}, err
+		}
+
+		lines[i].SKU = line.SKU
+
+		lineReq := CalculatePackRequest{OrderItemQuantity: line.Quantity}
+		if err := validateCalculateRequest(lineReq); err != nil {
+			lines[i].Error = err.Error()
+			continue
+		}
+
+		packSet, ok := packSets[line.SKU]
+		if err := packSetErrs[line.SKU]; err != nil {
+			lines[i].Error = err.Error()
+			continue
+		}
+
+		if !ok {
+			var err error
+
+			packSet, err = s.skuPackSet(ctx, req.Namespace, line.SKU, req.AsOf, now)
+			if err != nil {
+				packSetErrs[line.SKU] = err
+				lines[i].Error = err.Error()
+
+				continue
+			}
+
+			packSets[line.SKU] = packSet
+		}
+
+		lineReq, err := withConstraints(lineReq, packSet)
+		if err != nil {
+			lines[i].Error = err.Error()
+			continue
+		}
+
+		result, err := order.calculate(lineCtx, lineReq, packSet.Sizes, nil)
+		if err != nil {
+			lines[i].Error = err.Error()
+			continue
+		}
+
+		result.PackSetVersion = packSet.Version
+		result.PackSetScheduleID = packSet.ScheduleID
+		lines[i].Result = &result
+	}
+
+	return CalculateOrderResponse{
+		Lines:   lines,
+		Summary: newShipmentSummary(lines),
+	}, nil
+}
+
+// skuPackSet reads the pack set of a SKU within a namespace active at asOf, or at now when asOf is zero
+func (s *Service) skuPackSet(ctx context.Context, namespace, sku string, asOf, now time.Time) (activePackSet, error) {
+	if namespace == "" {
+		namespace = repository.DefaultNamespace
+	}
+
+	repo, err := s.store.Namespace(repository.SKUNamespace(namespace, sku))
 	if err != nil {
+		return activePackSet{}, err
+	}
+
+	return readPackSet(ctx, repo, 0, asOf, now)
+}
+
+// validateCalculateRequest checks the calculation options before any pack sizes are read
+func validateCalculateRequest(req CalculatePackRequest) error {
+	if req.OrderItemQuantity < 1 {
+		return ErrInvalidOrderItemQuantity
+	}
+
+	if req.Alternatives < 0 || req.Alternatives > maxAlternatives {
+		return ErrInvalidAlternatives
+	}
+
+	if err := req.Ranking.validate(); err != nil {
+		return err
+	}
+
+	if err := validateTolerance("maxOvershoot", req.MaxOvershoot, req.MaxOvershootPercent); err != nil {
+		return err
+	}
+
+	if err := validateTolerance("maxShortfall", req.MaxShortfall, req.MaxShortfallPercent); err != nil {
 		return err
 	}
-	if removedCount == 0 {
-		return ErrNotFoundPackSize
+
+	if (req.MaxShipmentWeight != nil && *req.MaxShipmentWeight < 0) || (req.MaxParcels != nil && *req.MaxParcels < 0) {
+		return ErrInvalidShipmentLimit
+	}
+
+	for _, limit := range []*int{req.SplitMaxPacks, req.SplitMaxItems, req.SplitMaxWeight} {
+		if limit != nil && *limit < 1 {
+			return ErrInvalidSplitLimit
+		}
+	}
+
+	if req.hasShipmentLimits() && (req.Alternatives > 0 || req.Explain || !req.Ranking.isDefault() ||
+		req.RespectStock || req.Reserve || req.allowsShortfall()) {
+		return ErrUnsupportedShipmentLimit
+	}
+
+	if req.allowsShortfall() && (req.Alternatives > 0 || req.Explain || !req.Ranking.isDefault() || req.RespectStock || req.Reserve) {
+		return ErrUnsupportedShortfall
+	}
+
+	if !req.Ranking.isDefault() && (req.RespectStock || req.Reserve) {
+		return ErrUnsupportedRanking
+	}
+
+	switch req.Objective {
+	case "", ObjectiveItems:
+	case ObjectiveCost:
+		if req.Alternatives > 0 || req.Explain || req.RespectStock || req.Reserve ||
+			len(req.Ranking) > 0 || req.MaxOvershoot != nil || req.MaxOvershootPercent != nil || req.allowsShortfall() ||
+			req.hasShipmentLimits() {
+			return ErrUnsupportedCostOption
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidObjective, req.Objective)
+	}
+
+	return nil
+}
+
+// withConstraints returns a validated calculation request bounded by the stored pack
+// count constraints of its pack set, the requested ones taking their place size by size.
+// Stored constraints are left out for orders below their minimum order quantity. Options
+// the constrained solver does not support fail whenever constraints apply, whether stored
+// or requested.
+func withConstraints(req CalculatePackRequest, packSet activePackSet) (CalculatePackRequest, error) {
+	requested := req.constraints()
+
+	stored := packSet.Constraints
+	if req.OrderItemQuantity < stored.MinOrderQuantity {
+		stored = model.PackConstraints{}
+	}
+
+	unsupported := req.Alternatives > 0 || req.Explain || !req.Ranking.isDefault() ||
+		req.allowsShortfall() || req.Objective == ObjectiveCost || req.hasShipmentLimits()
+
+	if unsupported && (hasConstraints(stored) || hasConstraints(requested)) {
+		return CalculatePackRequest{}, ErrUnsupportedConstraint
+	}
+
+	constraints := mergeConstraints(stored, requested)
+	if err := validateConstraints(constraints, packSet.Sizes); err != nil {
+		return CalculatePackRequest{}, err
+	}
+
+	req.MinPacks, req.MaxPacks = constraints.MinPacks, constraints.MaxPacks
+
+	return req, nil
+}
+
+// validateTolerance checks a tolerance given in items or as a percentage of the order
+func validateTolerance(name string, items *int, percent *float64) error {
+	if items != nil && percent != nil {
+		return fmt.Errorf("%w: %s and %sPercent cannot be combined", ErrInvalidTolerance, name, name)
+	}
+
+	if (items != nil && *items < 0) || (percent != nil && *percent < 0) {
+		return fmt.Errorf("%w: %s cannot be negative", ErrInvalidTolerance, name)
 	}
+
 	return nil
 }
+
+// toleranceItems returns a tolerance given in items or as a percentage of an order of
+// orderQty items, rounded down, or fallback when neither is given
+func toleranceItems(orderQty int, items *int, percent *float64, fallback int) int {
+	switch {
+	case items != nil:
+		return *items
+	case percent != nil:
+		return int(math.Floor(*percent * float64(orderQty) / 100))
+	default:
+		return fallback
+	}
+}
+
+// newShipmentLimits builds the shipment limits of a validated calculation request
+func newShipmentLimits(req CalculatePackRequest) shipmentLimits {
+	return shipmentLimits{
+		maxWeight:  orUnlimited(req.MaxShipmentWeight),
+		maxParcels: orUnlimited(req.MaxParcels),
+	}
+}
+
+// orUnlimited returns a limit, or -1 standing for unlimited when it is not given
+func orUnlimited(limit *int) int {
+	if limit == nil {
+		return -1
+	}
+
+	return *limit
+}
+
+// newSelection builds the selection of a validated calculation request
+func newSelection(req CalculatePackRequest) selection {
+	return selection{
+		ranking:      req.Ranking.orDefault(),
+		maxOvershoot: toleranceItems(req.OrderItemQuantity, req.MaxOvershoot, req.MaxOvershootPercent, -1),
+		maxShortfall: toleranceItems(req.OrderItemQuantity, req.MaxShortfall, req.MaxShortfallPercent, 0),
+		constraints:  req.constraints(),
+	}
+}
+
+// calculate runs a validated calculation request against the given pack sizes, using at
+// most the packs in stock unless stock is nil
+func (s *Service) calculate(ctx context.Context, req CalculatePackRequest, packSizes []int, stock map[int]int) (CalculatePackResponse, error) {
+	sel := newSelection(req)
+
+	resp, err := s.calculateSelected(ctx, req.OrderItemQuantity, packSizes, stock, sel)
+	if err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	// Explaining needs the next-best combination even without alternatives
+	k := req.Alternatives
+	if req.Explain && k == 0 {
+		k = 1
+	}
+
+	alternatives, err := findAlternativeCombinations(ctx, req.OrderItemQuantity, packSizes, stock, sel, resp, k, s.budget)
+	if err != nil {
+		return CalculatePackResponse{}, err
+	}
+
+	result := newCalculatePackResponse(req.OrderItemQuantity, resp)
+	result.Alternatives = alternatives[:min(len(alternatives), req.Alternatives)]
+
+	if req.Explain {
+		var nextBest *PackCombination
+		if len(alternatives) > 0 {
+			nextBest = &alternatives[0]
+		}
+
+		explanation := explain(resp, nextBest, sel.ranking)
+		result.Explanation = &explanation
+	}
+
+	return result, nil
+}
+
+// optimalPacks finds the optimal pack combination for an order, reading the remainder
+// after the largest packs from the pack set's lookup table
+func (s *Service) optimalPacks(ctx context.Context, orderItemQty int, packSizes []int) (OptimalPacking, error) {
+	return lookupPacks(ctx, orderItemQty, packSizes, s.tables.get(packSizes), s.budget)
+}
+
+// calculateSelected finds the combination the selection picks. The default ranking is
+// served by the regular solver, or by the constrained one when the selection bounds the
+// packs of a size, any other ranking by the ranked search. When the selection allows a
+// shortfall, the combination falling short closest to the order replaces the regular
+// one if it ships closer to the order.
+func (s *Service) calculateSelected(ctx context.Context, orderItemQty int, packSizes []int, stock map[int]int, sel selection) (OptimalPacking, error) {
+	if !sel.ranking.isDefault() {
+		return findRankedPacks(ctx, orderItemQty, packSizes, sel, s.budget)
+	}
+
+	if hasConstraints(sel.constraints) {
+		resp, err := calculateConstrainedPacks(ctx, orderItemQty, packSizes, sel.constraints, stock, s.budget)
+		if err != nil {
+			return OptimalPacking{}, err
+		}
+
+		if err := sel.checkOvershoot(resp, orderItemQty); err != nil {
+			return OptimalPacking{}, err
+		}
+
+		return resp, nil
+	}
+
+	resp, err := s.optimalPacks(ctx, orderItemQty, packSizes)
+	if err == nil && !withinStock(resp.Packs, stock) {
+		resp, err = calculateStockedPacks(ctx, orderItemQty, packSizes, stock, s.budget)
+	}
+
+	if err != nil {
+		return OptimalPacking{}, err
+	}
+
+	if sel.maxShortfall > 0 {
+		short, found, err := calculateShortPacks(ctx, orderItemQty, orderItemQty-sel.maxShortfall, packSizes, s.budget)
+		if err != nil {
+			return OptimalPacking{}, err
+		}
+
+		if found && (sel.checkOvershoot(resp, orderItemQty) != nil || closerToOrder(short, resp, orderItemQty, packSizes)) {
+			return short, nil
+		}
+	}
+
+	if err := sel.checkOvershoot(resp, orderItemQty); err != nil {
+		return OptimalPacking{}, err
+	}
+
+	return resp, nil
+}
+
+// namespace returns the pack size repository of a namespace, the default one when none is named
+func (s *Service) namespace(namespace string) (repository.PackSizeRepository, error) {
+	if namespace == "" {
+		namespace = repository.DefaultNamespace
+	}
+
+	return s.store.Namespace(namespace)
+}
+
+// readPackSet reads the pack set a calculation runs against: the recorded version when one
+// is requested, otherwise the set active at asOf, or now when no time is requested,
+// along with the stored pack count constraints on its sizes. An empty set cannot be
+// calculated against.
+func readPackSet(ctx context.Context, repo repository.PackSizeRepository, version int, asOf, now time.Time) (activePackSet, error) {
+	if version != 0 && !asOf.IsZero() {
+		return activePackSet{}, ErrAmbiguousPackSet
+	}
+
+	var (
+		packSet activePackSet
+		err     error
+	)
+
+	if version != 0 {
+		packSet.PackSetVersion, err = repo.Version(ctx, version)
+	} else {
+		packSet, err = resolveActivePackSet(ctx, repo, asOf, now)
+	}
+
+	if err != nil {
+		return activePackSet{}, err
+	}
+
+	if len(packSet.Sizes) == 0 {
+		return activePackSet{}, ErrNoPackSizesConfigured
+	}
+
+	constraints, err := repo.Constraints(ctx)
+	if err != nil {
+		return activePackSet{}, err
+	}
+
+	packSet.Constraints = constraintsFor(constraints, packSet.Sizes)
+
+	return packSet, nil
+}
+
+// resolveActivePackSet resolves the pack set active at asOf, or now when asOf is zero.
+// Only the live set or a due schedule can be active now, while another time needs the
+// whole version history.
+func resolveActivePackSet(ctx context.Context, repo repository.PackSizeRepository, asOf, now time.Time) (activePackSet, error) {
+	current, err := repo.Current(ctx)
+	if err != nil {
+		return activePackSet{}, err
+	}
+
+	versions := []model.PackSetVersion{current}
+
+	at := asOf
+	if at.IsZero() {
+		at = now
+	} else if current.Version != 0 {
+		versions, err = repo.Versions(ctx)
+		if err != nil {
+			return activePackSet{}, err
+		}
+	}
+
+	schedules, err := repo.Schedules(ctx)
+	if err != nil {
+		return activePackSet{}, err
+	}
+
+	packSet, ok := resolvePackSet(versions, schedules, at)
+	if !ok {
+		return activePackSet{}, ErrNoPackSizesConfigured
+	}
+
+	return packSet, nil
+}
+
+// GetNamespaces returns every namespace holding a pack set or a schedule
+func (s *Service) GetNamespaces(ctx context.Context) (GetNamespacesResponse, error) {
+	namespaces, err := s.store.Namespaces(ctx)
+	if err != nil {
+		return GetNamespacesResponse{}, err
+	}
+
+	return GetNamespacesResponse{Namespaces: namespaces}, nil
+}
+
+// GetPackSizes returns all pack sizes of a namespace in descending order (largest to smallest)
+func (s *Service) GetPackSizes(ctx context.Context, namespace string) (GetPackSizesResponse, error) {
+	repo, err := s.namespace(namespace)
+	if err != nil {
+		return GetPackSizesResponse{}, err
+	}
+
+	packSet, err := repo.Current(ctx)
+	if err != nil {
+		return GetPackSizesResponse{}, err
+	}
+
+	return GetPackSizesResponse{Sizes: packSet.Sizes, Version: packSet.Version}, nil
+}
+
+// AddPackSize validates a new pack size and adds it to the pack set as a new version
+func (s *Service) AddPackSize(ctx context.Context, req AddPackSizeRequest) error {
+	if err := validatePackSize(req.Size, s.cfg); err != nil {
+		return err
+	}
+
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	_, version, err := repo.Update(ctx, req.Actor, func(current []int) ([]int, error) {
+		next := append(current, req.Size)
+		if err := validatePackSet(next, s.cfg); err != nil {
+			return nil, err
+		}
+
+		return next, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Build the new set's lookup table now rather than on its first calculation
+	s.tables.build(version.Sizes)
+
+	return nil
+}
+
+// RemovePackSize removes a pack size from the pack set as a new version.
+// Unless configured otherwise, the last remaining size cannot be removed.
+func (s *Service) RemovePackSize(ctx context.Context, req RemovePackSizeRequest) error {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	_, version, err := repo.Update(ctx, req.Actor, func(current []int) ([]int, error) {
+		i := slices.Index(current, req.Size)
+		if i < 0 {
+			return nil, ErrNotFoundPackSize
+		}
+
+		if s.cfg.KeepLastSize && len(current) == 1 {
+			return nil, ErrLastPackSize
+		}
+
+		return slices.Delete(current, i, i+1), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.tables.build(version.Sizes)
+
+	return nil
+}
+
+// ReplacePackSizes validates a new pack set and atomically swaps it in for the whole
+// current set as a new version, so concurrent calculations see either the previous
+// or the new set
+func (s *Service) ReplacePackSizes(ctx context.Context, req ReplacePackSizesRequest) (PackSetChangeResponse, error) {
+	if err := validatePackSet(req.Sizes, s.cfg); err != nil {
+		return PackSetChangeResponse{}, err
+	}
+
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return PackSetChangeResponse{}, err
+	}
+
+	return s.swapPackSet(ctx, repo, req.Actor, req.Sizes)
+}
+
+// GetPackSetVersions returns every recorded version of the pack set of a namespace, oldest first
+func (s *Service) GetPackSetVersions(ctx context.Context, namespace string) (GetPackSetVersionsResponse, error) {
+	repo, err := s.namespace(namespace)
+	if err != nil {
+		return GetPackSetVersionsResponse{}, err
+	}
+
+	versions, err := repo.Versions(ctx)
+	if err != nil {
+		return GetPackSetVersionsResponse{}, err
+	}
+
+	return GetPackSetVersionsResponse{Versions: versions}, nil
+}
+
+// GetPackSetVersion returns a single recorded version of the pack set
+func (s *Service) GetPackSetVersion(ctx context.Context, req GetPackSetVersionRequest) (model.PackSetVersion, error) {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return model.PackSetVersion{}, err
+	}
+
+	return repo.Version(ctx, req.Version)
+}
+
+// RollbackPackSizes swaps a recorded version back in as the live pack set. The rollback
+// is recorded as a new version, so history is never rewritten.
+func (s *Service) RollbackPackSizes(ctx context.Context, req RollbackPackSizesRequest) (PackSetChangeResponse, error) {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return PackSetChangeResponse{}, err
+	}
+
+	target, err := repo.Version(ctx, req.Version)
+	if err != nil {
+		return PackSetChangeResponse{}, err
+	}
+
+	// Limits may have tightened since the version was recorded
+	if err := validatePackSet(target.Sizes, s.cfg); err != nil {
+		return PackSetChangeResponse{}, err
+	}
+
+	return s.swapPackSet(ctx, repo, req.Actor, target.Sizes)
+}
+
+// swapPackSet replaces the whole pack set with a validated set of sizes and builds the
+// lookup table of the new set
+func (s *Service) swapPackSet(ctx context.Context, repo repository.PackSizeRepository, actor string, sizes []int) (PackSetChangeResponse, error) {
+	previous, version, err := repo.Update(ctx, actor, func([]int) ([]int, error) {
+		return sizes, nil
+	})
+	if err != nil {
+		return PackSetChangeResponse{}, err
+	}
+
+	s.tables.build(version.Sizes)
+
+	return PackSetChangeResponse{
+		Previous: previous,
+		Current:  version.Sizes,
+		Version:  version.Version,
+	}, nil
+}
+
+// SchedulePackSizes validates a pack set and stages it to become the live set at a future time
+func (s *Service) SchedulePackSizes(ctx context.Context, req SchedulePackSizesRequest) (model.PackSetSchedule, error) {
+	if err := validatePackSet(req.Sizes, s.cfg); err != nil {
+		return model.PackSetSchedule{}, err
+	}
+
+	if !req.EffectiveFrom.After(s.now()) {
+		return model.PackSetSchedule{}, ErrInvalidEffectiveFrom
+	}
+
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return model.PackSetSchedule{}, err
+	}
+
+	return repo.Schedule(ctx, req.Actor, req.Sizes, req.EffectiveFrom)
+}
+
+// GetPackSetSchedules returns every pack set schedule of a namespace ordered by effective time
+func (s *Service) GetPackSetSchedules(ctx context.Context, namespace string) (GetPackSetSchedulesResponse, error) {
+	repo, err := s.namespace(namespace)
+	if err != nil {
+		return GetPackSetSchedulesResponse{}, err
+	}
+
+	schedules, err := repo.Schedules(ctx)
+	if err != nil {
+		return GetPackSetSchedulesResponse{}, err
+	}
+
+	return GetPackSetSchedulesResponse{Schedules: schedules}, nil
+}
+
+// CancelPackSetSchedule cancels a schedule that has not taken effect yet
+func (s *Service) CancelPackSetSchedule(ctx context.Context, req CancelPackSetScheduleRequest) error {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	return repo.CancelSchedule(ctx, req.ID)
+}
+
+// ActivateDueSchedules swaps in every pending schedule whose effective time has come, in
+// every namespace, and returns the schedules it settled. Schedules settled or cancelled
+// concurrently, e.g. by another instance, are skipped.
+func (s *Service) ActivateDueSchedules(ctx context.Context) ([]model.PackSetSchedule, error) {
+	namespaces, err := s.store.Namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+
+	var settled []model.PackSetSchedule
+	for _, namespace := range namespaces {
+		repo, err := s.store.Namespace(namespace)
+		if err != nil {
+			return settled, err
+		}
+
+		activated, err := activateDueSchedules(ctx, repo, now)
+		settled = append(settled, activated...)
+
+		if err != nil {
+			return settled, err
+		}
+	}
+
+	return settled, nil
+}
+
+// activateDueSchedules swaps in the pending schedules of a namespace that are due at now,
+// in effective order
+func activateDueSchedules(ctx context.Context, repo repository.PackSizeRepository, now time.Time) ([]model.PackSetSchedule, error) {
+	schedules, err := repo.Schedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var settled []model.PackSetSchedule
+	for _, schedule := range schedules {
+		if schedule.EffectiveFrom.After(now) {
+			break
+		}
+
+		if schedule.Status != model.ScheduleStatusPending {
+			continue
+		}
+
+		schedule, err := repo.ActivateSchedule(ctx, schedule.ID)
+		if errors.Is(err, repository.ErrScheduleNotPending) || errors.Is(err, repository.ErrScheduleNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return settled, err
+		}
+
+		settled = append(settled, schedule)
+	}
+
+	return settled, nil
+}
+
+// GetPackStock returns the packs in stock by size for a namespace
+func (s *Service) GetPackStock(ctx context.Context, namespace string) (GetPackStockResponse, error) {
+	repo, err := s.namespace(namespace)
+	if err != nil {
+		return GetPackStockResponse{}, err
+	}
+
+	stock, err := repo.Stock(ctx)
+	if err != nil {
+		return GetPackStockResponse{}, err
+	}
+
+	return GetPackStockResponse{Stock: stock}, nil
+}
+
+// SetPackStock sets the stock levels of pack sizes in the live set
+func (s *Service) SetPackStock(ctx context.Context, req SetPackStockRequest) error {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	current, err := repo.Current(ctx)
+	if err != nil {
+		return err
+	}
+
+	for size, level := range req.Levels {
+		if level < 0 {
+			return fmt.Errorf("%w: %d packs of %d", ErrInvalidStockLevel, level, size)
+		}
+
+		if !slices.Contains(current.Sizes, size) {
+			return fmt.Errorf("%w: %d", ErrNotFoundPackSize, size)
+		}
+	}
+
+	return repo.SetStock(ctx, req.Levels)
+}
+
+// GetPackCosts returns what every pack size and every surplus item costs in a namespace
+func (s *Service) GetPackCosts(ctx context.Context, namespace string) (model.PackCosts, error) {
+	repo, err := s.namespace(namespace)
+	if err != nil {
+		return model.PackCosts{}, err
+	}
+
+	costs, err := repo.Costs(ctx)
+	if err != nil {
+		return model.PackCosts{}, err
+	}
+
+	if costs.Packs == nil {
+		costs.Packs = []model.Pack{}
+	}
+
+	return costs, nil
+}
+
+// SetPackCosts replaces what the pack sizes of the live set and surplus items cost
+func (s *Service) SetPackCosts(ctx context.Context, req SetPackCostsRequest) error {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	current, err := repo.Current(ctx)
+	if err != nil {
+		return err
+	}
+
+	if req.SurplusItemCost < 0 {
+		return fmt.Errorf("%w: surplus item cost %d", ErrInvalidPackCost, req.SurplusItemCost)
+	}
+
+	seen := make(map[int]struct{}, len(req.Packs))
+	for _, pack := range req.Packs {
+		if pack.MaterialCost < 0 || pack.HandlingCost < 0 {
+			return fmt.Errorf("%w: negative cost of %d", ErrInvalidPackCost, pack.Size)
+		}
+
+		if _, ok := seen[pack.Size]; ok {
+			return fmt.Errorf("%w: %d is priced twice", ErrInvalidPackCost, pack.Size)
+		}
+
+		if !slices.Contains(current.Sizes, pack.Size) {
+			return fmt.Errorf("%w: %d", ErrNotFoundPackSize, pack.Size)
+		}
+
+		seen[pack.Size] = struct{}{}
+	}
+
+	return repo.SetCosts(ctx, req.PackCosts)
+}
+
+// GetPackSpecs returns the weight and dimensions of every pack size
+func (s *Service) GetPackSpecs(ctx context.Context, namespace string) (GetPackSpecsResponse, error) {
+	repo, err := s.namespace(namespace)
+	if err != nil {
+		return GetPackSpecsResponse{}, err
+	}
+
+	specs, err := repo.Specs(ctx)
+	if err != nil {
+		return GetPackSpecsResponse{}, err
+	}
+
+	if specs == nil {
+		specs = []model.PackSpec{}
+	}
+
+	return GetPackSpecsResponse{Specs: specs}, nil
+}
+
+// SetPackSpecs replaces the weight and dimensions of the pack sizes of the live set
+func (s *Service) SetPackSpecs(ctx context.Context, req SetPackSpecsRequest) error {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	current, err := repo.Current(ctx)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[int]struct{}, len(req.Specs))
+	for _, spec := range req.Specs {
+		if spec.GrossWeight < 0 || spec.Length < 0 || spec.Width < 0 || spec.Height < 0 {
+			return fmt.Errorf("%w: negative weight or dimension of %d", ErrInvalidPackSpec, spec.Size)
+		}
+
+		if _, ok := seen[spec.Size]; ok {
+			return fmt.Errorf("%w: %d is specified twice", ErrInvalidPackSpec, spec.Size)
+		}
+
+		if !slices.Contains(current.Sizes, spec.Size) {
+			return fmt.Errorf("%w: %d", ErrNotFoundPackSize, spec.Size)
+		}
+
+		seen[spec.Size] = struct{}{}
+	}
+
+	return repo.SetSpecs(ctx, req.Specs)
+}
+
+// GetPackConstraints returns the fewest and most packs of each size a combination may hold
+func (s *Service) GetPackConstraints(ctx context.Context, namespace string) (model.PackConstraints, error) {
+	repo, err := s.namespace(namespace)
+	if err != nil {
+		return model.PackConstraints{}, err
+	}
+
+	constraints, err := repo.Constraints(ctx)
+	if err != nil {
+		return model.PackConstraints{}, err
+	}
+
+	return mergeConstraints(constraints, model.PackConstraints{}), nil
+}
+
+// SetPackConstraints replaces the pack count constraints. They may only bound sizes of
+// the live set, and apply to every calculation against a set holding those sizes.
+func (s *Service) SetPackConstraints(ctx context.Context, req SetPackConstraintsRequest) error {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	current, err := repo.Current(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := validateConstraints(req.PackConstraints, current.Sizes); err != nil {
+		return err
+	}
+
+	return repo.SetConstraints(ctx, req.PackConstraints)
+}
+
+// GetCustomers returns every customer profile
+func (s *Service) GetCustomers(ctx context.Context, namespace string) (GetCustomersResponse, error) {
+	repo, err := s.namespace(namespace)
+	if err != nil {
+		return GetCustomersResponse{}, err
+	}
+
+	profiles, err := repo.Customers(ctx)
+	if err != nil {
+		return GetCustomersResponse{}, err
+	}
+
+	return GetCustomersResponse{Customers: profiles}, nil
+}
+
+// GetCustomer returns a single customer profile
+func (s *Service) GetCustomer(ctx context.Context, req CustomerRequest) (model.CustomerProfile, error) {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return model.CustomerProfile{}, err
+	}
+
+	return repo.Customer(ctx, req.ID)
+}
+
+// SetCustomer stores the pack sizes a customer's orders ship in, replacing any profile
+// the customer had. Sizes need not be in the live set, so a profile may exclude a size
+// before it is added.
+func (s *Service) SetCustomer(ctx context.Context, req SetCustomerRequest) (model.CustomerProfile, error) {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return model.CustomerProfile{}, err
+	}
+
+	profile := model.CustomerProfile{
+		ID:            req.ID,
+		AllowedSizes:  req.AllowedSizes,
+		ExcludedSizes: req.ExcludedSizes,
+	}
+
+	if err := validateCustomerProfile(profile); err != nil {
+		return model.CustomerProfile{}, err
+	}
+
+	if profile.AllowedSizes == nil {
+		profile.AllowedSizes = []int{}
+	}
+
+	if profile.ExcludedSizes == nil {
+		profile.ExcludedSizes = []int{}
+	}
+
+	if err := repo.SetCustomer(ctx, profile); err != nil {
+		return model.CustomerProfile{}, err
+	}
+
+	return profile, nil
+}
+
+// DeleteCustomer removes a customer profile
+func (s *Service) DeleteCustomer(ctx context.Context, req CustomerRequest) error {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	return repo.DeleteCustomer(ctx, req.ID)
+}
+
+// GetPackaging returns the cartons and pallet packs ship in
+func (s *Service) GetPackaging(ctx context.Context, namespace string) (model.Packaging, error) {
+	repo, err := s.namespace(namespace)
+	if err != nil {
+		return model.Packaging{}, err
+	}
+
+	packaging, err := repo.Packaging(ctx)
+	if err != nil {
+		return model.Packaging{}, err
+	}
+
+	if packaging.Cartons == nil {
+		packaging.Cartons = []model.CartonType{}
+	}
+
+	return packaging, nil
+}
+
+// SetPackaging replaces the packaging hierarchy. Cartons may only take sizes of the live set.
+func (s *Service) SetPackaging(ctx context.Context, req SetPackagingRequest) error {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	current, err := repo.Current(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := validatePackaging(req.Packaging, current.Sizes); err != nil {
+		return err
+	}
+
+	return repo.SetPackaging(ctx, req.Packaging)
+}
+
+// ClearPackStock stops tracking the stock of a pack size, making it unlimited again
+func (s *Service) ClearPackStock(ctx context.Context, req ClearPackStockRequest) error {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	return repo.ClearStock(ctx, req.Size)
+}
+
+// ConfirmPackReservation confirms a held reservation once its order ships, keeping its
+// packs out of stock
+func (s *Service) ConfirmPackReservation(ctx context.Context, req PackReservationRequest) (model.PackReservation, error) {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return model.PackReservation{}, err
+	}
+
+	return repo.ConfirmReservation(ctx, req.ID)
+}
+
+// ReleasePackReservation releases a held reservation, returning its packs to stock
+func (s *Service) ReleasePackReservation(ctx context.Context, req PackReservationRequest) (model.PackReservation, error) {
+	repo, err := s.namespace(req.Namespace)
+	if err != nil {
+		return model.PackReservation{}, err
+	}
+
+	return repo.ReleaseReservation(ctx, req.ID)
+}
+
+// ExpirePackReservations releases every reservation whose TTL ran out, in every namespace,
+// and returns the reservations it released
+func (s *Service) ExpirePackReservations(ctx context.Context) ([]model.PackReservation, error) {
+	namespaces, err := s.store.Namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+
+	var expired []model.PackReservation
+	for _, namespace := range namespaces {
+		repo, err := s.store.Namespace(namespace)
+		if err != nil {
+			return expired, err
+		}
+
+		released, err := repo.ExpireReservations(ctx, now)
+		expired = append(expired, released...)
+
+		if err != nil {
+			return expired, err
+		}
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,1420 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Amir-Sadati/order-packing/internal/config"
+	"github.com/Amir-Sadati/order-packing/internal/model"
+	"github.com/Amir-Sadati/order-packing/internal/repository"
+)
+
+// testPackSizeStore is an in-memory store whose default namespace starts with given sizes
+type testPackSizeStore struct {
+	*repository.MemoryPackSizeStore
+	defaultRepo *repository.MemoryPackSizeRepository
+}
+
+// Namespace returns the seeded repository for the default namespace
+func (s testPackSizeStore) Namespace(namespace string) (repository.PackSizeRepository, error) {
+	if namespace == repository.DefaultNamespace {
+		return s.defaultRepo, nil
+	}
+
+	return s.MemoryPackSizeStore.Namespace(namespace)
+}
+
+func newTestService(sizes ...int) *Service {
+	store := testPackSizeStore{
+		MemoryPackSizeStore: repository.NewMemoryPackSizeStore(),
+		defaultRepo:         repository.NewMemoryPackSizeRepository(sizes...),
+	}
+
+	return NewService(store, &config.PackConfig{
+		MaxComputeNodes: 5_000_000,
+		MinPackSize:     1,
+		MaxPackSize:     1_000_000,
+		MaxPackSizes:    5,
+		KeepLastSize:    true,
+		ReservationTTL:  time.Minute,
+	})
+}
+
+func TestServiceCalculatePack(t *testing.T) {
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	result, err := s.CalculatePack(context.Background(), CalculatePackRequest{OrderItemQuantity: 12001})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	expected := []PackLine{{Size: 5000, Count: 2}, {Size: 2000, Count: 1}, {Size: 250, Count: 1}}
+	if !reflect.DeepEqual(result.PackList, expected) {
+		t.Errorf("CalculatePack() pack list = %v, want %v", result.PackList, expected)
+	}
+
+	if result.ShippedQuantity != 12250 || result.Surplus != 249 || result.PackCount != 4 {
+		t.Errorf("CalculatePack() shipped = %d, surplus = %d, packs = %d, want 12250, 249, 4",
+			result.ShippedQuantity, result.Surplus, result.PackCount)
+	}
+}
+
+func TestServiceCalculatePackErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		sizes       []int
+		req         CalculatePackRequest
+		expectedErr error
+	}{
+		{
+			name:        "Invalid quantity",
+			sizes:       []int{250},
+			req:         CalculatePackRequest{OrderItemQuantity: 0},
+			expectedErr: ErrInvalidOrderItemQuantity,
+		},
+		{
+			name:        "Too many alternatives",
+			sizes:       []int{250},
+			req:         CalculatePackRequest{OrderItemQuantity: 1, Alternatives: maxAlternatives + 1},
+			expectedErr: ErrInvalidAlternatives,
+		},
+		{
+			name:        "Empty pack set",
+			sizes:       nil,
+			req:         CalculatePackRequest{OrderItemQuantity: 1},
+			expectedErr: ErrNoPackSizesConfigured,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestService(tt.sizes...).CalculatePack(context.Background(), tt.req)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("CalculatePack() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestServiceCalculatePackBatch(t *testing.T) {
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	result, err := s.CalculatePackBatch(context.Background(), CalculatePackBatchRequest{
+		Lines: []CalculatePackBatchLine{
+			{ID: "a", OrderItemQuantity: 251},
+			{ID: "b", OrderItemQuantity: -1},
+			{OrderItemQuantity: 5000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CalculatePackBatch() error = %v", err)
+	}
+
+	if len(result.Lines) != 3 {
+		t.Fatalf("CalculatePackBatch() returned %d lines, want 3", len(result.Lines))
+	}
+
+	if line := result.Lines[0]; line.ID != "a" || line.Result == nil || line.Result.ShippedQuantity != 500 {
+		t.Errorf("CalculatePackBatch() line a = %+v, want 500 items shipped", line)
+	}
+
+	if line := result.Lines[1]; line.ID != "b" || line.Result != nil || line.Error != ErrInvalidOrderItemQuantity.Error() {
+		t.Errorf("CalculatePackBatch() line b = %+v, want %v", line, ErrInvalidOrderItemQuantity)
+	}
+
+	if line := result.Lines[2]; line.Result == nil || line.Result.PackCount != 1 {
+		t.Errorf("CalculatePackBatch() line 3 = %+v, want a single pack", line)
+	}
+
+	if _, err := s.CalculatePackBatch(context.Background(), CalculatePackBatchRequest{}); !errors.Is(err, ErrInvalidBatchSize) {
+		t.Errorf("CalculatePackBatch() with no lines error = %v, want %v", err, ErrInvalidBatchSize)
+	}
+}
+
+func TestServiceCalculatePackBatchSharesBudget(t *testing.T) {
+	// Sizes this close leave remainders too large for a lookup table, so every line is
+	// searched and takes about 4,000,000 of the 5,000,000 nodes
+	s := newTestService(999983, 999979)
+
+	result, err := s.CalculatePackBatch(context.Background(), CalculatePackBatchRequest{
+		Lines: []CalculatePackBatchLine{
+			{ID: "a", OrderItemQuantity: 1_000_000},
+			{ID: "b", OrderItemQuantity: 1_000_000},
+			{ID: "c", OrderItemQuantity: 999983},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CalculatePackBatch() error = %v", err)
+	}
+
+	if line := result.Lines[0]; line.Result == nil {
+		t.Errorf("CalculatePackBatch() line a error = %q, want a result", line.Error)
+	}
+
+	if line := result.Lines[1]; line.Result != nil || !strings.HasPrefix(line.Error, ErrComputationBudgetExceeded.Error()) {
+		t.Errorf("CalculatePackBatch() line b = %+v, want %v", line, ErrComputationBudgetExceeded)
+	}
+
+	// Exact matches take no search, so they still fit once the budget is spent
+	if line := result.Lines[2]; line.Result == nil || line.Result.PackCount != 1 {
+		t.Errorf("CalculatePackBatch() line c = %+v, want a single pack", line)
+	}
+
+	// Every batch starts with the whole budget
+	result, err = s.CalculatePackBatch(context.Background(), CalculatePackBatchRequest{
+		Lines: []CalculatePackBatchLine{{ID: "b", OrderItemQuantity: 1_000_000}},
+	})
+	if err != nil || result.Lines[0].Result == nil {
+		t.Errorf("CalculatePackBatch() = %+v, error = %v, want a result", result, err)
+	}
+}
+
+func TestServiceAddPackSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		sizes       []int
+		size        int
+		expectedErr error
+	}{
+		{name: "New size", sizes: []int{250}, size: 500, expectedErr: nil},
+		{name: "Zero size", sizes: []int{250}, size: 0, expectedErr: ErrInvalidPackSize},
+		{name: "Existing size", sizes: []int{250}, size: 250, expectedErr: ErrPackSizeExists},
+		{name: "Too many sizes", sizes: []int{1, 2, 3, 4, 5}, size: 6, expectedErr: ErrTooManyPackSizes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestService(tt.sizes...).AddPackSize(context.Background(), AddPackSizeRequest{Size: tt.size})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("AddPackSize() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestServiceRemovePackSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		sizes       []int
+		size        int
+		expectedErr error
+	}{
+		{name: "Existing size", sizes: []int{250, 500}, size: 500, expectedErr: nil},
+		{name: "Missing size", sizes: []int{250, 500}, size: 1000, expectedErr: ErrNotFoundPackSize},
+		{name: "Last size", sizes: []int{250}, size: 250, expectedErr: ErrLastPackSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestService(tt.sizes...).RemovePackSize(context.Background(), RemovePackSizeRequest{Size: tt.size})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("RemovePackSize() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestServiceReplacePackSizes(t *testing.T) {
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	result, err := s.ReplacePackSizes(context.Background(), ReplacePackSizesRequest{Sizes: []int{23, 53, 31}})
+	if err != nil {
+		t.Fatalf("ReplacePackSizes() error = %v", err)
+	}
+
+	expected := PackSetChangeResponse{
+		Previous: []int{5000, 2000, 1000, 500, 250},
+		Current:  []int{53, 31, 23},
+		Version:  1,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ReplacePackSizes() = %+v, want %+v", result, expected)
+	}
+
+	if _, err := s.ReplacePackSizes(context.Background(), ReplacePackSizesRequest{Sizes: []int{250, 0}}); !errors.Is(err, ErrInvalidPackSize) {
+		t.Errorf("ReplacePackSizes() with a zero size error = %v, want %v", err, ErrInvalidPackSize)
+	}
+
+	sizes, err := s.GetPackSizes(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetPackSizes() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(sizes.Sizes, []int{53, 31, 23}) {
+		t.Errorf("GetPackSizes() after a rejected replace = %v, want [53 31 23]", sizes.Sizes)
+	}
+}
+
+func TestServiceRollbackPackSizes(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	for _, sizes := range [][]int{{250, 500, 1000}, {23, 31, 53}} {
+		if _, err := s.ReplacePackSizes(ctx, ReplacePackSizesRequest{Sizes: sizes, Actor: "alice"}); err != nil {
+			t.Fatalf("ReplacePackSizes(%v) error = %v", sizes, err)
+		}
+	}
+
+	result, err := s.RollbackPackSizes(ctx, RollbackPackSizesRequest{Version: 1, Actor: "bob"})
+	if err != nil {
+		t.Fatalf("RollbackPackSizes() error = %v", err)
+	}
+
+	expected := PackSetChangeResponse{
+		Previous: []int{53, 31, 23},
+		Current:  []int{1000, 500, 250},
+		Version:  3,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("RollbackPackSizes() = %+v, want %+v", result, expected)
+	}
+
+	versions, err := s.GetPackSetVersions(ctx, "")
+	if err != nil {
+		t.Fatalf("GetPackSetVersions() error = %v", err)
+	}
+
+	if len(versions.Versions) != 3 || versions.Versions[2].Actor != "bob" {
+		t.Errorf("GetPackSetVersions() = %+v, want 3 versions with the last by bob", versions.Versions)
+	}
+
+	if _, err := s.RollbackPackSizes(ctx, RollbackPackSizesRequest{Version: 9}); !errors.Is(err, repository.ErrVersionNotFound) {
+		t.Errorf("RollbackPackSizes() to a missing version error = %v, want %v", err, repository.ErrVersionNotFound)
+	}
+}
+
+func TestServiceCalculatePackWithPackSetVersion(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	for _, sizes := range [][]int{{250, 500, 1000}, {23, 31, 53}} {
+		if _, err := s.ReplacePackSizes(ctx, ReplacePackSizesRequest{Sizes: sizes}); err != nil {
+			t.Fatalf("ReplacePackSizes(%v) error = %v", sizes, err)
+		}
+	}
+
+	result, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251, PackSetVersion: 1})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	if result.PackSetVersion != 1 || !reflect.DeepEqual(result.Packs, map[int]int{500: 1}) {
+		t.Errorf("CalculatePack() against version 1 = %+v, want {500: 1} at version 1", result)
+	}
+
+	result, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	if result.PackSetVersion != 2 {
+		t.Errorf("CalculatePack() against the live set ran at version %d, want 2", result.PackSetVersion)
+	}
+
+	if _, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251, PackSetVersion: 7}); !errors.Is(err, repository.ErrVersionNotFound) {
+		t.Errorf("CalculatePack() against a missing version error = %v, want %v", err, repository.ErrVersionNotFound)
+	}
+}
+
+func TestServiceSchedulePackSizes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := newTestService(250, 500, 1000)
+	s.now = func() time.Time { return now }
+
+	if _, err := s.SchedulePackSizes(ctx, SchedulePackSizesRequest{Sizes: []int{23, 31}, EffectiveFrom: now}); !errors.Is(err, ErrInvalidEffectiveFrom) {
+		t.Errorf("SchedulePackSizes() effective now error = %v, want %v", err, ErrInvalidEffectiveFrom)
+	}
+
+	if _, err := s.SchedulePackSizes(ctx, SchedulePackSizesRequest{Sizes: []int{23, 0}, EffectiveFrom: now.Add(time.Hour)}); !errors.Is(err, ErrInvalidPackSize) {
+		t.Errorf("SchedulePackSizes() with a zero size error = %v, want %v", err, ErrInvalidPackSize)
+	}
+
+	schedule, err := s.SchedulePackSizes(ctx, SchedulePackSizesRequest{Sizes: []int{23, 31, 53}, EffectiveFrom: now.Add(time.Hour), Actor: "alice"})
+	if err != nil {
+		t.Fatalf("SchedulePackSizes() error = %v", err)
+	}
+
+	result, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Packs, map[int]int{500: 1}) || result.PackSetScheduleID != 0 {
+		t.Errorf("CalculatePack() before the schedule = %+v, want {500: 1} from the live set", result)
+	}
+
+	result, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251, AsOf: now.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	if result.PackSetScheduleID != schedule.ID || result.ShippedQuantity != 251 {
+		t.Errorf("CalculatePack() as of after the schedule = %+v, want exactly 251 items from schedule %d", result, schedule.ID)
+	}
+
+	if _, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251, AsOf: now, PackSetVersion: 1}); !errors.Is(err, ErrAmbiguousPackSet) {
+		t.Errorf("CalculatePack() with both a version and a time error = %v, want %v", err, ErrAmbiguousPackSet)
+	}
+
+	// The schedule is active once due, even before the activator swaps it in
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+	result, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	if result.PackSetScheduleID != schedule.ID {
+		t.Errorf("CalculatePack() after the schedule is due ran against schedule %d, want %d", result.PackSetScheduleID, schedule.ID)
+	}
+}
+
+func TestServiceActivateDueSchedules(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := newTestService(250, 500, 1000)
+	s.now = func() time.Time { return now }
+
+	for _, offset := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		if _, err := s.SchedulePackSizes(ctx, SchedulePackSizesRequest{Sizes: []int{int(offset / time.Hour)}, EffectiveFrom: now.Add(offset)}); err != nil {
+			t.Fatalf("SchedulePackSizes() error = %v", err)
+		}
+	}
+
+	s.now = func() time.Time { return now.Add(150 * time.Minute) }
+
+	settled, err := s.ActivateDueSchedules(ctx)
+	if err != nil {
+		t.Fatalf("ActivateDueSchedules() error = %v", err)
+	}
+
+	if len(settled) != 2 || settled[0].Status != model.ScheduleStatusActivated || settled[1].ActivatedVersion != 2 {
+		t.Fatalf("ActivateDueSchedules() = %+v, want the first two schedules activated as versions 1 and 2", settled)
+	}
+
+	sizes, err := s.GetPackSizes(ctx, "")
+	if err != nil {
+		t.Fatalf("GetPackSizes() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(sizes.Sizes, []int{2}) || sizes.Version != 2 {
+		t.Errorf("GetPackSizes() after activation = %+v, want [2] at version 2", sizes)
+	}
+
+	if settled, err := s.ActivateDueSchedules(ctx); err != nil || len(settled) != 0 {
+		t.Errorf("ActivateDueSchedules() again = %v, %v, want nothing settled", settled, err)
+	}
+
+	if err := s.CancelPackSetSchedule(ctx, CancelPackSetScheduleRequest{ID: 1}); !errors.Is(err, repository.ErrScheduleNotPending) {
+		t.Errorf("CancelPackSetSchedule() of an activated schedule error = %v, want %v", err, repository.ErrScheduleNotPending)
+	}
+
+	if err := s.CancelPackSetSchedule(ctx, CancelPackSetScheduleRequest{ID: 3}); err != nil {
+		t.Errorf("CancelPackSetSchedule() error = %v", err)
+	}
+}
+
+func TestServiceNamespaces(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000)
+
+	if _, err := s.ReplacePackSizes(ctx, ReplacePackSizesRequest{Sizes: []int{23, 31, 53}, Namespace: "bolts"}); err != nil {
+		t.Fatalf("ReplacePackSizes() in bolts error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		namespace     string
+		expectedSizes []int
+		expectedErr   error
+	}{
+		{name: "Default namespace", namespace: "", expectedSizes: []int{1000, 500, 250}},
+		{name: "Named namespace", namespace: "bolts", expectedSizes: []int{53, 31, 23}},
+		{name: "Empty namespace", namespace: "screws", expectedErr: ErrNoPackSizesConfigured},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sizes, err := s.GetPackSizes(ctx, tt.namespace)
+			if err != nil {
+				t.Fatalf("GetPackSizes(%q) error = %v", tt.namespace, err)
+			}
+
+			if tt.expectedErr == nil && !reflect.DeepEqual(sizes.Sizes, tt.expectedSizes) {
+				t.Errorf("GetPackSizes(%q) = %v, want %v", tt.namespace, sizes.Sizes, tt.expectedSizes)
+			}
+
+			_, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251, Namespace: tt.namespace})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("CalculatePack() in %q error = %v, want %v", tt.namespace, err, tt.expectedErr)
+			}
+		})
+	}
+
+	namespaces, err := s.GetNamespaces(ctx)
+	if err != nil {
+		t.Fatalf("GetNamespaces() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(namespaces.Namespaces, []string{"bolts", "default"}) {
+		t.Errorf("GetNamespaces() = %v, want [bolts default]", namespaces.Namespaces)
+	}
+}
+
+func TestServiceActivateDueSchedulesAcrossNamespaces(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := newTestService(250, 500, 1000)
+	s.now = func() time.Time { return now }
+
+	for _, namespace := range []string{"", "bolts"} {
+		if _, err := s.SchedulePackSizes(ctx, SchedulePackSizesRequest{Sizes: []int{42}, EffectiveFrom: now.Add(time.Hour), Namespace: namespace}); err != nil {
+			t.Fatalf("SchedulePackSizes() in %q error = %v", namespace, err)
+		}
+	}
+
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+	settled, err := s.ActivateDueSchedules(ctx)
+	if err != nil {
+		t.Fatalf("ActivateDueSchedules() error = %v", err)
+	}
+
+	if len(settled) != 2 || settled[0].Namespace != "bolts" || settled[1].Namespace != "default" {
+		t.Errorf("ActivateDueSchedules() = %+v, want one schedule settled in bolts and one in default", settled)
+	}
+}
+
+func TestServiceCalculateOrder(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	for sku, sizes := range map[string][]int{"BOLT-M8": {250, 500, 1000}, "nut.m8": {23, 31, 53}} {
+		namespace := repository.SKUNamespace("hardware", sku)
+		if _, err := s.ReplacePackSizes(ctx, ReplacePackSizesRequest{Sizes: sizes, Namespace: namespace}); err != nil {
+			t.Fatalf("ReplacePackSizes() in %s error = %v", namespace, err)
+		}
+	}
+
+	// The same SKU in another namespace has its own pack set
+	if _, err := s.ReplacePackSizes(ctx, ReplacePackSizesRequest{Sizes: []int{1}, Namespace: repository.SKUNamespace("other", "BOLT-M8")}); err != nil {
+		t.Fatalf("ReplacePackSizes() error = %v", err)
+	}
+
+	result, err := s.CalculateOrder(ctx, CalculateOrderRequest{Namespace: "hardware", Lines: []OrderLine{
+		{SKU: "BOLT-M8", Quantity: 251},
+		{SKU: "nut.m8", Quantity: 263},
+		{SKU: "BOLT-M8", Quantity: 750},
+		{SKU: "washer.m8", Quantity: 10},
+		{SKU: "BOLT-M8", Quantity: 0},
+		{SKU: "bolt m8", Quantity: 10},
+	}})
+	if err != nil {
+		t.Fatalf("CalculateOrder() error = %v", err)
+	}
+
+	expectedErrors := []string{"", "", "", ErrNoPackSizesConfigured.Error(), ErrInvalidOrderItemQuantity.Error(), repository.ErrInvalidSKU.Error()}
+	for i, line := range result.Lines {
+		if line.Error != expectedErrors[i] {
+			t.Errorf("CalculateOrder() line %d error = %q, want %q", i, line.Error, expectedErrors[i])
+		}
+	}
+
+	expected := ShipmentSummary{
+		LineCount:       6,
+		FailedLines:     3,
+		OrderedQuantity: 1264,
+		ShippedQuantity: 1513,
+		Surplus:         249,
+		PackCount:       12,
+		PackList: []SKUPackLine{
+			{SKU: "BOLT-M8", Size: 500, Count: 2},
+			{SKU: "BOLT-M8", Size: 250, Count: 1},
+			{SKU: "nut.m8", Size: 31, Count: 7},
+			{SKU: "nut.m8", Size: 23, Count: 2},
+		},
+	}
+	if !reflect.DeepEqual(result.Summary, expected) {
+		t.Errorf("CalculateOrder() summary = %+v, want %+v", result.Summary, expected)
+	}
+
+	if _, err := s.CalculateOrder(ctx, CalculateOrderRequest{}); !errors.Is(err, ErrInvalidOrderLines) {
+		t.Errorf("CalculateOrder() without lines error = %v, want %v", err, ErrInvalidOrderLines)
+	}
+}
+
+func TestServiceCalculateOrderSharesBudget(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	// Each SKU's lines are searched and take about 4,000,000 of the 5,000,000 nodes, as
+	// in TestServiceCalculatePackBatchSharesBudget
+	for _, sku := range []string{"BOLT-M8", "nut.m8"} {
+		namespace := repository.SKUNamespace("hardware", sku)
+		if _, err := s.ReplacePackSizes(ctx, ReplacePackSizesRequest{Sizes: []int{999983, 999979}, Namespace: namespace}); err != nil {
+			t.Fatalf("ReplacePackSizes() in %s error = %v", namespace, err)
+		}
+	}
+
+	result, err := s.CalculateOrder(ctx, CalculateOrderRequest{Namespace: "hardware", Lines: []OrderLine{
+		{SKU: "BOLT-M8", Quantity: 1_000_000},
+		{SKU: "nut.m8", Quantity: 1_000_000},
+		{SKU: "nut.m8", Quantity: 999983},
+	}})
+	if err != nil {
+		t.Fatalf("CalculateOrder() error = %v", err)
+	}
+
+	if line := result.Lines[0]; line.Result == nil {
+		t.Errorf("CalculateOrder() line 0 error = %q, want a result", line.Error)
+	}
+
+	// The budget is shared across SKUs, not reset for each pack set
+	if line := result.Lines[1]; line.Result != nil || !strings.HasPrefix(line.Error, ErrComputationBudgetExceeded.Error()) {
+		t.Errorf("CalculateOrder() line 1 = %+v, want %v", line, ErrComputationBudgetExceeded)
+	}
+
+	if line := result.Lines[2]; line.Result == nil || line.Result.PackCount != 1 {
+		t.Errorf("CalculateOrder() line 2 = %+v, want a single pack", line)
+	}
+}
+
+func TestServiceCalculatePackRespectingStock(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	if err := s.SetPackStock(ctx, SetPackStockRequest{Levels: map[int]int{5000: 0, 2000: 3}}); err != nil {
+		t.Fatalf("SetPackStock() error = %v", err)
+	}
+
+	result, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 12001})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Packs, map[int]int{5000: 2, 2000: 1, 250: 1}) {
+		t.Errorf("CalculatePack() ignoring stock = %v, want {5000: 2, 2000: 1, 250: 1}", result.Packs)
+	}
+
+	result, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 12001, RespectStock: true, Alternatives: 3})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Packs, map[int]int{2000: 3, 1000: 6, 250: 1}) {
+		t.Errorf("CalculatePack() respecting stock = %v, want {2000: 3, 1000: 6, 250: 1}", result.Packs)
+	}
+
+	for _, alternative := range result.Alternatives {
+		if alternative.Packs[5000] > 0 || alternative.Packs[2000] > 3 {
+			t.Errorf("CalculatePack() alternative %v exceeds the stock", alternative.Packs)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		levels      map[int]int
+		expectedErr error
+	}{
+		{name: "Negative level", levels: map[int]int{250: -1}, expectedErr: ErrInvalidStockLevel},
+		{name: "Unknown size", levels: map[int]int{750: 3}, expectedErr: ErrNotFoundPackSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.SetPackStock(ctx, SetPackStockRequest{Levels: tt.levels}); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("SetPackStock(%v) error = %v, want %v", tt.levels, err, tt.expectedErr)
+			}
+		})
+	}
+
+	if err := s.ClearPackStock(ctx, ClearPackStockRequest{Size: 5000}); err != nil {
+		t.Fatalf("ClearPackStock() error = %v", err)
+	}
+
+	stock, err := s.GetPackStock(ctx, "")
+	if err != nil {
+		t.Fatalf("GetPackStock() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(stock.Stock, map[int]int{2000: 3}) {
+		t.Errorf("GetPackStock() = %v, want {2000: 3}", stock.Stock)
+	}
+}
+
+func TestServiceCalculatePackReservingStock(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	if err := s.SetPackStock(ctx, SetPackStockRequest{Levels: map[int]int{5000: 1}}); err != nil {
+		t.Fatalf("SetPackStock() error = %v", err)
+	}
+
+	// Two orders race for the last 5000-pack
+	results := make([]CalculatePackResponse, 2)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var err error
+			results[i], err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 5000, Reserve: true, Actor: "alice"})
+			if err != nil {
+				t.Errorf("CalculatePack() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if t.Failed() {
+		return
+	}
+
+	if results[1].Packs[5000] == 1 {
+		results[0], results[1] = results[1], results[0]
+	}
+
+	winner, loser := results[0], results[1]
+	if !reflect.DeepEqual(winner.Packs, map[int]int{5000: 1}) || !reflect.DeepEqual(winner.Reservation.Packs, map[int]int{5000: 1}) {
+		t.Errorf("CalculatePack() winner = %v holding %v, want {5000: 1} held", winner.Packs, winner.Reservation.Packs)
+	}
+
+	if !reflect.DeepEqual(loser.Packs, map[int]int{2000: 2, 1000: 1}) || len(loser.Reservation.Packs) != 0 {
+		t.Errorf("CalculatePack() loser = %v holding %v, want {2000: 2, 1000: 1} holding nothing", loser.Packs, loser.Reservation.Packs)
+	}
+
+	assertStock := func(expected map[int]int) {
+		t.Helper()
+
+		stock, err := s.GetPackStock(ctx, "")
+		if err != nil {
+			t.Fatalf("GetPackStock() error = %v", err)
+		}
+
+		if !reflect.DeepEqual(stock.Stock, expected) {
+			t.Errorf("GetPackStock() = %v, want %v", stock.Stock, expected)
+		}
+	}
+
+	assertStock(map[int]int{5000: 0})
+
+	released, err := s.ReleasePackReservation(ctx, PackReservationRequest{ID: winner.Reservation.ID})
+	if err != nil {
+		t.Fatalf("ReleasePackReservation() error = %v", err)
+	}
+
+	if released.Status != model.ReservationStatusReleased {
+		t.Errorf("ReleasePackReservation() status = %v, want %v", released.Status, model.ReservationStatusReleased)
+	}
+
+	assertStock(map[int]int{5000: 1})
+
+	if _, err := s.ConfirmPackReservation(ctx, PackReservationRequest{ID: winner.Reservation.ID}); !errors.Is(err, repository.ErrReservationNotFound) {
+		t.Errorf("ConfirmPackReservation() of a released reservation error = %v, want %v", err, repository.ErrReservationNotFound)
+	}
+
+	confirmed, err := s.ConfirmPackReservation(ctx, PackReservationRequest{ID: loser.Reservation.ID})
+	if err != nil {
+		t.Fatalf("ConfirmPackReservation() error = %v", err)
+	}
+
+	if confirmed.Status != model.ReservationStatusConfirmed {
+		t.Errorf("ConfirmPackReservation() status = %v, want %v", confirmed.Status, model.ReservationStatusConfirmed)
+	}
+
+	result, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 5000, Reserve: true})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	// Nothing expires before its TTL runs out
+	if expired, err := s.ExpirePackReservations(ctx); err != nil || len(expired) != 0 {
+		t.Fatalf("ExpirePackReservations() = %v, %v, want nothing expired", expired, err)
+	}
+
+	s.now = func() time.Time {
+		return time.Now().Add(2 * time.Minute)
+	}
+
+	expired, err := s.ExpirePackReservations(ctx)
+	if err != nil {
+		t.Fatalf("ExpirePackReservations() error = %v", err)
+	}
+
+	if len(expired) != 1 || expired[0].ID != result.Reservation.ID || expired[0].Status != model.ReservationStatusExpired {
+		t.Errorf("ExpirePackReservations() = %+v, want reservation %s expired", expired, result.Reservation.ID)
+	}
+
+	assertStock(map[int]int{5000: 1})
+}
+
+func TestServiceCalculatePackByCost(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	req := CalculatePackRequest{OrderItemQuantity: 5000, Objective: ObjectiveCost}
+	if _, err := s.CalculatePack(ctx, req); !errors.Is(err, ErrMissingPackCost) {
+		t.Fatalf("CalculatePack() without costs error = %v, want %v", err, ErrMissingPackCost)
+	}
+
+	costs := SetPackCostsRequest{PackCosts: model.PackCosts{SurplusItemCost: 1}}
+	for _, pack := range testPrices(1).packs {
+		costs.Packs = append(costs.Packs, pack)
+	}
+
+	if err := s.SetPackCosts(ctx, costs); err != nil {
+		t.Fatalf("SetPackCosts() error = %v", err)
+	}
+
+	result, err := s.CalculatePack(ctx, req)
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Packs, map[int]int{2000: 2, 1000: 1}) || result.Cost == nil || result.Cost.Total != 400 {
+		t.Errorf("CalculatePack() by cost = %v costing %+v, want {2000: 2, 1000: 1} costing 400", result.Packs, result.Cost)
+	}
+
+	tests := []struct {
+		name        string
+		req         CalculatePackRequest
+		costs       model.PackCosts
+		expectedErr error
+	}{
+		{
+			name:        "Unknown objective",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, Objective: "speed"},
+			expectedErr: ErrInvalidObjective,
+		},
+		{
+			name:        "Cost with stock",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, Objective: ObjectiveCost, RespectStock: true},
+			expectedErr: ErrUnsupportedCostOption,
+		},
+		{
+			name:        "Negative cost",
+			costs:       model.PackCosts{Packs: []model.Pack{{Size: 250, MaterialCost: -1}}},
+			expectedErr: ErrInvalidPackCost,
+		},
+		{
+			name:        "Size priced twice",
+			costs:       model.PackCosts{Packs: []model.Pack{{Size: 250}, {Size: 250}}},
+			expectedErr: ErrInvalidPackCost,
+		},
+		{
+			name:        "Unknown size",
+			costs:       model.PackCosts{Packs: []model.Pack{{Size: 750}}},
+			expectedErr: ErrNotFoundPackSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.req.OrderItemQuantity > 0 {
+				_, err = s.CalculatePack(ctx, tt.req)
+			} else {
+				err = s.SetPackCosts(ctx, SetPackCostsRequest{PackCosts: tt.costs})
+			}
+
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestServiceCalculatePackRanking(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	percent := func(p float64) *float64 {
+		return &p
+	}
+
+	tests := []struct {
+		name           string
+		req            CalculatePackRequest
+		expectedPacks  map[int]int
+		expectedReason string
+		expectedErr    error
+	}{
+		{
+			name:           "Packs before items",
+			req:            CalculatePackRequest{OrderItemQuantity: 1250, Ranking: Ranking{CriterionPacks, CriterionItems}, Explain: true},
+			expectedPacks:  map[int]int{2000: 1},
+			expectedReason: "ships 3000 fewer items than the next-best combination",
+		},
+		{
+			name:           "Packs before items within the overshoot",
+			req:            CalculatePackRequest{OrderItemQuantity: 1250, Ranking: Ranking{CriterionPacks, CriterionItems}, MaxOvershootPercent: percent(20), Explain: true},
+			expectedPacks:  map[int]int{1000: 1, 250: 1},
+			expectedReason: "ships 250 fewer items than the next-best combination",
+		},
+		{
+			name:        "Default ranking over the overshoot",
+			req:         CalculatePackRequest{OrderItemQuantity: 251, MaxOvershootPercent: percent(5)},
+			expectedErr: ErrOvershootExceeded,
+		},
+		{
+			name:        "Unknown criterion",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, Ranking: Ranking{"weight"}},
+			expectedErr: ErrInvalidRanking,
+		},
+		{
+			name:        "Repeated criterion",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, Ranking: Ranking{CriterionPacks, CriterionPacks}},
+			expectedErr: ErrInvalidRanking,
+		},
+		{
+			name:        "Negative overshoot",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, MaxOvershootPercent: percent(-1)},
+			expectedErr: ErrInvalidTolerance,
+		},
+		{
+			name:        "Ranking with stock",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, Ranking: Ranking{CriterionPacks}, RespectStock: true},
+			expectedErr: ErrUnsupportedRanking,
+		},
+		{
+			name:        "Ranking with cost",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, Ranking: Ranking{CriterionPacks}, Objective: ObjectiveCost},
+			expectedErr: ErrUnsupportedCostOption,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.CalculatePack(ctx, tt.req)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("CalculatePack() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if tt.expectedErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
+				t.Errorf("CalculatePack() packs = %v, want %v", result.Packs, tt.expectedPacks)
+			}
+
+			if result.Explanation == nil || result.Explanation.Reason != tt.expectedReason {
+				t.Errorf("CalculatePack() explanation = %+v, want reason %q", result.Explanation, tt.expectedReason)
+			}
+		})
+	}
+}
+
+func TestServiceCalculatePackWithShortfall(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	items := func(n int) *int {
+		return &n
+	}
+	percent := func(p float64) *float64 {
+		return &p
+	}
+
+	tests := []struct {
+		name              string
+		req               CalculatePackRequest
+		expectedPacks     map[int]int
+		expectedShortfall int
+		expectedErr       error
+	}{
+		{
+			name:              "Short closer than covering",
+			req:               CalculatePackRequest{OrderItemQuantity: 1010, MaxShortfallPercent: percent(2)},
+			expectedPacks:     map[int]int{1000: 1},
+			expectedShortfall: 10,
+		},
+		{
+			name:          "Covering closer than short",
+			req:           CalculatePackRequest{OrderItemQuantity: 990, MaxShortfall: items(100)},
+			expectedPacks: map[int]int{1000: 1},
+		},
+		{
+			name:              "Equally close in fewer packs",
+			req:               CalculatePackRequest{OrderItemQuantity: 1125, MaxShortfall: items(125)},
+			expectedPacks:     map[int]int{1000: 1},
+			expectedShortfall: 125,
+		},
+		{
+			name:              "Short when covering overshoots",
+			req:               CalculatePackRequest{OrderItemQuantity: 1240, MaxShortfall: items(250), MaxOvershoot: items(0)},
+			expectedPacks:     map[int]int{1000: 1},
+			expectedShortfall: 240,
+		},
+		{
+			name:        "Nothing within the tolerances",
+			req:         CalculatePackRequest{OrderItemQuantity: 1240, MaxShortfall: items(100), MaxOvershoot: items(5)},
+			expectedErr: ErrOvershootExceeded,
+		},
+		{
+			name:        "Items and percent",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, MaxShortfall: items(1), MaxShortfallPercent: percent(1)},
+			expectedErr: ErrInvalidTolerance,
+		},
+		{
+			name:        "Negative shortfall",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, MaxShortfall: items(-1)},
+			expectedErr: ErrInvalidTolerance,
+		},
+		{
+			name:        "Shortfall with explain",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, MaxShortfall: items(1), Explain: true},
+			expectedErr: ErrUnsupportedShortfall,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.CalculatePack(ctx, tt.req)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("CalculatePack() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if tt.expectedErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
+				t.Errorf("CalculatePack() packs = %v, want %v", result.Packs, tt.expectedPacks)
+			}
+
+			if result.Shortfall != tt.expectedShortfall || result.ShortfallAccepted != (tt.expectedShortfall > 0) {
+				t.Errorf("CalculatePack() shortfall = %d (accepted %v), want %d",
+					result.Shortfall, result.ShortfallAccepted, tt.expectedShortfall)
+			}
+		})
+	}
+}
+
+func TestServiceCalculatePackWithPackaging(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	req := CalculatePackRequest{OrderItemQuantity: 12001, Packaging: true}
+	if _, err := s.CalculatePack(ctx, req); !errors.Is(err, ErrNoPackagingConfigured) {
+		t.Fatalf("CalculatePack() without packaging error = %v, want %v", err, ErrNoPackagingConfigured)
+	}
+
+	if err := s.SetPackaging(ctx, SetPackagingRequest{Packaging: testPackaging(3)}); err != nil {
+		t.Fatalf("SetPackaging() error = %v", err)
+	}
+
+	result, err := s.CalculatePack(ctx, req)
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	// 2×5000 ship loose, 1×2000 in a large carton and 1×250 in a small one
+	if result.Shipment == nil || result.Shipment.PalletCount != 1 || result.Shipment.CartonCount != 2 ||
+		!reflect.DeepEqual(result.Shipment.Packs, []PackLine{{Size: 5000, Count: 2}}) {
+		t.Errorf("CalculatePack() shipment = %+v, want 2 cartons on 1 pallet and 2 loose 5000-packs", result.Shipment)
+	}
+}
+
+func TestServiceCalculatePackWithinShipmentLimits(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	weight := func(grams int) *int {
+		return &grams
+	}
+
+	req := CalculatePackRequest{OrderItemQuantity: 5000, MaxShipmentWeight: weight(5300)}
+	if _, err := s.CalculatePack(ctx, req); !errors.Is(err, ErrMissingPackSpec) {
+		t.Fatalf("CalculatePack() without specs error = %v, want %v", err, ErrMissingPackSpec)
+	}
+
+	specs := SetPackSpecsRequest{}
+	for _, spec := range testSpecs() {
+		specs.Specs = append(specs.Specs, spec)
+	}
+
+	if err := s.SetPackSpecs(ctx, specs); err != nil {
+		t.Fatalf("SetPackSpecs() error = %v", err)
+	}
+
+	result, err := s.CalculatePack(ctx, req)
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Packs, map[int]int{2000: 2, 1000: 1}) || result.TotalWeight != 5250 || result.TotalVolume != 90000 {
+		t.Errorf("CalculatePack() = %v weighing %d g in %d cm3, want {2000: 2, 1000: 1} weighing 5250 g in 90000 cm3",
+			result.Packs, result.TotalWeight, result.TotalVolume)
+	}
+
+	// Weight and volume are reported with splits too, but not read without a weight or parcel feature
+	packs := 10
+	result, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 250, SplitMaxPacks: &packs})
+	if err != nil || result.TotalWeight != 260 || result.TotalVolume != 4500 {
+		t.Errorf("CalculatePack() weighing %d g in %d cm3, error = %v, want 260 g in 4500 cm3", result.TotalWeight, result.TotalVolume, err)
+	}
+
+	result, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 250})
+	if err != nil || result.TotalWeight != 0 || result.TotalVolume != 0 {
+		t.Errorf("CalculatePack() weighing %d g in %d cm3, error = %v, want neither reported", result.TotalWeight, result.TotalVolume, err)
+	}
+
+	tests := []struct {
+		name        string
+		req         CalculatePackRequest
+		specs       []model.PackSpec
+		expectedErr error
+	}{
+		{
+			name:        "Too heavy",
+			req:         CalculatePackRequest{OrderItemQuantity: 5000, MaxShipmentWeight: weight(5000)},
+			expectedErr: ErrShipmentLimitExceeded,
+		},
+		{
+			name:        "Negative parcels",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, MaxParcels: weight(-1)},
+			expectedErr: ErrInvalidShipmentLimit,
+		},
+		{
+			name:        "Limit with stock",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, MaxParcels: weight(1), RespectStock: true},
+			expectedErr: ErrUnsupportedShipmentLimit,
+		},
+		{
+			name:        "Negative weight",
+			specs:       []model.PackSpec{{Size: 250, GrossWeight: -1}},
+			expectedErr: ErrInvalidPackSpec,
+		},
+		{
+			name:        "Size specified twice",
+			specs:       []model.PackSpec{{Size: 250}, {Size: 250}},
+			expectedErr: ErrInvalidPackSpec,
+		},
+		{
+			name:        "Unknown size",
+			specs:       []model.PackSpec{{Size: 750}},
+			expectedErr: ErrNotFoundPackSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.specs != nil {
+				err = s.SetPackSpecs(ctx, SetPackSpecsRequest{Specs: tt.specs})
+			} else {
+				_, err = s.CalculatePack(ctx, tt.req)
+			}
+
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestServiceCalculatePackSplit(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	limit := func(n int) *int {
+		return &n
+	}
+
+	result, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 12001, SplitMaxPacks: limit(2)})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	// 2×5000 + 1×2000 + 1×250 in two shipments of two packs
+	if len(result.Shipments) != 2 || result.Shipments[0].ShippedQuantity != 7000 || result.Shipments[1].ShippedQuantity != 5250 {
+		t.Errorf("CalculatePack() shipments = %+v, want 7000 and 5250 items", result.Shipments)
+	}
+
+	tests := []struct {
+		name        string
+		req         CalculatePackRequest
+		expectedErr error
+	}{
+		{
+			name:        "Weight without specs",
+			req:         CalculatePackRequest{OrderItemQuantity: 12001, SplitMaxWeight: limit(10000)},
+			expectedErr: ErrMissingPackSpec,
+		},
+		{
+			name:        "Zero limit",
+			req:         CalculatePackRequest{OrderItemQuantity: 12001, SplitMaxItems: limit(0)},
+			expectedErr: ErrInvalidSplitLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.CalculatePack(ctx, tt.req); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("CalculatePack() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestServiceCalculatePackWithConstraints(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	stored := model.PackConstraints{MinPacks: map[int]int{5000: 1}, MaxPacks: map[int]int{250: 0}}
+	if err := s.SetPackConstraints(ctx, SetPackConstraintsRequest{PackConstraints: stored}); err != nil {
+		t.Fatalf("SetPackConstraints() error = %v", err)
+	}
+
+	result, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251})
+	if err != nil || !reflect.DeepEqual(result.Packs, map[int]int{5000: 1}) {
+		t.Errorf("CalculatePack() = %v, error = %v, want {5000: 1}", result.Packs, err)
+	}
+
+	// Requested constraints take the place of the stored ones size by size
+	req := CalculatePackRequest{OrderItemQuantity: 251, MinPacks: map[int]int{5000: 0}}
+	result, err = s.CalculatePack(ctx, req)
+	if err != nil || !reflect.DeepEqual(result.Packs, map[int]int{500: 1}) {
+		t.Errorf("CalculatePack() = %v, error = %v, want {500: 1}", result.Packs, err)
+	}
+
+	// Stored constraints apply to every line of a batch
+	batch, err := s.CalculatePackBatch(ctx, CalculatePackBatchRequest{Lines: []CalculatePackBatchLine{{OrderItemQuantity: 251}}})
+	if err != nil || batch.Lines[0].Result == nil || !reflect.DeepEqual(batch.Lines[0].Result.Packs, map[int]int{5000: 1}) {
+		t.Errorf("CalculatePackBatch() = %+v, error = %v, want {5000: 1}", batch.Lines[0], err)
+	}
+
+	tests := []struct {
+		name        string
+		req         CalculatePackRequest
+		expectedErr error
+	}{
+		{
+			name:        "Cannot be met",
+			req:         CalculatePackRequest{OrderItemQuantity: 251, MinPacks: map[int]int{5000: 0}, MaxPacks: map[int]int{5000: 0, 2000: 0, 1000: 0, 500: 0}},
+			expectedErr: ErrConstraintsInfeasible,
+		},
+		{
+			name:        "Minimum above the stored maximum",
+			req:         CalculatePackRequest{OrderItemQuantity: 251, MinPacks: map[int]int{250: 1}},
+			expectedErr: ErrInvalidPackConstraint,
+		},
+		{
+			name:        "Unknown size",
+			req:         CalculatePackRequest{OrderItemQuantity: 251, MaxPacks: map[int]int{300: 1}},
+			expectedErr: ErrNotFoundPackSize,
+		},
+		{
+			name:        "Requested with alternatives",
+			req:         CalculatePackRequest{OrderItemQuantity: 251, Alternatives: 1, MaxPacks: map[int]int{500: 1}},
+			expectedErr: ErrUnsupportedConstraint,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.CalculatePack(ctx, tt.req); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("CalculatePack() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+
+	costs := SetPackCostsRequest{PackCosts: model.PackCosts{SurplusItemCost: 1}}
+	for _, pack := range testPrices(1).packs {
+		costs.Packs = append(costs.Packs, pack)
+	}
+
+	if err := s.SetPackCosts(ctx, costs); err != nil {
+		t.Fatalf("SetPackCosts() error = %v", err)
+	}
+
+	// Options the constrained solver does not support fail rather than leave the stored
+	// constraints out
+	unsupported := []CalculatePackRequest{
+		{OrderItemQuantity: 251, Alternatives: 2},
+		{OrderItemQuantity: 251, Explain: true},
+		{OrderItemQuantity: 251, Ranking: Ranking{CriterionPacks, CriterionItems}},
+		{OrderItemQuantity: 251, Objective: ObjectiveCost},
+	}
+	parcels := 2
+	for _, req := range append(unsupported, CalculatePackRequest{OrderItemQuantity: 251, MaxParcels: &parcels}) {
+		if _, err := s.CalculatePack(ctx, req); !errors.Is(err, ErrUnsupportedConstraint) {
+			t.Errorf("CalculatePack(%+v) error = %v, want %v", req, err, ErrUnsupportedConstraint)
+		}
+	}
+
+	// Stored constraints with a minimum order quantity leave smaller orders alone
+	stored.MinOrderQuantity = 1000
+	if err := s.SetPackConstraints(ctx, SetPackConstraintsRequest{PackConstraints: stored}); err != nil {
+		t.Fatalf("SetPackConstraints() error = %v", err)
+	}
+
+	result, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251})
+	if err != nil || !reflect.DeepEqual(result.Packs, map[int]int{500: 1}) {
+		t.Errorf("CalculatePack() = %v, error = %v, want {500: 1}", result.Packs, err)
+	}
+
+	result, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 1001})
+	if err != nil || !reflect.DeepEqual(result.Packs, map[int]int{5000: 1}) {
+		t.Errorf("CalculatePack() = %v, error = %v, want {5000: 1}", result.Packs, err)
+	}
+
+	// and the options they do not support along with them
+	for _, req := range unsupported {
+		if _, err := s.CalculatePack(ctx, req); err != nil {
+			t.Errorf("CalculatePack(%+v) error = %v", req, err)
+		}
+	}
+
+	// Constraints on a size that was removed since they were stored are dropped
+	if err := s.RemovePackSize(ctx, RemovePackSizeRequest{Size: 5000}); err != nil {
+		t.Fatalf("RemovePackSize() error = %v", err)
+	}
+
+	result, err = s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 251})
+	if err != nil || !reflect.DeepEqual(result.Packs, map[int]int{500: 1}) {
+		t.Errorf("CalculatePack() = %v, error = %v, want {500: 1}", result.Packs, err)
+	}
+}
+
+func TestServiceCalculatePackForCustomer(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(250, 500, 1000, 2000, 5000)
+
+	if _, err := s.SetCustomer(ctx, SetCustomerRequest{ID: "small-dock", ExcludedSizes: []int{5000}}); err != nil {
+		t.Fatalf("SetCustomer() error = %v", err)
+	}
+
+	result, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 5000, Customer: "small-dock"})
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	want := &SizeRestriction{Customer: "small-dock", ExcludedSizes: []int{5000}}
+	if !reflect.DeepEqual(result.Packs, map[int]int{2000: 2, 1000: 1}) || !reflect.DeepEqual(result.Restriction, want) {
+		t.Errorf("CalculatePack() = %v restricted by %+v, want {2000: 2, 1000: 1} restricted by %+v", result.Packs, result.Restriction, want)
+	}
+
+	// The request narrows the customer's sizes further, here raising the overshoot
+	req := CalculatePackRequest{OrderItemQuantity: 1250, Customer: "small-dock", AllowedSizes: []int{1000, 2000}}
+	result, err = s.CalculatePack(ctx, req)
+	if err != nil {
+		t.Fatalf("CalculatePack() error = %v", err)
+	}
+
+	want = &SizeRestriction{Customer: "small-dock", ExcludedSizes: []int{5000, 500, 250}, OvershootIncreased: true, OvershootIncrease: 750}
+	if !reflect.DeepEqual(result.Packs, map[int]int{2000: 1}) || !reflect.DeepEqual(result.Restriction, want) {
+		t.Errorf("CalculatePack() = %v restricted by %+v, want {2000: 1} restricted by %+v", result.Packs, result.Restriction, want)
+	}
+
+	tests := []struct {
+		name        string
+		req         CalculatePackRequest
+		expectedErr error
+	}{
+		{
+			name:        "Unknown customer",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, Customer: "nobody"},
+			expectedErr: repository.ErrCustomerNotFound,
+		},
+		{
+			name:        "Size outside the set",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, AllowedSizes: []int{300}},
+			expectedErr: ErrNotFoundPackSize,
+		},
+		{
+			name:        "Every size excluded",
+			req:         CalculatePackRequest{OrderItemQuantity: 1, Customer: "small-dock", AllowedSizes: []int{5000}},
+			expectedErr: ErrAllPackSizesExcluded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.CalculatePack(ctx, tt.req); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("CalculatePack() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestServiceCalculatePackLookup(t *testing.T) {
+	s := newTestService(250, 500, 1000, 2000, 5000)
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		change        func() error
+		expectedSizes []int
+		expectedPacks []PackLine
+		description   string
+	}{
+		{
+			name:          "Initial pack set",
+			change:        func() error { return nil },
+			expectedSizes: []int{5000, 2000, 1000, 500, 250},
+			expectedPacks: []PackLine{{Size: 5000, Count: 2}, {Size: 2000, Count: 1}, {Size: 250, Count: 1}},
+			description:   "Should read the remainder from the table built on the first calculation",
+		},
+		{
+			name: "Added pack size",
+			change: func() error {
+				// The set is at MaxPackSizes, so the smallest size makes room first
+				if err := s.RemovePackSize(ctx, RemovePackSizeRequest{Size: 250}); err != nil {
+					return err
+				}
+
+				return s.AddPackSize(ctx, AddPackSizeRequest{Size: 2100})
+			},
+			expectedSizes: []int{5000, 2100, 2000, 1000, 500},
+			expectedPacks: []PackLine{{Size: 5000, Count: 2}, {Size: 2100, Count: 1}},
+			description:   "Should rebuild the table once a size is added",
+		},
+		{
+			name: "Removed pack size",
+			change: func() error {
+				return s.RemovePackSize(ctx, RemovePackSizeRequest{Size: 2100})
+			},
+			expectedSizes: []int{5000, 2000, 1000, 500},
+			expectedPacks: []PackLine{{Size: 5000, Count: 2}, {Size: 2000, Count: 1}, {Size: 500, Count: 1}},
+			description:   "Should rebuild the table once a size is removed",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatalf("changing the pack set failed: %v", err)
+			}
+
+			if i > 0 && s.tables.tables[fmt.Sprint(tt.expectedSizes)] == nil {
+				t.Fatalf("no lookup table for %v after the change", tt.expectedSizes)
+			}
+
+			result, err := s.CalculatePack(ctx, CalculatePackRequest{OrderItemQuantity: 12001, Explain: true})
+			if err != nil {
+				t.Fatalf("CalculatePack() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(result.PackList, tt.expectedPacks) {
+				t.Errorf("CalculatePack() pack list = %v, want %v", result.PackList, tt.expectedPacks)
+			}
+
+			if !result.Explanation.Lookup || result.Explanation.NodesExplored != 0 {
+				t.Errorf("CalculatePack() trace = %+v, want a lookup", result.Explanation.Trace)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
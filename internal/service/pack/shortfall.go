@@ -0,0 +1,125 @@
+package pack
+
+import "context"
+
+// calculateShortPacks finds the combination holding the most items below an order of
+// orderItemQty items while still holding at least minQty, and among those the one with
+// the fewest packs. It reports false when no combination falls in that window, and stops
+// with ErrComputationBudgetExceeded once the budget runs out or ctx is done.
+//
+// A combination with the fewest packs for its total holds at most exchangeBound items
+// outside the largest pack, so as in calculatePacks the largest packs largestPackCount
+// asks for at minQty are held by the best combination of every total in the window.
+func calculateShortPacks(ctx context.Context, orderItemQty, minQty int, packSizes []int, budget Budget) (OptimalPacking, bool, error) {
+	if len(packSizes) == 0 {
+		return OptimalPacking{}, false, ErrNoPackSizesConfigured
+	}
+
+	// A shipment holds at least one item
+	minQty = max(minQty, 1)
+	maxQty := orderItemQty - 1
+
+	largest := packSizes[0]
+	count := largestPackCount(minQty, packSizes)
+	fixed := count * largest
+
+	if minQty > maxQty || fixed > maxQty {
+		return OptimalPacking{}, false, nil
+	}
+
+	ctx, cancel := withBudgetTimeout(ctx, budget)
+	defer cancel()
+
+	t := newTracker(ctx, budget)
+
+	best, found, err := findShortPackCombination(t, max(minQty-fixed, 0), maxQty-fixed, packSizes)
+	if err != nil || !found {
+		return OptimalPacking{}, false, err
+	}
+
+	if count > 0 {
+		best.Packs[largest] += count
+	}
+
+	return newOptimalPacking(best.Packs, Trace{
+		Branch:        BranchShortfall,
+		LargestPacks:  count,
+		Remainder:     maxQty - fixed,
+		NodesExplored: t.nodes,
+	}), true, nil
+}
+
+// findShortPackCombination uses dynamic programming to find the combination holding the
+// most items between minQty and maxQty, and among those the fewest packs. Every item
+// total it evaluates costs one node per pack size against the tracker's budget.
+func findShortPackCombination(t *tracker, minQty, maxQty int, packSizes []int) (PackCombination, bool, error) {
+	// packSizes should be sorted in descending order so ties keep the larger packs
+	limit := maxQty + 1
+
+	// Fail before allocating tables the budget could never fill
+	if err := t.fits(limit * len(packSizes)); err != nil {
+		return PackCombination{}, false, err
+	}
+
+	// counts[t] is the fewest packs holding exactly t items (-1 when impossible),
+	// last[t] is the pack size added last to reach t.
+	counts := make([]int, limit)
+	last := make([]int, limit)
+
+	for total := 1; total < limit; total++ {
+		if err := t.spend(len(packSizes)); err != nil {
+			return PackCombination{}, false, err
+		}
+
+		counts[total] = -1
+
+		for _, packSize := range packSizes {
+			if packSize > total || counts[total-packSize] < 0 {
+				continue
+			}
+
+			if counts[total] < 0 || counts[total-packSize]+1 < counts[total] {
+				counts[total] = counts[total-packSize] + 1
+				last[total] = packSize
+			}
+		}
+	}
+
+	for total := maxQty; total >= minQty; total-- {
+		if counts[total] < 0 {
+			continue
+		}
+
+		packs := make(map[int]int)
+		for rest := total; rest > 0; rest -= last[rest] {
+			packs[last[rest]]++
+		}
+
+		return PackCombination{Packs: packs, Total: total, PackCount: counts[total]}, true, nil
+	}
+
+	return PackCombination{}, false, nil
+}
+
+// closerToOrder reports whether a ships closer to an order of orderQty items than b,
+// breaking ties by fewer packs and then by larger packs
+func closerToOrder(a, b OptimalPacking, orderQty int, packSizes []int) bool {
+	if da, db := distance(a.Total, orderQty), distance(b.Total, orderQty); da != db {
+		return da < db
+	}
+
+	if a.PackCount != b.PackCount {
+		return a.PackCount < b.PackCount
+	}
+
+	return compareBy(CriterionLargest, newPackCombination(a.Packs), newPackCombination(b.Packs), packSizes) < 0
+}
+
+// distance returns how many items a shipment of total items is off an order of orderQty
+func distance(total, orderQty int) int {
+	if total < orderQty {
+		return orderQty - total
+	}
+
+	return total - orderQty
+}
@@ -0,0 +1,120 @@
+package pack
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestCalculateShortPacks(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	tests := []struct {
+		name          string
+		packSizes     []int
+		orderItemQty  int
+		minQty        int
+		expectedPacks map[int]int
+		expectedFound bool
+		description   string
+	}{
+		{
+			name:          "Just short",
+			packSizes:     packSizes,
+			orderItemQty:  1010,
+			minQty:        990,
+			expectedPacks: map[int]int{1000: 1},
+			expectedFound: true,
+			description:   "Should ship the most items below the order",
+		},
+		{
+			name:          "Large order",
+			packSizes:     packSizes,
+			orderItemQty:  12260,
+			minQty:        12000,
+			expectedPacks: map[int]int{5000: 2, 2000: 1, 250: 1},
+			expectedFound: true,
+			description:   "Should keep the largest packs and optimise the rest",
+		},
+		{
+			name:          "Nothing in the window",
+			packSizes:     []int{500},
+			orderItemQty:  999,
+			minQty:        980,
+			expectedFound: false,
+			description:   "Should report no combination when none falls in the window",
+		},
+		{
+			name:          "Below the smallest pack",
+			packSizes:     packSizes,
+			orderItemQty:  200,
+			minQty:        0,
+			expectedFound: false,
+			description:   "Should never ship an empty shipment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, found, err := calculateShortPacks(context.Background(), tt.orderItemQty, tt.minQty, tt.packSizes, Budget{})
+			if err != nil {
+				t.Fatalf("calculateShortPacks() error = %v", err)
+			}
+
+			if found != tt.expectedFound {
+				t.Fatalf("calculateShortPacks() found = %v, want %v", found, tt.expectedFound)
+			}
+
+			if found && !reflect.DeepEqual(result.Packs, tt.expectedPacks) {
+				t.Errorf("calculateShortPacks() packs = %v, want %v", result.Packs, tt.expectedPacks)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+// bruteForceShortPacks finds the fewest packs for every total up to the order and returns
+// the most items below it but at least minQty, and their pack count, or a total of -1
+func bruteForceShortPacks(orderItemQty, minQty int, packSizes []int) (bestTotal, bestCount int) {
+	counts := make([]int, orderItemQty)
+	for total := 1; total < orderItemQty; total++ {
+		counts[total] = -1
+		for _, packSize := range packSizes {
+			if packSize <= total && counts[total-packSize] >= 0 && (counts[total] < 0 || counts[total-packSize]+1 < counts[total]) {
+				counts[total] = counts[total-packSize] + 1
+			}
+		}
+	}
+
+	for total := orderItemQty - 1; total >= max(minQty, 1); total-- {
+		if counts[total] >= 0 {
+			return total, counts[total]
+		}
+	}
+
+	return -1, 0
+}
+
+func TestCalculateShortPacksMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 3000; i++ {
+		packSizes := randomPackSizes(rng)
+		orderItemQty := 1 + rng.Intn(3000)
+		minQty := orderItemQty - rng.Intn(100)
+
+		wantTotal, wantCount := bruteForceShortPacks(orderItemQty, minQty, packSizes)
+
+		result, found, err := calculateShortPacks(context.Background(), orderItemQty, minQty, packSizes, Budget{})
+		if err != nil {
+			t.Fatalf("calculateShortPacks(%d, %d, %v) error = %v", orderItemQty, minQty, packSizes, err)
+		}
+
+		if found != (wantTotal >= 0) || (found && (result.Total != wantTotal || result.PackCount != wantCount)) {
+			t.Fatalf("calculateShortPacks(%d, %d, %v) = %v (found %v), brute force gives total %d, packs %d",
+				orderItemQty, minQty, packSizes, result.Packs, found, wantTotal, wantCount)
+		}
+	}
+}
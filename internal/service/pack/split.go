@@ -0,0 +1,205 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// maxSplitShipments caps how many shipments a single order may be split into
+const maxSplitShipments = 1000
+
+// splitLimits caps every shipment of a split order by packs, items and gross weight in
+// grams, negative meaning unlimited
+type splitLimits struct {
+	maxPacks  int
+	maxItems  int
+	maxWeight int
+}
+
+// shipmentLoad is what a shipment being filled holds so far
+type shipmentLoad struct {
+	packs  map[int]int
+	count  int
+	items  int
+	weight int
+}
+
+// fits reports whether one more pack of packSize weighing weight grams stays within the limits
+func (l splitLimits) fits(load shipmentLoad, packSize, weight int) bool {
+	return l.room(load, packSize, weight, 1) == 1
+}
+
+// room returns how many more packs of packSize weighing weight grams, up to most, the
+// limits leave room for in a shipment
+func (l splitLimits) room(load shipmentLoad, packSize, weight, most int) int {
+	if l.maxPacks >= 0 {
+		most = min(most, l.maxPacks-load.count)
+	}
+
+	if l.maxItems >= 0 {
+		most = min(most, (l.maxItems-load.items)/packSize)
+	}
+
+	if l.maxWeight >= 0 && weight > 0 {
+		most = min(most, (l.maxWeight-load.weight)/weight)
+	}
+
+	return max(most, 0)
+}
+
+// minShipments returns the fewest shipments the limits could hold the packs in
+func (l splitLimits) minShipments(packs map[int]int, specs specList) int {
+	c := newPackCombination(packs)
+	weight, _, _ := specs.measure(packs)
+
+	n := 1
+	for _, bound := range [][2]int{{c.PackCount, l.maxPacks}, {c.Total, l.maxItems}, {weight, l.maxWeight}} {
+		if bound[1] > 0 {
+			n = max(n, (bound[0]+bound[1]-1)/bound[1])
+		}
+	}
+
+	return n
+}
+
+// splitShipments spreads packs across few shipments within the limits. It starts from
+// the fewest shipments the limits allow and places the packs size by size, largest
+// first: each size is spread evenly over the shipments with room for it, the odd packs
+// going to those holding the fewest items, so shipments end up balanced. Packs no
+// shipment has room for fill as few new shipments as they need. Sizes need a spec only
+// when weight is limited. It stops with ErrComputationBudgetExceeded once the budget
+// runs out or ctx is done.
+func splitShipments(ctx context.Context, packs map[int]int, specs specList, limits splitLimits, budget Budget) ([]SplitShipment, error) {
+	for _, line := range newPackList(packs) {
+		if !limits.fits(shipmentLoad{}, line.Size, specs[line.Size].GrossWeight) {
+			return nil, fmt.Errorf("%w: a %d-pack exceeds the limits of a shipment on its own", ErrUnsplittablePack, line.Size)
+		}
+	}
+
+	ctx, cancel := withBudgetTimeout(ctx, budget)
+	defer cancel()
+
+	t := newTracker(ctx, budget)
+
+	n := limits.minShipments(packs, specs)
+	if n > maxSplitShipments {
+		return nil, fmt.Errorf("%w: the order needs more than %d shipments", ErrTooManyShipments, maxSplitShipments)
+	}
+
+	loads := make([]shipmentLoad, 0, n)
+	loads = addShipments(loads, n)
+
+	for _, line := range newPackList(packs) {
+		weight := specs[line.Size].GrossWeight
+
+		left, err := spreadPacks(t, loads, line.Size, weight, line.Count, limits)
+		if err != nil {
+			return nil, err
+		}
+
+		if left == 0 {
+			continue
+		}
+
+		// Every new shipment holds as many of the packs left as an empty one has room for
+		per := limits.room(shipmentLoad{}, line.Size, weight, left)
+		extra := (left + per - 1) / per
+		if len(loads)+extra > maxSplitShipments {
+			return nil, fmt.Errorf("%w: the order needs more than %d shipments", ErrTooManyShipments, maxSplitShipments)
+		}
+
+		loads = addShipments(loads, extra)
+
+		if _, err := spreadPacks(t, loads[len(loads)-extra:], line.Size, weight, left, limits); err != nil {
+			return nil, err
+		}
+	}
+
+	return newSplitShipments(loads), nil
+}
+
+// addShipments appends n empty shipments to loads
+func addShipments(loads []shipmentLoad, n int) []shipmentLoad {
+	for range n {
+		loads = append(loads, shipmentLoad{packs: make(map[int]int)})
+	}
+
+	return loads
+}
+
+// spreadPacks places count packs of packSize weighing weight grams evenly over the
+// shipments with room for them and returns how many packs none had room for. Every
+// round hands each shipment with room the same share, capped by its room, until fewer
+// packs are left than shipments with room, and those go to the shipments holding the
+// fewest items. A round costs one node per shipment against the tracker's budget.
+func spreadPacks(t *tracker, loads []shipmentLoad, packSize, weight, count int, limits splitLimits) (int, error) {
+	place := func(i, n int) {
+		loads[i].packs[packSize] += n
+		loads[i].count += n
+		loads[i].items += n * packSize
+		loads[i].weight += n * weight
+	}
+
+	for count > 0 {
+		if err := t.spend(len(loads)); err != nil {
+			return 0, err
+		}
+
+		open := make([]int, 0, len(loads))
+		for i, load := range loads {
+			if limits.room(load, packSize, weight, 1) > 0 {
+				open = append(open, i)
+			}
+		}
+
+		if len(open) == 0 {
+			break
+		}
+
+		share := count / len(open)
+		if share == 0 {
+			// Fewer packs than shipments with room, so each of the emptiest takes one
+			slices.SortStableFunc(open, func(a, b int) int {
+				return loads[a].items - loads[b].items
+			})
+
+			for _, i := range open[:count] {
+				place(i, 1)
+			}
+
+			return 0, nil
+		}
+
+		for _, i := range open {
+			n := limits.room(loads[i], packSize, weight, share)
+			place(i, n)
+			count -= n
+		}
+	}
+
+	return count, nil
+}
+
+// newSplitShipments lists filled shipments, the ones holding the most items first
+func newSplitShipments(loads []shipmentLoad) []SplitShipment {
+	shipments := make([]SplitShipment, 0, len(loads))
+	for _, load := range loads {
+		shipments = append(shipments, SplitShipment{
+			ShippedQuantity: load.items,
+			PackCount:       load.count,
+			TotalWeight:     load.weight,
+			PackList:        newPackList(load.packs),
+		})
+	}
+
+	slices.SortStableFunc(shipments, func(a, b SplitShipment) int {
+		return b.ShippedQuantity - a.ShippedQuantity
+	})
+
+	for i := range shipments {
+		shipments[i].Number = i + 1
+	}
+
+	return shipments
+}
@@ -0,0 +1,130 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSplitShipments(t *testing.T) {
+	tests := []struct {
+		name            string
+		packs           map[int]int
+		limits          splitLimits
+		expectedPacks   []int
+		expectedWeights []int
+		expectedErr     error
+		description     string
+	}{
+		{
+			name:          "Pack limit",
+			packs:         map[int]int{250: 10},
+			limits:        splitLimits{maxPacks: 4, maxItems: -1, maxWeight: -1},
+			expectedPacks: []int{4, 3, 3},
+			description:   "Should spread the packs evenly over the fewest shipments",
+		},
+		{
+			name:            "Weight limit needs another shipment",
+			packs:           map[int]int{2000: 2, 1000: 1},
+			limits:          splitLimits{maxPacks: -1, maxItems: -1, maxWeight: 3000},
+			expectedPacks:   []int{1, 1, 1},
+			expectedWeights: []int{2100, 2100, 1050},
+			description:     "Should add a shipment when a pack fits none of the fewest the weight allows",
+		},
+		{
+			name:        "Pack over the limit",
+			packs:       map[int]int{5000: 1},
+			limits:      splitLimits{maxPacks: -1, maxItems: 4000, maxWeight: -1},
+			expectedErr: ErrUnsplittablePack,
+			description: "Should fail when a single pack exceeds the limits",
+		},
+		{
+			name:        "Too many shipments",
+			packs:       map[int]int{250: maxSplitShipments + 1},
+			limits:      splitLimits{maxPacks: 1, maxItems: -1, maxWeight: -1},
+			expectedErr: ErrTooManyShipments,
+			description: "Should fail when the order needs more shipments than allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shipments, err := splitShipments(context.Background(), tt.packs, testSpecs(), tt.limits, Budget{})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("splitShipments() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if len(shipments) != len(tt.expectedPacks) {
+				t.Fatalf("splitShipments() = %d shipments, want %d", len(shipments), len(tt.expectedPacks))
+			}
+
+			for i, shipment := range shipments {
+				if shipment.Number != i+1 || shipment.PackCount != tt.expectedPacks[i] ||
+					(tt.expectedWeights != nil && shipment.TotalWeight != tt.expectedWeights[i]) {
+					t.Errorf("splitShipments() shipment %d = %+v, want number %d with %d packs", i, shipment, i+1, tt.expectedPacks[i])
+				}
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}
+
+func TestSplitShipmentsLargeOrder(t *testing.T) {
+	packSizes := []int{5000, 2000, 1000, 500, 250}
+
+	packing, err := calculatePacks(context.Background(), 1324001, packSizes, Budget{})
+	if err != nil {
+		t.Fatalf("calculatePacks() error = %v", err)
+	}
+
+	limits := splitLimits{maxPacks: -1, maxItems: 100000, maxWeight: -1}
+
+	shipments, err := splitShipments(context.Background(), packing.Packs, testSpecs(), limits, Budget{})
+	if err != nil {
+		t.Fatalf("splitShipments() error = %v", err)
+	}
+
+	// 1324250 items need at least 14 shipments of 100000
+	if len(shipments) != 14 {
+		t.Fatalf("splitShipments() = %d shipments, want 14", len(shipments))
+	}
+
+	total, largest, smallest := 0, shipments[0].ShippedQuantity, shipments[len(shipments)-1].ShippedQuantity
+	for _, shipment := range shipments {
+		if shipment.ShippedQuantity > limits.maxItems {
+			t.Errorf("shipment %d ships %d items, limit is %d", shipment.Number, shipment.ShippedQuantity, limits.maxItems)
+		}
+
+		total += shipment.ShippedQuantity
+	}
+
+	if total != packing.Total {
+		t.Errorf("shipments hold %d items, want %d", total, packing.Total)
+	}
+
+	// Balanced shipments differ by less than one largest pack
+	if largest-smallest >= packSizes[0] {
+		t.Errorf("shipments hold between %d and %d items, want them within %d", smallest, largest, packSizes[0])
+	}
+}
+
+func TestSplitShipmentsWithinBudget(t *testing.T) {
+	// 10,000,000 items in 250-packs at 100 packs per shipment
+	limits := splitLimits{maxPacks: 100, maxItems: -1, maxWeight: -1}
+
+	shipments, err := splitShipments(context.Background(), map[int]int{250: 40000}, testSpecs(), limits, Budget{MaxNodes: 5_000_000})
+	if err != nil {
+		t.Fatalf("splitShipments() error = %v", err)
+	}
+
+	if len(shipments) != 400 {
+		t.Fatalf("splitShipments() = %d shipments, want 400", len(shipments))
+	}
+
+	for _, shipment := range shipments {
+		if shipment.PackCount != 100 || shipment.ShippedQuantity != 25000 {
+			t.Errorf("shipment %d = %+v, want 100 packs holding 25000 items", shipment.Number, shipment)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package pack
+
+import (
+	"fmt"
+
+	"github.com/Amir-Sadati/order-packing/internal/config"
+)
+
+// validatePackSet checks that the set is not empty, that every pack size lies within
+// the configured limits, that no size repeats and that the set does not hold too many sizes
+func validatePackSet(packSizes []int, cfg *config.PackConfig) error {
+	if len(packSizes) == 0 {
+		return ErrEmptyPackSet
+	}
+
+	if len(packSizes) > cfg.MaxPackSizes {
+		return fmt.Errorf("%w: at most %d pack sizes are allowed", ErrTooManyPackSizes, cfg.MaxPackSizes)
+	}
+
+	seen := make(map[int]struct{}, len(packSizes))
+	for _, size := range packSizes {
+		if err := validatePackSize(size, cfg); err != nil {
+			return err
+		}
+
+		if _, ok := seen[size]; ok {
+			return fmt.Errorf("%w: %d", ErrPackSizeExists, size)
+		}
+
+		seen[size] = struct{}{}
+	}
+
+	return nil
+}
+
+// validatePackSize checks that a single pack size lies within the configured limits
+func validatePackSize(size int, cfg *config.PackConfig) error {
+	if size < cfg.MinPackSize || size > cfg.MaxPackSize {
+		return fmt.Errorf("%w: %d is not between %d and %d", ErrInvalidPackSize, size, cfg.MinPackSize, cfg.MaxPackSize)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package pack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Amir-Sadati/order-packing/internal/config"
+)
+
+func TestValidatePackSet(t *testing.T) {
+	cfg := &config.PackConfig{
+		MinPackSize:  1,
+		MaxPackSize:  10000,
+		MaxPackSizes: 5,
+	}
+
+	tests := []struct {
+		name        string
+		packSizes   []int
+		expectedErr error
+		description string
+	}{
+		{
+			name:        "Valid set",
+			packSizes:   []int{5000, 2000, 1000, 500, 250},
+			expectedErr: nil,
+			description: "Should accept sizes within the limits",
+		},
+		{
+			name:        "Zero size",
+			packSizes:   []int{500, 0},
+			expectedErr: ErrInvalidPackSize,
+			description: "Should reject a zero size that would divide by zero in the calculator",
+		},
+		{
+			name:        "Negative size",
+			packSizes:   []int{-250},
+			expectedErr: ErrInvalidPackSize,
+			description: "Should reject negative sizes",
+		},
+		{
+			name:        "Too large size",
+			packSizes:   []int{10001},
+			expectedErr: ErrInvalidPackSize,
+			description: "Should reject sizes above the maximum",
+		},
+		{
+			name:        "Duplicate size",
+			packSizes:   []int{500, 250, 500},
+			expectedErr: ErrPackSizeExists,
+			description: "Should reject a size that is already in the set",
+		},
+		{
+			name:        "Empty set",
+			packSizes:   []int{},
+			expectedErr: ErrEmptyPackSet,
+			description: "Should reject a set the calculator could not use",
+		},
+		{
+			name:        "Too many sizes",
+			packSizes:   []int{6, 5, 4, 3, 2, 1},
+			expectedErr: ErrTooManyPackSizes,
+			description: "Should reject sets holding more sizes than allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePackSet(tt.packSizes, cfg)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("validatePackSet(%v) error = %v, want %v", tt.packSizes, err, tt.expectedErr)
+			}
+
+			t.Logf("Test passed: %s", tt.description)
+		})
+	}
+}